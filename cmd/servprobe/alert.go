@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+func alertCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alert",
+		Short: "Work with alert channels",
+	}
+	cmd.AddCommand(alertTestCmd())
+	return cmd
+}
+
+// alertTestOptions selects the sample event and what to do with it.
+type alertTestOptions struct {
+	service string
+	status  string
+	send    bool
+}
+
+func alertTestCmd() *cobra.Command {
+	var opts alertTestOptions
+	cmd := &cobra.Command{
+		Use:   "test [channel...]",
+		Short: "Render the message each channel would send for a sample event",
+		Long: `Render the message all or the named channels would send for a sample event,
+using the channel templates from the config file. With --send the message is
+also delivered.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			return runAlertTest(cmd.Context(), cmd.OutOrStdout(), cfg, args, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.service, "service", "", "service the sample event is about (default: first service)")
+	cmd.Flags().StringVar(&opts.status, "status", "down", "status of the sample event: down or up")
+	cmd.Flags().BoolVar(&opts.send, "send", false, "deliver the message as well")
+	return cmd
+}
+
+func runAlertTest(ctx context.Context, w io.Writer, cfg *config.Config, channels []string, opts alertTestOptions) error {
+	status := checker.Status(opts.status)
+	if status != checker.StatusDown && status != checker.StatusUp {
+		return fmt.Errorf("invalid --status %q: must be down or up", opts.status)
+	}
+	svc := cfg.Services[0]
+	if opts.service != "" {
+		found := false
+		for _, s := range cfg.Services {
+			if s.Name == opts.service {
+				svc, found = s, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown service %q", opts.service)
+		}
+	}
+
+	notifiers, err := alert.NewNotifiers(cfg.Alerts)
+	if err != nil {
+		return err
+	}
+	if len(notifiers) == 0 {
+		return fmt.Errorf("no alert channels configured")
+	}
+	if len(channels) > 0 {
+		byName := make(map[string]alert.Notifier, len(notifiers))
+		for _, n := range notifiers {
+			byName[n.Name()] = n
+		}
+		notifiers = notifiers[:0]
+		for _, name := range channels {
+			n, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("unknown alert channel %q", name)
+			}
+			notifiers = append(notifiers, n)
+		}
+	}
+
+	e := alert.SampleEvent(svc, status)
+	var errs []error
+	for i, n := range notifiers {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "=== %s ===\n", n.Name())
+		if r, ok := n.(alert.Renderer); ok {
+			msg, err := r.Render(e)
+			if err != nil {
+				fmt.Fprintf(w, "error: %v\n", err)
+				errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+				continue
+			}
+			printMessage(w, msg)
+		}
+		if opts.send {
+			sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			err := n.Notify(sendCtx, e)
+			cancel()
+			if err != nil {
+				fmt.Fprintf(w, "send failed: %v\n", err)
+				errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+				continue
+			}
+			fmt.Fprintln(w, "sent")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func printMessage(w io.Writer, msg alert.Message) {
+	fmt.Fprintln(w, msg.URL)
+	keys := make([]string, 0, len(msg.Header))
+	for k := range msg.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s: %s\n", k, msg.Header.Get(k))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, string(msg.Body))
+}
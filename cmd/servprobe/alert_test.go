@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+func alertTestConfig(t *testing.T, url string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	content := `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://api.example.com"
+  - name: "db"
+    type: "tcp"
+    target: "db:5432"
+alerts:
+  dashboard_url: "https://status.example.com"
+  channels:
+    - name: "incidents"
+      type: "webhook"
+      url: "` + url + `"
+      template:
+        body: '{"title": {{json .Title}}, "link": {{json .DashboardURL}}}'
+        headers:
+          X-Service: "{{.Service.Name}}"
+    - name: "chat"
+      type: "slack"
+      url: "` + url + `"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestRunAlertTest_Render(t *testing.T) {
+	cfg := alertTestConfig(t, "http://hooks.invalid/alert")
+	var out bytes.Buffer
+	err := runAlertTest(context.Background(), &out, cfg, []string{"incidents"}, alertTestOptions{service: "db", status: "up"})
+	if err != nil {
+		t.Fatalf("runAlertTest: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"=== incidents ===", "http://hooks.invalid/alert", "X-Service: db", `{"title": "db is back UP", "link": "https://status.example.com"}`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "=== chat ===") {
+		t.Errorf("expected only the named channel:\n%s", got)
+	}
+}
+
+func TestRunAlertTest_Send(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+	defer srv.Close()
+
+	cfg := alertTestConfig(t, srv.URL)
+	var out bytes.Buffer
+	if err := runAlertTest(context.Background(), &out, cfg, nil, alertTestOptions{status: "down", send: true}); err != nil {
+		t.Fatalf("runAlertTest: %v", err)
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[0], `"title": "api is DOWN"`) {
+		t.Errorf("expected both channels to be sent the sample for api, got %v", bodies)
+	}
+	if strings.Count(out.String(), "sent\n") != 2 {
+		t.Errorf("expected two sent lines:\n%s", out.String())
+	}
+}
+
+func TestRunAlertTest_Errors(t *testing.T) {
+	cfg := alertTestConfig(t, "http://hooks.invalid/alert")
+	tests := []struct {
+		name     string
+		channels []string
+		opts     alertTestOptions
+		want     string
+	}{
+		{"unknown channel", []string{"pager"}, alertTestOptions{status: "down"}, `unknown alert channel "pager"`},
+		{"unknown service", nil, alertTestOptions{service: "nope", status: "down"}, `unknown service "nope"`},
+		{"bad status", nil, alertTestOptions{status: "sideways"}, "invalid --status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runAlertTest(context.Background(), io.Discard, cfg, tt.channels, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
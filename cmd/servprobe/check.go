@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"sync"
 	"text/tabwriter"
 	"time"
@@ -14,6 +16,28 @@ import (
 	"github.com/hazz-dev/servprobe/internal/config"
 )
 
+// selectServices narrows cfg.Services down to the named services, in the
+// given order. With no names, all services are kept.
+func selectServices(cfg *config.Config, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	byName := make(map[string]config.Service, len(cfg.Services))
+	for _, svc := range cfg.Services {
+		byName[svc.Name] = svc
+	}
+	selected := make([]config.Service, 0, len(names))
+	for _, name := range names {
+		svc, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown service %q", name)
+		}
+		selected = append(selected, svc)
+	}
+	cfg.Services = selected
+	return nil
+}
+
 func executeCheck(cmd *cobra.Command, cfg *config.Config) error {
 	return runChecks(cmd.OutOrStdout(), cfg)
 }
@@ -77,3 +101,35 @@ func runChecks(out io.Writer, cfg *config.Config) error {
 	}
 	return nil
 }
+
+// checkResponse mirrors the data returned by POST /api/services/{name}/check.
+type checkResponse struct {
+	Service    string `json:"service"`
+	Status     string `json:"status"`
+	ResponseMs int64  `json:"response_ms"`
+	Error      string `json:"error"`
+}
+
+// runRemoteChecks asks the server to check each named service now.
+func runRemoteChecks(ctx context.Context, out io.Writer, c *apiClient, names []string) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tSTATUS\tRESPONSE\tERROR")
+	allUp := true
+	for _, name := range names {
+		var res checkResponse
+		if err := c.do(ctx, http.MethodPost, "/api/services/"+url.PathEscape(name)+"/check", nil, &res); err != nil {
+			w.Flush()
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", res.Service, res.Status, time.Duration(res.ResponseMs)*time.Millisecond, res.Error)
+		if res.Status != string(checker.StatusUp) {
+			allUp = false
+		}
+	}
+	w.Flush()
+
+	if !allUp {
+		return fmt.Errorf("one or more services are down")
+	}
+	return nil
+}
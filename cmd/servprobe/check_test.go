@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -82,3 +83,41 @@ func TestRunChecks_MultipleServices(t *testing.T) {
 		t.Errorf("expected 'svc2' in output, got:\n%s", output)
 	}
 }
+
+func TestRunRemoteChecks(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"data":{"service":"api","status":"down","response_ms":120,"error":"connection refused"},"error":""}`))
+	}))
+	defer srv.Close()
+
+	c := &apiClient{base: srv.URL, http: srv.Client()}
+	var buf bytes.Buffer
+	err := runRemoteChecks(context.Background(), &buf, c, []string{"api"})
+	if err == nil {
+		t.Error("expected error when the service is down, got nil")
+	}
+	if len(paths) != 1 || paths[0] != "POST /api/services/api/check" {
+		t.Errorf("unexpected requests: %v", paths)
+	}
+	output := buf.String()
+	for _, want := range []string{"SERVICE", "api", "down", "120ms", "connection refused"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestSelectServices(t *testing.T) {
+	cfg := &config.Config{Services: []config.Service{{Name: "api"}, {Name: "db"}, {Name: "web"}}}
+	if err := selectServices(cfg, []string{"web", "api"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Services) != 2 || cfg.Services[0].Name != "web" || cfg.Services[1].Name != "api" {
+		t.Errorf("expected [web api], got %+v", cfg.Services)
+	}
+	if err := selectServices(cfg, []string{"ghost"}); err == nil {
+		t.Error("expected error for unknown service, got nil")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func dbCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the database",
+	}
+	cmd.AddCommand(dbMigrateCmd())
+	return cmd
+}
+
+func dbMigrateCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending schema migrations",
+		Long: `Apply pending schema migrations to the SQLite or PostgreSQL database in the
+config file.
+
+serve and status migrate the database when they open it; run this to upgrade
+ahead of time, or with --dry-run to see what an upgrade would change.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			return runMigrate(cmd.Context(), cmd.OutOrStdout(), cfg.Storage, dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list pending migrations without applying them")
+	return cmd
+}
+
+func runMigrate(ctx context.Context, w io.Writer, cfg config.StorageConfig, dryRun bool) error {
+	plan, err := storage.Migrate(ctx, cfg, dryRun)
+	if err != nil {
+		// The DSN may hold a password, so only SQLite databases are named.
+		if cfg.Driver == config.DriverPostgres {
+			return fmt.Errorf("migrating postgres database: %w", err)
+		}
+		return fmt.Errorf("migrating %s: %w", cfg.Path, err)
+	}
+	if len(plan.Pending) == 0 {
+		fmt.Fprintf(w, "Schema is up to date at version %d.\n", plan.Current)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Schema version %d, %d pending:\n", plan.Current, len(plan.Pending))
+	for _, m := range plan.Pending {
+		fmt.Fprintf(w, "  %3d  %s\n", m.Version, m.Name)
+	}
+	last := plan.Pending[len(plan.Pending)-1].Version
+	if dryRun {
+		fmt.Fprintf(w, "Dry run: nothing applied.\n")
+	} else {
+		fmt.Fprintf(w, "Migrated to version %d.\n", last)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+func TestRunMigrate(t *testing.T) {
+	cfg := config.StorageConfig{Driver: config.DriverSQLite, Path: filepath.Join(t.TempDir(), "servprobe.db")}
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := runMigrate(ctx, &buf, cfg, true); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Schema version 0") || !strings.Contains(out, "1  baseline schema") || !strings.Contains(out, "Dry run") {
+		t.Errorf("unexpected dry run output:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := runMigrate(ctx, &buf, cfg, false); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Migrated to version") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := runMigrate(ctx, &buf, cfg, true); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(buf.String(), "up to date") {
+		t.Errorf("expected an up-to-date schema, got:\n%s", buf.String())
+	}
+}
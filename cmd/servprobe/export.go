@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/export"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// importBatchSize is how many checks import stores per transaction.
+const importBatchSize = 1000
+
+type exportStore interface {
+	ExportChecks(ctx context.Context, q storage.ExportQuery, fn func(storage.Check) error) error
+}
+
+type importStore interface {
+	ImportChecks(ctx context.Context, checks []storage.Check) (int, error)
+}
+
+func exportCmd() *cobra.Command {
+	var (
+		q          storage.ExportQuery
+		from, to   string
+		formatName string
+		output     string
+	)
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export check history as CSV or JSON Lines",
+		Long: `Export check history from the database in the config file, oldest first.
+
+The format defaults to the extension of --output, or JSON Lines when writing
+to stdout.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var err error
+			if q.From, err = parseTimeFlag("from", from); err != nil {
+				return err
+			}
+			if q.To, err = parseTimeFlag("to", to); err != nil {
+				return err
+			}
+			format := export.JSONL
+			switch {
+			case formatName != "":
+				format, err = export.ParseFormat(formatName)
+			case output != "":
+				format, err = export.FormatOf(output)
+			}
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			db, err := storage.Connect(cfg.Storage)
+			if err != nil {
+				return fmt.Errorf("opening database: %w", err)
+			}
+			defer db.Close()
+
+			if output == "" {
+				return runExport(cmd.Context(), cmd.OutOrStdout(), cmd.ErrOrStderr(), db, q, format)
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("creating %s: %w", output, err)
+			}
+			err = runExport(cmd.Context(), f, cmd.ErrOrStderr(), db, q, format)
+			if cerr := f.Close(); err == nil && cerr != nil {
+				err = fmt.Errorf("writing %s: %w", output, cerr)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&q.Service, "service", "", "service to export (default: all)")
+	cmd.Flags().StringVar(&from, "from", "", "export checks from this time, in RFC 3339 or YYYY-MM-DD")
+	cmd.Flags().StringVar(&to, "to", "", "export checks before this time, in RFC 3339 or YYYY-MM-DD")
+	cmd.Flags().StringVar(&formatName, "format", "", "csv or jsonl")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write (default: stdout)")
+	return cmd
+}
+
+// runExport writes the checks q selects to w and reports how many to log.
+func runExport(ctx context.Context, w, log io.Writer, db exportStore, q storage.ExportQuery, format export.Format) error {
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		return errors.New("--to must be after --from")
+	}
+	ew := export.NewWriter(w, format)
+	n := 0
+	err := db.ExportChecks(ctx, q, func(c storage.Check) error {
+		n++
+		return ew.Write(c)
+	})
+	if err == nil {
+		err = ew.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("exporting checks: %w", err)
+	}
+	fmt.Fprintf(log, "Exported %d checks.\n", n)
+	return nil
+}
+
+func importCmd() *cobra.Command {
+	var formatName string
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import check history from a CSV or JSON Lines export",
+		Long: `Import check history from a file made by export, e.g. to move to another
+database or merge the history of several instances. Pass - to read stdin.
+
+A check is skipped if its service already has one at the same time, so an
+import that stopped halfway can simply be run again. Imported checks don't
+open or close incidents.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			var (
+				format export.Format
+				err    error
+			)
+			switch {
+			case formatName != "":
+				format, err = export.ParseFormat(formatName)
+			case path == "-":
+				format = export.JSONL
+			default:
+				format, err = export.FormatOf(path)
+			}
+			if err != nil {
+				return err
+			}
+
+			in := cmd.InOrStdin()
+			if path != "-" {
+				f, err := os.Open(path)
+				if err != nil {
+					return fmt.Errorf("opening %s: %w", path, err)
+				}
+				defer f.Close()
+				in = f
+			}
+
+			cfg, err := config.Load(cfgFile)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			db, err := storage.Connect(cfg.Storage)
+			if err != nil {
+				return fmt.Errorf("opening database: %w", err)
+			}
+			defer db.Close()
+
+			return runImport(cmd.Context(), cmd.OutOrStdout(), db, in, format)
+		},
+	}
+	cmd.Flags().StringVar(&formatName, "format", "", "csv or jsonl (default: from the file extension)")
+	return cmd
+}
+
+// runImport stores the checks read from r in batches.
+func runImport(ctx context.Context, out io.Writer, db importStore, r io.Reader, format export.Format) error {
+	var (
+		reader        = export.NewReader(r, format)
+		batch         = make([]storage.Check, 0, importBatchSize)
+		read, written int
+	)
+	flush := func() error {
+		n, err := db.ImportChecks(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("importing checks after %d stored: %w", written, err)
+		}
+		written += n
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		c, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading checks after %d stored: %w", written, err)
+		}
+		read++
+		batch = append(batch, c)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "Imported %d checks, skipped %d already stored.\n", written, read-written)
+	return nil
+}
+
+// parseTimeFlag parses the value of a time flag in RFC 3339 or YYYY-MM-DD.
+// An empty value is the zero time.
+func parseTimeFlag(name, v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s %q: use RFC 3339 or YYYY-MM-DD", name, v)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/export"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src, err := storage.Open(filepath.Join(t.TempDir(), "src.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		for _, svc := range []string{"api", "db"} {
+			err := src.InsertCheck(ctx, checker.CheckResult{
+				ServiceName: svc, Status: checker.StatusUp, ResponseTime: 20 * time.Millisecond,
+				CheckedAt: t0.Add(time.Duration(i) * time.Minute),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, format := range []export.Format{export.CSV, export.JSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var file, log bytes.Buffer
+			q := storage.ExportQuery{Service: "api", From: t0.Add(time.Minute)}
+			if err := runExport(ctx, &file, &log, src, q, format); err != nil {
+				t.Fatalf("runExport: %v", err)
+			}
+			if !strings.Contains(log.String(), "Exported 4 checks") {
+				t.Errorf("unexpected export output: %q", log.String())
+			}
+
+			dst, err := storage.Open(filepath.Join(t.TempDir(), "dst.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dst.Close()
+			var out bytes.Buffer
+			if err := runImport(ctx, &out, dst, bytes.NewReader(file.Bytes()), format); err != nil {
+				t.Fatalf("runImport: %v", err)
+			}
+			if !strings.Contains(out.String(), "Imported 4 checks, skipped 0") {
+				t.Errorf("unexpected import output: %q", out.String())
+			}
+
+			// Importing again stores nothing new.
+			out.Reset()
+			if err := runImport(ctx, &out, dst, bytes.NewReader(file.Bytes()), format); err != nil {
+				t.Fatalf("runImport: %v", err)
+			}
+			if !strings.Contains(out.String(), "Imported 0 checks, skipped 4") {
+				t.Errorf("unexpected output of a repeated import: %q", out.String())
+			}
+			page, err := dst.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != 4 || !page.Checks[0].CheckedAt.Equal(t0.Add(4*time.Minute)) {
+				t.Errorf("unexpected imported history: %+v", page)
+			}
+		})
+	}
+}
+
+func TestRunImport_InvalidFile(t *testing.T) {
+	dst, err := storage.Open(filepath.Join(t.TempDir(), "dst.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	in := "service,checked_at,status\napi,2026-03-01T00:00:00Z,up\napi,2026-03-01T00:01:00Z,maybe\n"
+	err = runImport(context.Background(), &bytes.Buffer{}, dst, strings.NewReader(in), export.CSV)
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error naming line 3, got %v", err)
+	}
+}
+
+func TestRunExport_InvalidRange(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	q := storage.ExportQuery{From: t0, To: t0}
+	if err := runExport(context.Background(), &bytes.Buffer{}, &bytes.Buffer{}, nil, q, export.CSV); err == nil {
+		t.Error("expected an error for an empty range")
+	}
+}
@@ -13,16 +13,24 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/catalog"
 	"github.com/hazz-dev/servprobe/internal/checker"
 	"github.com/hazz-dev/servprobe/internal/config"
 	"github.com/hazz-dev/servprobe/internal/dashboard"
+	"github.com/hazz-dev/servprobe/internal/maintenance"
+	"github.com/hazz-dev/servprobe/internal/metrics"
 	"github.com/hazz-dev/servprobe/internal/scheduler"
 	"github.com/hazz-dev/servprobe/internal/server"
 	"github.com/hazz-dev/servprobe/internal/storage"
+	"github.com/hazz-dev/servprobe/internal/telemetry"
 	"github.com/hazz-dev/servprobe/internal/version"
+	"github.com/hazz-dev/servprobe/internal/writer"
 )
 
-var cfgFile string
+var (
+	cfgFile       string
+	watchInterval time.Duration
+)
 
 func main() {
 	if err := rootCmd().Execute(); err != nil {
@@ -42,6 +50,13 @@ func rootCmd() *cobra.Command {
 	root.AddCommand(serveCmd())
 	root.AddCommand(checkCmd())
 	root.AddCommand(statusCmd())
+	root.AddCommand(maintenanceCmd())
+	root.AddCommand(pauseCmd())
+	root.AddCommand(resumeCmd())
+	root.AddCommand(alertCmd())
+	root.AddCommand(dbCmd())
+	root.AddCommand(exportCmd())
+	root.AddCommand(importCmd())
 
 	return root
 }
@@ -57,11 +72,15 @@ func versionCmd() *cobra.Command {
 }
 
 func serveCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "serve",
 		Short: "Start the service monitor",
-		RunE:  runServe,
+		Long: "Start the service monitor.\n\n" +
+			"The config file is reloaded on SIGHUP and whenever it changes on disk.",
+		RunE: runServe,
 	}
+	cmd.Flags().DurationVar(&watchInterval, "watch-interval", 5*time.Second, "how often to check the config file for changes (0 disables)")
+	return cmd
 }
 
 func runServe(cmd *cobra.Command, _ []string) error {
@@ -74,34 +93,104 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	}
 	logger.Info("config loaded", "services", len(cfg.Services))
 
-	// 2. Open SQLite
-	db, err := storage.Open(cfg.Storage.Path)
+	// 2. Open the database
+	db, err := storage.Connect(cfg.Storage)
 	if err != nil {
 		return fmt.Errorf("opening database: %w", err)
 	}
 	defer db.Close()
 
-	// 3. Build alerter (if configured)
-	var alerter *alert.Alerter
-	if cfg.Alerts.Webhook.URL != "" {
-		alerter = alert.New(cfg.Alerts.Webhook.URL, cfg.Alerts.Webhook.Cooldown.Duration, logger)
+	// 3. Build alerter (sends nothing until a channel is configured)
+	notifiers, err := alert.NewNotifiers(cfg.Alerts)
+	if err != nil {
+		return fmt.Errorf("building alert channels: %w", err)
+	}
+	alerter := alert.New("", 0, logger)
+	alerter.SetNotifiers(notifiers, cfg.Alerts.Cooldown.Duration)
+	alerter.SetRoutes(cfg.Alerts.Routes)
+	alerter.SetRetry(cfg.Alerts.Retry)
+	alerter.SetOutbox(db)
+	alerter.SetEscalation(cfg.Alerts.ReminderInterval.Duration, cfg.Alerts.Escalation)
+	alerter.SetOutageStore(db)
+	if err := alerter.LoadOutages(context.Background()); err != nil {
+		return err
+	}
+
+	// 4. Load maintenance windows (config + ad-hoc from the database)
+	maint := maintenance.New(cfg.Maintenance, db)
+	if err := maint.Load(context.Background()); err != nil {
+		return err
+	}
+
+	// 5. Merge config services with those managed through the API
+	services := catalog.New(cfg.Services, db, logger)
+	if err := services.Load(context.Background()); err != nil {
+		return err
 	}
+	alerter.SetServiceLookup(services.Lookup)
 
-	// 4. Build scheduler
+	// 6. Build scheduler, storing results in batches in the background
+	writes := writer.New(db, cfg.Storage.Writes, logger)
 	factory := func(svc config.Service) (checker.Checker, error) {
 		return checker.New(svc)
 	}
-	sched := scheduler.New(cfg.Services, db, factory, logger)
-	if alerter != nil {
-		sched.SetOnResult(alerter.Notify)
+	var tel *telemetry.Telemetry
+	if cfg.Telemetry.Enabled() {
+		tel, err = telemetry.New(context.Background(), cfg.Telemetry, logger)
+		if err != nil {
+			return err
+		}
+		logger.Info("exporting telemetry", "endpoint", cfg.Telemetry.Endpoint, "protocol", cfg.Telemetry.Protocol)
+		factory = func(svc config.Service) (checker.Checker, error) {
+			c, err := checker.New(svc)
+			if err != nil {
+				return nil, err
+			}
+			return tel.WrapChecker(svc, c), nil
+		}
 	}
+	sched := scheduler.New(services.Services(), checkStore{Store: db, writes: writes}, factory, logger)
+	sched.SetMaintenance(maint)
+	stats := metrics.New(services.Services(), logger)
+	sched.SetOnResult(func(r checker.CheckResult, prev *checker.Status) {
+		stats.ObserveCheck(r)
+		alerter.Notify(r, prev)
+	})
+	sched.State().SetFlapping(cfg.Flapping)
+	if err := sched.LoadState(context.Background()); err != nil {
+		return err
+	}
+	alerter.SetStateSource(sched.State())
+	alerter.SetOnDelivery(stats.ObserveDelivery)
+	stats.SetState(sched.State())
+	stats.SetSchedulerStats(sched)
+	stats.SetWriteStats(writes)
+	stats.SetDBStats(db)
+
+	// 7. Build API server
+	apiServer := server.New(db, services.Services(), logger)
+	apiServer.SetMaintenance(maint)
+	apiServer.SetServiceManager(services)
+	apiServer.SetCheckRunner(sched)
+	apiServer.SetState(sched.State())
+	apiServer.SetAlertLog(db)
+	apiServer.SetIncidentLog(db)
+	apiServer.SetRollups(db)
+	apiServer.SetStats(db)
+	apiServer.SetExporter(db)
+	apiServer.SetWriteStats(writes)
+	apiServer.SetAPIToken(cfg.Server.APIToken)
 
-	// 5. Build API server
-	apiServer := server.New(db, cfg.Services, logger)
+	services.OnChange(func(list []config.Service) {
+		apiServer.SetServices(list)
+		stats.SetServices(list)
+		sched.Update(list)
+	})
 
-	// 6. Mount routes on a single mux
+	// 8. Mount routes on a single mux
 	mux := http.NewServeMux()
 	mux.Handle("/api/", apiServer.Router())
+	mux.Handle("/metrics", stats.Handler())
 	mux.Handle("/", dashboard.Handler())
 
 	httpServer := &http.Server{
@@ -109,15 +198,45 @@ func runServe(cmd *cobra.Command, _ []string) error {
 		Handler: mux,
 	}
 
-	// 7. Signal context for graceful shutdown
+	// 9. Signal context for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
 
-	// 8. Start scheduler
+	// 10. Start the result writer, scheduler, alert delivery, storage
+	// compaction and config reloader. From here on every exit goes through
+	// the shutdown below, so queued results are written before the
+	// database closes.
+	writes.Start()
 	sched.Start(ctx)
-	logger.Info("scheduler started", "services", len(cfg.Services))
+	logger.Info("scheduler started", "services", len(sched.Services()))
+
+	alertsDone := make(chan struct{})
+	go func() {
+		defer close(alertsDone)
+		alerter.Run(ctx)
+	}()
+
+	compactDone := make(chan struct{})
+	go func() {
+		defer close(compactDone)
+		runCompaction(ctx, db, cfg.Storage.Retention, logger)
+	}()
+
+	rl := &reloader{
+		path:    cfgFile,
+		logger:  logger,
+		catalog: services,
+		maint:   maint,
+		alerter: alerter,
+		states:  sched.State(),
+		current: cfg,
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go rl.watch(ctx, hup, watchInterval)
 
-	// 9. Start HTTP server in background
+	// 11. Start HTTP server in background
 	serverErr := make(chan error, 1)
 	go func() {
 		logger.Info("listening", "address", cfg.Server.Address)
@@ -126,40 +245,89 @@ func runServe(cmd *cobra.Command, _ []string) error {
 		}
 	}()
 
-	// 10. Wait for signal or server error
+	// 12. Wait for signal or server error
+	var runErr error
 	select {
 	case <-ctx.Done():
 		logger.Info("shutdown signal received")
 	case err := <-serverErr:
-		return fmt.Errorf("HTTP server: %w", err)
+		runErr = fmt.Errorf("HTTP server: %w", err)
+		logger.Error("HTTP server failed; shutting down", "error", err)
+		stop()
 	}
 
-	// 11. Graceful shutdown
+	// 13. Graceful shutdown
 	sched.Wait()
+	<-alertsDone
+	<-compactDone
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		logger.Error("HTTP server shutdown", "error", err)
 	}
+	if err := writes.Close(shutdownCtx); err != nil {
+		logger.Error("writing queued check results", "error", err, "queued", writes.Stats().Queued)
+	}
+	if tel != nil {
+		if err := tel.Shutdown(shutdownCtx); err != nil {
+			logger.Error("flushing telemetry", "error", err)
+		}
+	}
 
+	if runErr != nil {
+		return runErr
+	}
 	logger.Info("shutdown complete")
 	return nil
 }
 
+// checkStore is the scheduler's store: results are queued on the writer and
+// everything else goes to the database.
+type checkStore struct {
+	storage.Store
+	writes *writer.Writer
+}
+
+func (s checkStore) InsertCheck(ctx context.Context, r checker.CheckResult) error {
+	return s.writes.InsertCheck(ctx, r)
+}
+
 func checkCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:   "check",
-		Short: "Run a one-off check of all configured services",
-		RunE:  runCheck,
+	var remote remoteOptions
+	cmd := &cobra.Command{
+		Use:   "check [service...]",
+		Short: "Run a one-off check of all or the named services",
+		Long: `Run a one-off check of all or the named services.
+
+By default checks run locally and nothing is stored. With --remote, the running
+server checks the named services now: results are stored and alerts fire.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("remote") {
+				if len(args) == 0 {
+					return fmt.Errorf("--remote requires at least one service name")
+				}
+				c, err := remote.client()
+				if err != nil {
+					return err
+				}
+				return runRemoteChecks(cmd.Context(), cmd.OutOrStdout(), c, args)
+			}
+			return runCheck(cmd, args)
+		},
 	}
+	remote.addFlags(cmd)
+	return cmd
 }
 
-func runCheck(cmd *cobra.Command, _ []string) error {
+func runCheck(cmd *cobra.Command, args []string) error {
 	cfg, err := config.Load(cfgFile)
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
+	if err := selectServices(cfg, args); err != nil {
+		return err
+	}
 	return executeCheck(cmd, cfg)
 }
 
@@ -177,7 +345,7 @@ func runStatus(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("loading config: %w", err)
 	}
 
-	db, err := storage.Open(cfg.Storage.Path)
+	db, err := storage.Connect(cfg.Storage)
 	if err != nil {
 		return fmt.Errorf("opening database: %w", err)
 	}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazz-dev/servprobe/internal/maintenance"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func maintenanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Manage maintenance windows on a running server",
+	}
+	cmd.AddCommand(maintenanceListCmd())
+	cmd.AddCommand(maintenanceCreateCmd())
+	cmd.AddCommand(maintenanceDeleteCmd())
+	return cmd
+}
+
+func maintenanceListCmd() *cobra.Command {
+	var remote remoteOptions
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List active and upcoming maintenance windows",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c, err := remote.client()
+			if err != nil {
+				return err
+			}
+			return listMaintenance(cmd.Context(), cmd.OutOrStdout(), c)
+		},
+	}
+	remote.addFlags(cmd)
+	return cmd
+}
+
+// maintenanceRequest mirrors the body accepted by POST /api/maintenance.
+type maintenanceRequest struct {
+	Name     string     `json:"name,omitempty"`
+	Services []string   `json:"services,omitempty"`
+	Tags     []string   `json:"tags,omitempty"`
+	Start    *time.Time `json:"start,omitempty"`
+	End      *time.Time `json:"end,omitempty"`
+	Duration string     `json:"duration,omitempty"`
+}
+
+func maintenanceCreateCmd() *cobra.Command {
+	var (
+		remote   remoteOptions
+		req      maintenanceRequest
+		start    string
+		end      string
+		duration time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Start or schedule an ad-hoc maintenance window",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if start != "" {
+				t, err := time.Parse(time.RFC3339, start)
+				if err != nil {
+					return fmt.Errorf("invalid --start: %w", err)
+				}
+				req.Start = &t
+			}
+			if end != "" {
+				t, err := time.Parse(time.RFC3339, end)
+				if err != nil {
+					return fmt.Errorf("invalid --end: %w", err)
+				}
+				req.End = &t
+			} else {
+				req.Duration = duration.String()
+			}
+			c, err := remote.client()
+			if err != nil {
+				return err
+			}
+			return createMaintenance(cmd.Context(), cmd.OutOrStdout(), c, req)
+		},
+	}
+	remote.addFlags(cmd)
+	cmd.Flags().StringVar(&req.Name, "name", "", "window name, e.g. the reason for the maintenance")
+	cmd.Flags().StringSliceVar(&req.Services, "service", nil, "service in scope (repeatable; default: all)")
+	cmd.Flags().StringSliceVar(&req.Tags, "tag", nil, "tag in scope (repeatable)")
+	cmd.Flags().StringVar(&start, "start", "", "start time in RFC 3339 (default: now)")
+	cmd.Flags().StringVar(&end, "end", "", "end time in RFC 3339 (overrides --duration)")
+	cmd.Flags().DurationVar(&duration, "duration", time.Hour, "window length")
+	return cmd
+}
+
+func maintenanceDeleteCmd() *cobra.Command {
+	var remote remoteOptions
+	cmd := &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete an ad-hoc maintenance window",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid id %q", args[0])
+			}
+			c, err := remote.client()
+			if err != nil {
+				return err
+			}
+			if err := c.do(cmd.Context(), "DELETE", fmt.Sprintf("/api/maintenance/%d", id), nil, nil); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted maintenance window %d\n", id)
+			return nil
+		},
+	}
+	remote.addFlags(cmd)
+	return cmd
+}
+
+func listMaintenance(ctx context.Context, out io.Writer, c *apiClient) error {
+	var windows []maintenance.Window
+	if err := c.do(ctx, "GET", "/api/maintenance", nil, &windows); err != nil {
+		return err
+	}
+	if len(windows) == 0 {
+		fmt.Fprintln(out, "No active or upcoming maintenance windows.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPE\tSTART\tEND\tACTIVE")
+	for _, mw := range windows {
+		id := "—"
+		if mw.ID != 0 {
+			id = strconv.FormatInt(mw.ID, 10)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n",
+			id,
+			mw.Name,
+			scope(mw.Services, mw.Tags),
+			mw.Start.Local().Format("2006-01-02 15:04"),
+			mw.End.Local().Format("2006-01-02 15:04"),
+			mw.Active,
+		)
+	}
+	w.Flush()
+	return nil
+}
+
+func createMaintenance(ctx context.Context, out io.Writer, c *apiClient, req maintenanceRequest) error {
+	var created storage.MaintenanceWindow
+	if err := c.do(ctx, "POST", "/api/maintenance", req, &created); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Created maintenance window %d (%s) for %s, %s – %s\n",
+		created.ID,
+		created.Name,
+		scope(created.Services, created.Tags),
+		created.StartsAt.Local().Format("2006-01-02 15:04"),
+		created.EndsAt.Local().Format("2006-01-02 15:04"),
+	)
+	return nil
+}
+
+func scope(services, tags []string) string {
+	var parts []string
+	parts = append(parts, services...)
+	for _, t := range tags {
+		parts = append(parts, "tag:"+t)
+	}
+	if len(parts) == 0 {
+		return "all services"
+	}
+	return strings.Join(parts, ",")
+}
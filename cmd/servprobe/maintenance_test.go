@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateMaintenance_SendsRequest(t *testing.T) {
+	var gotAuth string
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		now := time.Now().UTC().Format(time.RFC3339)
+		w.Write([]byte(`{"data":{"id":7,"name":"deploy","services":["api"],"tags":[],"starts_at":"` + now + `","ends_at":"` + now + `"},"error":""}`))
+	}))
+	defer srv.Close()
+
+	c := &apiClient{base: srv.URL, token: "secret", http: srv.Client()}
+	var buf bytes.Buffer
+	err := createMaintenance(context.Background(), &buf, c, maintenanceRequest{
+		Name:     "deploy",
+		Services: []string{"api"},
+		Duration: "1h0m0s",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("expected bearer token, got %q", gotAuth)
+	}
+	if gotBody["duration"] != "1h0m0s" || gotBody["name"] != "deploy" {
+		t.Errorf("unexpected request body: %v", gotBody)
+	}
+	if !strings.Contains(buf.String(), "Created maintenance window 7") {
+		t.Errorf("expected confirmation in output, got:\n%s", buf.String())
+	}
+}
+
+func TestListMaintenance_OutputFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"name":"nightly","services":[],"tags":["prod"],"start":"2026-03-01T02:00:00Z","end":"2026-03-01T03:00:00Z","recurring":true,"source":"config","active":false}],"error":""}`))
+	}))
+	defer srv.Close()
+
+	c := &apiClient{base: srv.URL, http: srv.Client()}
+	var buf bytes.Buffer
+	if err := listMaintenance(context.Background(), &buf, c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"NAME", "nightly", "tag:prod"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestAPIClient_ErrorEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"data":null,"error":"unauthorized"}`))
+	}))
+	defer srv.Close()
+
+	c := &apiClient{base: srv.URL, http: srv.Client()}
+	err := c.do(context.Background(), "GET", "/api/maintenance", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestLocalURL(t *testing.T) {
+	tests := map[string]string{
+		":8080":            "http://localhost:8080",
+		"0.0.0.0:9090":     "http://localhost:9090",
+		"10.0.0.5:8080":    "http://10.0.0.5:8080",
+		"monitor.local:80": "http://monitor.local:80",
+	}
+	for in, want := range tests {
+		if got := localURL(in); got != want {
+			t.Errorf("localURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
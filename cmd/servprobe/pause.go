@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+func pauseCmd() *cobra.Command {
+	var remote remoteOptions
+	cmd := &cobra.Command{
+		Use:   "pause <service>...",
+		Short: "Stop checking services on a running server until resumed",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := remote.client()
+			if err != nil {
+				return err
+			}
+			return setPaused(cmd.Context(), cmd.OutOrStdout(), c, args, true)
+		},
+	}
+	remote.addFlags(cmd)
+	return cmd
+}
+
+func resumeCmd() *cobra.Command {
+	var remote remoteOptions
+	cmd := &cobra.Command{
+		Use:   "resume <service>...",
+		Short: "Resume checking paused services on a running server",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := remote.client()
+			if err != nil {
+				return err
+			}
+			return setPaused(cmd.Context(), cmd.OutOrStdout(), c, args, false)
+		},
+	}
+	remote.addFlags(cmd)
+	return cmd
+}
+
+func setPaused(ctx context.Context, out io.Writer, c *apiClient, names []string, paused bool) error {
+	action, done := "resume", "Resumed"
+	if paused {
+		action, done = "pause", "Paused"
+	}
+	for _, name := range names {
+		if err := c.do(ctx, "POST", "/api/services/"+url.PathEscape(name)+"/"+action, nil, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s %s\n", done, name)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetPaused_SendsRequests(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"data":{"name":"api","status":"paused"},"error":""}`))
+	}))
+	defer srv.Close()
+
+	c := &apiClient{base: srv.URL, token: "secret", http: srv.Client()}
+	var buf bytes.Buffer
+	if err := setPaused(context.Background(), &buf, c, []string{"api", "db"}, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != "POST /api/services/api/pause" || paths[1] != "POST /api/services/db/pause" {
+		t.Errorf("unexpected requests: %v", paths)
+	}
+	if !strings.Contains(buf.String(), "Paused api") {
+		t.Errorf("expected confirmation in output, got:\n%s", buf.String())
+	}
+
+	paths = nil
+	if err := setPaused(context.Background(), &buf, c, []string{"api"}, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "POST /api/services/api/resume" {
+		t.Errorf("unexpected requests: %v", paths)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/catalog"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/maintenance"
+	"github.com/hazz-dev/servprobe/internal/state"
+)
+
+// reloader re-reads the config file and applies it to the running
+// components. Services, maintenance windows, alert and flap detection
+// settings are applied in place; server, storage and telemetry settings need
+// a restart.
+type reloader struct {
+	path    string
+	logger  *slog.Logger
+	catalog *catalog.Catalog
+	maint   *maintenance.Schedule
+	alerter *alert.Alerter
+	states  *state.Tracker
+
+	mu      sync.Mutex
+	current *config.Config
+}
+
+// reload loads the config file and applies it. An invalid config is rejected
+// and the running config is left untouched.
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.Load(r.path)
+	if err != nil {
+		return err
+	}
+	notifiers, err := alert.NewNotifiers(cfg.Alerts)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Server != r.current.Server {
+		r.logger.Warn("server settings changed; restart to apply them")
+	}
+	if cfg.Storage != r.current.Storage {
+		r.logger.Warn("storage settings changed; restart to apply them")
+	}
+	if !reflect.DeepEqual(cfg.Telemetry, r.current.Telemetry) {
+		r.logger.Warn("telemetry settings changed; restart to apply them")
+	}
+
+	r.catalog.SetStatic(cfg.Services)
+	r.maint.SetStatic(cfg.Maintenance)
+	r.alerter.SetNotifiers(notifiers, cfg.Alerts.Cooldown.Duration)
+	r.alerter.SetRoutes(cfg.Alerts.Routes)
+	r.alerter.SetRetry(cfg.Alerts.Retry)
+	r.alerter.SetEscalation(cfg.Alerts.ReminderInterval.Duration, cfg.Alerts.Escalation)
+	r.states.SetFlapping(cfg.Flapping)
+
+	r.current = cfg
+	r.logger.Info("config reloaded", "services", len(cfg.Services))
+	return nil
+}
+
+// watch reloads on every signal from hup and, if poll is positive, whenever
+// the config file's modification time changes. It returns when ctx is done.
+func (r *reloader) watch(ctx context.Context, hup <-chan os.Signal, poll time.Duration) {
+	var tick <-chan time.Time
+	if poll > 0 {
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	lastMod := r.modTime()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.logger.Info("SIGHUP received, reloading config")
+		case <-tick:
+			mod := r.modTime()
+			if mod.IsZero() || mod.Equal(lastMod) {
+				continue
+			}
+			lastMod = mod
+			r.logger.Info("config file changed, reloading", "path", r.path)
+		}
+		if err := r.reload(); err != nil {
+			r.logger.Error("config reload failed; keeping previous config", "error", err)
+		}
+	}
+}
+
+func (r *reloader) modTime() time.Time {
+	fi, err := os.Stat(r.path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/catalog"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/maintenance"
+	"github.com/hazz-dev/servprobe/internal/scheduler"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+type nopStore struct{}
+
+func (nopStore) InsertCheck(context.Context, checker.CheckResult) error { return nil }
+func (nopStore) CurrentRuns(context.Context) ([]storage.ServiceRun, error) {
+	return nil, nil
+}
+
+type nopChecker struct{ name string }
+
+func (c nopChecker) Check(context.Context) checker.CheckResult {
+	return checker.CheckResult{ServiceName: c.name, Status: checker.StatusUp, CheckedAt: time.Now()}
+}
+
+const reloadConfigV1 = `
+services:
+  - name: "api"
+    type: "tcp"
+    target: "localhost:1"
+    interval: "1h"
+`
+
+const reloadConfigV2 = `
+services:
+  - name: "api"
+    type: "tcp"
+    target: "localhost:1"
+    interval: "1h"
+  - name: "db"
+    type: "tcp"
+    target: "localhost:2"
+    interval: "1h"
+`
+
+func newTestReloader(t *testing.T, content string) (*reloader, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	factory := func(svc config.Service) (checker.Checker, error) {
+		return nopChecker{name: svc.Name}, nil
+	}
+	sched := scheduler.New(cfg.Services, nopStore{}, factory, discardLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	sched.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		sched.Wait()
+	})
+	cat := catalog.New(cfg.Services, nil, discardLogger())
+	cat.OnChange(sched.Update)
+
+	return &reloader{
+		path:    path,
+		logger:  discardLogger(),
+		catalog: cat,
+		maint:   maintenance.New(cfg.Maintenance, nil),
+		alerter: alert.New("", 0, discardLogger()),
+		states:  sched.State(),
+		current: cfg,
+	}, path
+}
+
+func serviceNames(services []config.Service) []string {
+	names := make([]string, len(services))
+	for i, svc := range services {
+		names[i] = svc.Name
+	}
+	return names
+}
+
+func TestReloader_AppliesValidConfig(t *testing.T) {
+	r, path := newTestReloader(t, reloadConfigV1)
+	if err := os.WriteFile(path, []byte(reloadConfigV2), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if names := serviceNames(r.catalog.Services()); len(names) != 2 || names[1] != "db" {
+		t.Errorf("expected scheduler to have [api db], got %v", names)
+	}
+	if len(r.current.Services) != 2 {
+		t.Errorf("expected current config to be replaced, got %d services", len(r.current.Services))
+	}
+}
+
+func TestReloader_RejectsInvalidConfig(t *testing.T) {
+	r, path := newTestReloader(t, reloadConfigV1)
+	if err := os.WriteFile(path, []byte("services:\n  - name: api\n    type: bogus\n    target: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reload(); err == nil {
+		t.Fatal("expected error for invalid config, got nil")
+	}
+	if names := serviceNames(r.catalog.Services()); len(names) != 1 || names[0] != "api" {
+		t.Errorf("expected old service list to keep running, got %v", names)
+	}
+}
+
+func TestReloader_WatchDetectsFileChange(t *testing.T) {
+	r, path := newTestReloader(t, reloadConfigV1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.watch(ctx, nil, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond) // let the watcher record the original mtime
+
+	// Make sure the new modification time differs from the original.
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte(reloadConfigV2), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(r.catalog.Services()) == 2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("expected file change to trigger a reload within 2s")
+}
+
+func TestReloader_WatchReloadsOnSignal(t *testing.T) {
+	r, path := newTestReloader(t, reloadConfigV1)
+	if err := os.WriteFile(path, []byte(reloadConfigV2), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hup := make(chan os.Signal, 1)
+	go r.watch(ctx, hup, 0)
+	hup <- os.Interrupt
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(r.catalog.Services()) == 2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("expected signal to trigger a reload within 2s")
+}
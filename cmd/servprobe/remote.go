@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// remoteOptions holds the flags for commands that talk to a running server.
+type remoteOptions struct {
+	addr  string
+	token string
+}
+
+func (o *remoteOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.addr, "remote", "", "server URL (default: derived from server.address in config)")
+	cmd.Flags().StringVar(&o.token, "token", "", "API token (default: server.api_token from config)")
+}
+
+// client builds an API client, filling unset options from the config file.
+func (o *remoteOptions) client() (*apiClient, error) {
+	addr, token := o.addr, o.token
+	if addr == "" || token == "" {
+		cfg, err := config.Load(cfgFile)
+		if err != nil {
+			if addr == "" {
+				return nil, fmt.Errorf("loading config: %w", err)
+			}
+		} else {
+			if addr == "" {
+				addr = localURL(cfg.Server.Address)
+			}
+			if token == "" {
+				token = cfg.Server.APIToken
+			}
+		}
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &apiClient{
+		base:  strings.TrimRight(addr, "/"),
+		token: token,
+		http:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// localURL turns a listen address such as ":8080" into a URL reachable from
+// the same host.
+func localURL(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "http://" + listen
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// apiClient calls the servprobe REST API.
+type apiClient struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// do sends a request with an optional JSON body and decodes the response
+// envelope's data into out (if non-nil).
+func (c *apiClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	var env struct {
+		Data  json.RawMessage `json:"data"`
+		Error string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("%s %s: status %d: decoding response: %w", method, path, resp.StatusCode, err)
+	}
+	if resp.StatusCode >= 300 {
+		if env.Error == "" {
+			env.Error = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("%s %s: %s (status %d)", method, path, env.Error, resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			return fmt.Errorf("decoding response data: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// compactInterval is how often completed hours are rolled up and data past
+// its retention is deleted.
+const compactInterval = 15 * time.Minute
+
+// runCompaction compacts db at startup and every compactInterval until ctx
+// is done.
+func runCompaction(ctx context.Context, db storage.Store, policy config.RetentionConfig, logger *slog.Logger) {
+	compact := func() {
+		start := time.Now()
+		if err := db.Compact(ctx, start, policy); err != nil {
+			if ctx.Err() == nil {
+				logger.Error("compacting storage", "error", err)
+			}
+			return
+		}
+		logger.Debug("storage compacted", "took", time.Since(start))
+	}
+
+	compact()
+	ticker := time.NewTicker(compactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			compact()
+		}
+	}
+}
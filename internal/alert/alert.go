@@ -1,52 +1,175 @@
 package alert
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/state"
 )
 
-// Alerter sends webhook notifications on service state changes.
+// sendTimeout bounds a single delivery to one channel.
+const sendTimeout = 30 * time.Second
+
+// Alerter sends notifications to its channels on service state changes.
 type Alerter struct {
-	webhookURL string
-	cooldown   time.Duration
-	client     *http.Client
-	lastAlert  map[string]time.Time
+	notifiers []Notifier
+	routes    []config.AlertRoute
+	lookup    func(name string) (config.Service, bool)
+	states    StateSource
+	cooldown  time.Duration
+	outbox    Outbox
+	retry     config.RetryConfig
+	wake      chan struct{}
+	lastAlert map[string]time.Time
+	// held holds state changes suppressed by the cooldown, per service.
+	held map[string]*heldBack
+	// flapping holds the services last alerted as flapping.
+	flapping map[string]bool
+	// outages holds services that went down, for reminders and escalation.
+	outages     map[string]*outage
+	outageStore OutageStore
+	reminder    time.Duration
+	escalation  []config.EscalationStep
+	// preMaint holds each service's status from before its current
+	// maintenance window, so the first check afterwards is compared against it.
+	preMaint map[string]checker.Status
+	// onDelivery is called with the outcome of every delivery attempt.
+	onDelivery func(channel, outcome string)
 	mu         sync.Mutex
 	logger     *slog.Logger
 }
 
-// New creates a new Alerter. Pass nil logger to use the default logger.
+// Outcomes of a delivery attempt, as passed to the OnDelivery callback.
+const (
+	DeliverySent = "sent"
+	// DeliveryRetry is a failed attempt that will be retried.
+	DeliveryRetry  = "retry"
+	DeliveryFailed = "failed"
+)
+
+// New creates a new Alerter that posts to a single generic webhook. Pass nil
+// logger to use the default logger. With an empty webhookURL, Notify sends
+// nothing until SetNotifiers is called.
 func New(webhookURL string, cooldown time.Duration, logger *slog.Logger) *Alerter {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	return &Alerter{
-		webhookURL: webhookURL,
-		cooldown:   cooldown,
-		client:     &http.Client{Timeout: 10 * time.Second},
-		lastAlert:  make(map[string]time.Time),
-		logger:     logger,
+	a := &Alerter{
+		cooldown:  cooldown,
+		wake:      make(chan struct{}, 1),
+		lastAlert: make(map[string]time.Time),
+		held:      make(map[string]*heldBack),
+		flapping:  make(map[string]bool),
+		outages:   make(map[string]*outage),
+		preMaint:  make(map[string]checker.Status),
+		logger:    logger,
+	}
+	if webhookURL != "" {
+		a.notifiers = []Notifier{&httpNotifier{
+			name:   "webhook",
+			client: &http.Client{Timeout: 10 * time.Second},
+			build:  webhookMessage(webhookURL, nil),
+		}}
+	}
+	return a
+}
+
+// SetNotifiers replaces the channels and cooldown. Cooldown state is kept.
+func (a *Alerter) SetNotifiers(notifiers []Notifier, cooldown time.Duration) {
+	a.mu.Lock()
+	a.notifiers = notifiers
+	a.cooldown = cooldown
+	a.mu.Unlock()
+}
+
+// SetRoutes replaces the routing rules. With no routes every channel
+// receives every alert.
+func (a *Alerter) SetRoutes(routes []config.AlertRoute) {
+	a.mu.Lock()
+	a.routes = routes
+	a.mu.Unlock()
+}
+
+// SetOnDelivery sets the callback invoked after each attempt to deliver an
+// alert, with the channel's name and the outcome.
+func (a *Alerter) SetOnDelivery(fn func(channel, outcome string)) {
+	a.mu.Lock()
+	a.onDelivery = fn
+	a.mu.Unlock()
+}
+
+// delivered reports the outcome of a delivery attempt to the OnDelivery
+// callback.
+func (a *Alerter) delivered(channel, outcome string) {
+	a.mu.Lock()
+	fn := a.onDelivery
+	a.mu.Unlock()
+	if fn != nil {
+		fn(channel, outcome)
 	}
 }
 
-type webhookPayload struct {
-	Service        string `json:"service"`
-	Status         string `json:"status"`
-	PreviousStatus string `json:"previous_status"`
-	Error          string `json:"error"`
-	ResponseTimeMs int64  `json:"response_time_ms"`
-	CheckedAt      string `json:"checked_at"`
-	Source         string `json:"source"`
+// StateSource reports the current state of a service.
+type StateSource interface {
+	Get(name string) (state.ServiceState, bool)
 }
 
-// Notify sends a webhook if the service state has changed and the cooldown has elapsed.
+// SetStateSource sets where service state is read from, so alerts about a
+// recovery can say how long the outage lasted.
+func (a *Alerter) SetStateSource(s StateSource) {
+	a.mu.Lock()
+	a.states = s
+	a.mu.Unlock()
+}
+
+// SetServiceLookup sets how service definitions are found, so routes can
+// match on tags and templates can use service metadata.
+func (a *Alerter) SetServiceLookup(lookup func(name string) (config.Service, bool)) {
+	a.mu.Lock()
+	a.lookup = lookup
+	a.mu.Unlock()
+}
+
+// Notify alerts the routed channels if the service state has changed. A
+// change within the cooldown is held back and summarized, together with any
+// later ones, when the cooldown ends. While the service is flapping its
+// transitions are not alerted; starting and stopping to flap are. Results
+// taken during maintenance never alert; once maintenance ends, the service's
+// status is compared against its status from before the window.
 func (a *Alerter) Notify(result checker.CheckResult, previousStatus *checker.Status) {
+	a.mu.Lock()
+	if result.Maintenance {
+		if _, ok := a.preMaint[result.ServiceName]; !ok && previousStatus != nil {
+			a.preMaint[result.ServiceName] = *previousStatus
+		}
+		if o := a.outages[result.ServiceName]; o != nil {
+			o.inMaintenance = true
+		}
+		a.mu.Unlock()
+		if previousStatus != nil && result.Status != *previousStatus {
+			a.logger.Info("alert suppressed by maintenance", "service", result.ServiceName)
+		}
+		return
+	}
+	if st, ok := a.preMaint[result.ServiceName]; ok {
+		delete(a.preMaint, result.ServiceName)
+		previousStatus = &st
+	}
+	if o := a.outages[result.ServiceName]; o != nil {
+		o.inMaintenance = false
+	}
+	a.mu.Unlock()
+
+	escalated := a.trackOutage(result, previousStatus)
+	if a.checkFlapping(result, previousStatus) {
+		return
+	}
+
 	// No previous status means first check — skip.
 	if previousStatus == nil {
 		return
@@ -56,49 +179,88 @@ func (a *Alerter) Notify(result checker.CheckResult, previousStatus *checker.Sta
 		return
 	}
 
-	// Check cooldown.
+	event := newEvent(result, *previousStatus)
+
 	a.mu.Lock()
+	lookup, states := a.lookup, a.states
+	a.mu.Unlock()
+	if lookup != nil {
+		if svc, ok := lookup(result.ServiceName); ok {
+			event.ServiceType = svc.Type
+			event.Target = svc.Target
+			event.Tags = svc.Tags
+		}
+	}
+	if states != nil {
+		if st, ok := states.Get(result.ServiceName); ok && st.Status == result.Status {
+			event.PreviousSince = st.PreviousSince
+		}
+	}
+
+	// Route, then check cooldown. A recovery also goes to the channels the
+	// outage was escalated to.
+	a.mu.Lock()
+	notifiers := route(a.routes, a.notifiers, event)
+	if len(escalated) > 0 {
+		wanted := make(map[string]bool)
+		for _, n := range notifiers {
+			wanted[n.Name()] = true
+		}
+		for _, ch := range escalated {
+			wanted[ch] = true
+		}
+		notifiers = selectNotifiers(a.notifiers, wanted)
+	}
+	if len(notifiers) == 0 {
+		configured := len(a.notifiers) > 0
+		a.mu.Unlock()
+		if configured {
+			a.logger.Info("alert matched no route", "service", result.ServiceName)
+		}
+		return
+	}
 	last, exists := a.lastAlert[result.ServiceName]
 	if exists && time.Since(last) < a.cooldown {
+		a.holdBack(event, escalated)
 		a.mu.Unlock()
-		a.logger.Info("alert suppressed by cooldown", "service", result.ServiceName)
+		a.logger.Info("alert held back by cooldown", "service", result.ServiceName)
+		a.wakeRun()
 		return
 	}
+	if h := a.held[result.ServiceName]; h != nil {
+		// The cooldown ended before Run summarized the held back changes;
+		// this alert reports the final status and covers them.
+		event.Suppressed = h.count
+		delete(a.held, result.ServiceName)
+	}
 	a.lastAlert[result.ServiceName] = time.Now()
 	a.mu.Unlock()
 
-	// Send asynchronously so Notify doesn't block the scheduler.
-	go a.send(result, string(*previousStatus))
+	a.dispatch(notifiers, event)
 }
 
-func (a *Alerter) send(result checker.CheckResult, prevStatus string) {
-	payload := webhookPayload{
-		Service:        result.ServiceName,
-		Status:         string(result.Status),
-		PreviousStatus: prevStatus,
-		Error:          result.Error,
-		ResponseTimeMs: result.ResponseTime.Milliseconds(),
-		CheckedAt:      result.CheckedAt.UTC().Format(time.RFC3339),
-		Source:         "servprobe",
-	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		a.logger.Error("marshaling webhook payload", "service", result.ServiceName, "error", err)
+// dispatch queues e for notifiers in the outbox or, without one, sends it
+// directly. It does not block on delivery.
+func (a *Alerter) dispatch(notifiers []Notifier, e Event) {
+	a.mu.Lock()
+	outbox := a.outbox
+	a.mu.Unlock()
+	if outbox != nil {
+		a.enqueue(outbox, notifiers, e)
 		return
 	}
-
-	resp, err := a.client.Post(a.webhookURL, "application/json", bytes.NewReader(body))
-	if err != nil {
-		a.logger.Error("sending webhook", "service", result.ServiceName, "url", a.webhookURL, "error", err)
-		return
+	for _, n := range notifiers {
+		go a.send(n, e)
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode >= 300 {
-		a.logger.Warn("webhook returned non-2xx status",
-			"service", result.ServiceName,
-			"status", resp.StatusCode,
-		)
+func (a *Alerter) send(n Notifier, e Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := n.Notify(ctx, e); err != nil {
+		a.logger.Error("sending alert", "service", e.Service, "channel", n.Name(), "error", err)
+		a.delivered(n.Name(), DeliveryFailed)
+		return
 	}
+	a.delivered(n.Name(), DeliverySent)
 }
@@ -189,3 +189,59 @@ func TestAlerter_HTTPError_DoesNotCrash(t *testing.T) {
 	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestAlerter_Maintenance_Suppresses(t *testing.T) {
+	var callCount int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&callCount, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	a := alert.New(srv.URL, 0, nil)
+
+	// Goes down during a deploy and comes back before maintenance ends.
+	down := makeResult("api", checker.StatusDown)
+	down.Maintenance = true
+	a.Notify(down, statusPtr(checker.StatusUp))
+	up := makeResult("api", checker.StatusUp)
+	up.Maintenance = true
+	a.Notify(up, statusPtr(checker.StatusDown))
+
+	// First check after maintenance: still up, compared against pre-maintenance "up".
+	a.Notify(makeResult("api", checker.StatusUp), statusPtr(checker.StatusUp))
+
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&callCount) != 0 {
+		t.Errorf("expected 0 webhook calls during maintenance, got %d", atomic.LoadInt32(&callCount))
+	}
+}
+
+func TestAlerter_Maintenance_StillDownAfterWindow(t *testing.T) {
+	var payload map[string]interface{}
+	var callCount int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		atomic.AddInt32(&callCount, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	a := alert.New(srv.URL, 0, nil)
+
+	down := makeResult("api", checker.StatusDown)
+	down.Maintenance = true
+	a.Notify(down, statusPtr(checker.StatusUp))
+
+	// Maintenance is over but the service never came back.
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusDown))
+
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt32(&callCount) != 1 {
+		t.Fatalf("expected 1 webhook call after maintenance, got %d", atomic.LoadInt32(&callCount))
+	}
+	if payload["previous_status"] != "up" {
+		t.Errorf("expected previous_status 'up' (from before maintenance), got %v", payload["previous_status"])
+	}
+}
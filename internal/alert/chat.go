@@ -0,0 +1,71 @@
+package alert
+
+import (
+	"time"
+)
+
+// slackMessage posts to a Slack incoming webhook.
+func slackMessage(url string) func(Event) (Message, error) {
+	return func(e Event) (Message, error) {
+		color := "#e01e5a"
+		if e.up() {
+			color = "#2eb886"
+		}
+		return jsonMessage(url, nil, map[string]interface{}{
+			"text": e.Title(),
+			"attachments": []map[string]interface{}{{
+				"color":  color,
+				"title":  e.Title(),
+				"text":   e.Text(),
+				"footer": "servprobe",
+				"ts":     e.CheckedAt.Unix(),
+			}},
+		})
+	}
+}
+
+// discordMessage posts an embed to a Discord webhook.
+func discordMessage(url string) func(Event) (Message, error) {
+	return func(e Event) (Message, error) {
+		color := 0xe01e5a
+		if e.up() {
+			color = 0x2eb886
+		}
+		return jsonMessage(url, nil, map[string]interface{}{
+			"username": "servprobe",
+			"embeds": []map[string]interface{}{{
+				"title":       e.Title(),
+				"description": e.Text(),
+				"color":       color,
+				"timestamp":   e.CheckedAt.UTC().Format(time.RFC3339),
+			}},
+		})
+	}
+}
+
+// teamsMessage posts a MessageCard to a Microsoft Teams incoming webhook.
+func teamsMessage(url string) func(Event) (Message, error) {
+	return func(e Event) (Message, error) {
+		color := "E01E5A"
+		if e.up() {
+			color = "2EB886"
+		}
+		facts := []map[string]string{
+			{"name": "Status", "value": string(e.Status)},
+			{"name": "Previous status", "value": string(e.PreviousStatus)},
+			{"name": "Response time", "value": e.ResponseTime.Round(time.Millisecond).String()},
+			{"name": "Checked at", "value": e.CheckedAt.UTC().Format(time.RFC3339)},
+		}
+		if e.Error != "" {
+			facts = append(facts, map[string]string{"name": "Error", "value": e.Error})
+		}
+		return jsonMessage(url, nil, map[string]interface{}{
+			"@type":      "MessageCard",
+			"@context":   "http://schema.org/extensions",
+			"themeColor": color,
+			"summary":    e.Title(),
+			"title":      e.Title(),
+			"sections":   []map[string]interface{}{{"facts": facts}},
+		})
+	}
+}
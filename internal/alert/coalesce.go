@@ -0,0 +1,111 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+)
+
+// heldBack collects the state changes of one service suppressed by the
+// cooldown, to be summarized in a single alert once the cooldown ends.
+type heldBack struct {
+	// from is the status the last alert sent reported.
+	from checker.Status
+	// last is the most recent suppressed change; its status is the one the
+	// summary reports.
+	last Event
+	// escalated lists channels an outage that ended meanwhile was escalated
+	// to, which should hear about the recovery.
+	escalated []string
+	count     int
+}
+
+// holdBack records e, suppressed by the cooldown, for the summary alert.
+// The caller must hold a.mu.
+func (a *Alerter) holdBack(e Event, escalated []string) {
+	h := a.held[e.Service]
+	if h == nil {
+		h = &heldBack{from: e.PreviousStatus}
+		a.held[e.Service] = h
+	}
+	h.last = e
+	h.count++
+	for _, ch := range escalated {
+		if !contains(h.escalated, ch) {
+			h.escalated = append(h.escalated, ch)
+		}
+	}
+}
+
+// flushHeld sends a summary for every service whose cooldown has ended at
+// now with state changes held back. The summary reports the service's final
+// status against the one last alerted, and how many changes it covers.
+func (a *Alerter) flushHeld(now time.Time) {
+	type summary struct {
+		notifiers []Notifier
+		event     Event
+	}
+	var summaries []summary
+
+	a.mu.Lock()
+	for name, h := range a.held {
+		if now.Sub(a.lastAlert[name]) < a.cooldown {
+			continue
+		}
+		delete(a.held, name)
+
+		e := h.last
+		e.Suppressed = h.count
+		if e.PreviousStatus != h.from {
+			// PreviousSince belongs to the status before the last change,
+			// not to the one last alerted.
+			e.PreviousStatus = h.from
+			e.PreviousSince = time.Time{}
+		}
+		// Route on the last actual change, so transition matchers see a
+		// real transition even when the status ends where it started.
+		wanted := make(map[string]bool)
+		for _, n := range route(a.routes, a.notifiers, h.last) {
+			wanted[n.Name()] = true
+		}
+		for _, ch := range h.escalated {
+			wanted[ch] = true
+		}
+		notifiers := selectNotifiers(a.notifiers, wanted)
+		if len(notifiers) == 0 {
+			continue
+		}
+		a.lastAlert[name] = now
+		summaries = append(summaries, summary{notifiers: notifiers, event: e})
+	}
+	a.mu.Unlock()
+
+	for _, s := range summaries {
+		a.logger.Info("cooldown ended; sending summary of held back changes",
+			"service", s.event.Service, "status", s.event.Status, "changes", s.event.Suppressed)
+		a.dispatch(s.notifiers, s.event)
+	}
+}
+
+// nextHeld returns how long until the next cooldown with held back changes
+// ends, or 0 if there is none.
+func (a *Alerter) nextHeld(now time.Time) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var next time.Duration
+	for name := range a.held {
+		d := max(a.lastAlert[name].Add(a.cooldown).Sub(now), time.Millisecond)
+		if next == 0 || d < next {
+			next = d
+		}
+	}
+	return next
+}
+
+// wakeRun makes Run re-check its work without waiting out its timer.
+func (a *Alerter) wakeRun() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
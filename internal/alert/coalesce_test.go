@@ -0,0 +1,80 @@
+package alert_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/checker"
+)
+
+func cooldownAlerter(t *testing.T, cooldown time.Duration) (*alert.Alerter, capture) {
+	t.Helper()
+	events := make(capture, 10)
+	a := alert.New("", 0, nil)
+	a.SetNotifiers([]alert.Notifier{events}, cooldown)
+	runAlerter(t, a)
+	return a, events
+}
+
+func nextEvent(t *testing.T, events capture) alert.Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert")
+		return alert.Event{}
+	}
+}
+
+func TestAlerter_CooldownSummarizesRecovery(t *testing.T) {
+	a, events := cooldownAlerter(t, 100*time.Millisecond)
+
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
+	if e := nextEvent(t, events); e.Status != checker.StatusDown || e.Suppressed != 0 {
+		t.Fatalf("expected the down alert, got %+v", e)
+	}
+
+	// A recovery within the cooldown is not lost: it is sent once the
+	// cooldown ends.
+	a.Notify(makeResult("api", checker.StatusUp), statusPtr(checker.StatusDown))
+	select {
+	case e := <-events:
+		t.Fatalf("expected the recovery to wait for the cooldown, got %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+	e := nextEvent(t, events)
+	if e.Status != checker.StatusUp || e.PreviousStatus != checker.StatusDown || e.Suppressed != 1 {
+		t.Errorf("expected a summary of the recovery, got %+v", e)
+	}
+	if e.Title() != "api is back UP" {
+		t.Errorf("Title() = %q", e.Title())
+	}
+}
+
+func TestAlerter_CooldownSummaryReportsFinalState(t *testing.T) {
+	a, events := cooldownAlerter(t, 100*time.Millisecond)
+
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
+	nextEvent(t, events)
+	a.Notify(makeResult("api", checker.StatusUp), statusPtr(checker.StatusDown))
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
+	a.Notify(makeResult("api", checker.StatusUp), statusPtr(checker.StatusDown))
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
+
+	e := nextEvent(t, events)
+	if e.Status != checker.StatusDown || e.PreviousStatus != checker.StatusDown || e.Suppressed != 4 {
+		t.Errorf("expected one summary of 4 changes ending down, got %+v", e)
+	}
+	if e.Title() != "api is DOWN again" {
+		t.Errorf("Title() = %q", e.Title())
+	}
+
+	// The summary starts a new cooldown with nothing held back.
+	select {
+	case e := <-events:
+		t.Errorf("expected no further alerts, got %+v", e)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
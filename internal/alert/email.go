@@ -0,0 +1,130 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// emailNotifier sends a plain-text email over SMTP, upgrading to TLS with
+// STARTTLS when the server offers it.
+type emailNotifier struct {
+	name     string
+	smtp     config.SMTPConfig
+	template *messageTemplate
+}
+
+func (n *emailNotifier) Name() string { return n.name }
+
+// Render returns the mail headers and body. A template's headers become
+// mail headers, so it can override the Subject.
+func (n *emailNotifier) Render(e Event) (Message, error) {
+	header := http.Header{}
+	header.Set("From", n.smtp.From)
+	header.Set("To", strings.Join(n.smtp.To, ", "))
+	header.Set("Subject", "[servprobe] "+e.Title())
+	header.Set("Content-Type", "text/plain; charset=UTF-8")
+	msg := Message{
+		URL:    "smtp://" + net.JoinHostPort(n.smtp.Host, strconv.Itoa(n.smtp.Port)),
+		Header: header,
+		Body:   []byte(e.Text() + "\n"),
+	}
+	if n.template == nil {
+		return msg, nil
+	}
+	return n.template.apply(msg, e)
+}
+
+func (n *emailNotifier) Notify(ctx context.Context, e Event) error {
+	msg, err := n.Render(e)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(n.smtp.Host, strconv.Itoa(n.smtp.Port))
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, n.smtp.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("starting SMTP session: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.smtp.Host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if n.smtp.Username != "" {
+		auth := smtp.PlainAuth("", n.smtp.Username, n.smtp.Password, n.smtp.Host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	if err := c.Mail(n.smtp.From); err != nil {
+		return fmt.Errorf("MAIL FROM: %w", err)
+	}
+	for _, to := range n.smtp.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("RCPT TO %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("DATA: %w", err)
+	}
+	if _, err := w.Write(mailBytes(msg)); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+	return c.Quit()
+}
+
+// mailBytes formats msg as an RFC 5322 message with CRLF line endings.
+func mailBytes(msg Message) []byte {
+	var b bytes.Buffer
+	keys := make([]string, 0, len(msg.Header))
+	for k := range msg.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.ContainsAny(k, "\r\n:") {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\r\n", k, headerValue(msg.Header.Get(k)))
+	}
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n\r\n")
+	body := strings.ReplaceAll(string(msg.Body), "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// headerValue makes v safe as a header value. Service names, tags and
+// templates end up in the Subject, so line breaks, which would start a new
+// header or the body, become spaces; non-ASCII text is encoded per RFC 2047.
+func headerValue(v string) string {
+	v = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(v)
+	return mime.QEncoding.Encode("utf-8", v)
+}
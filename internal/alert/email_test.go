@@ -0,0 +1,165 @@
+package alert_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// smtpMessage is what the SMTP stand-in received in one session.
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+	auth bool
+}
+
+// smtpStandIn accepts one SMTP session on a local port and reports the
+// message it received. It speaks just enough of the protocol for net/smtp.
+func smtpStandIn(t *testing.T) (host string, port int, msgs <-chan smtpMessage) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan smtpMessage, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		var msg smtpMessage
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case strings.HasPrefix(cmd, "AUTH"):
+				msg.auth = true
+				reply("235 2.7.0 Authentication successful")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				msg.data = data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				out <- msg
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, out
+}
+
+func TestNotifier_Email(t *testing.T) {
+	host, port, msgs := smtpStandIn(t)
+	n := notifierFor(t, config.ChannelConfig{Name: "ops-mail", Type: "email", SMTP: config.SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: "servprobe",
+		Password: "secret",
+		From:     "servprobe@example.com",
+		To:       []string{"ops@example.com", "oncall@example.com"},
+	}})
+
+	if err := n.Notify(context.Background(), downEvent()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	msg := <-msgs
+	if !msg.auth {
+		t.Error("expected client to authenticate")
+	}
+	if msg.from != "servprobe@example.com" {
+		t.Errorf("unexpected sender %q", msg.from)
+	}
+	if len(msg.to) != 2 || msg.to[1] != "oncall@example.com" {
+		t.Errorf("unexpected recipients %v", msg.to)
+	}
+	for _, want := range []string{"Subject: [servprobe] api is DOWN", "To: ops@example.com, oncall@example.com", "Error: connection refused"} {
+		if !strings.Contains(msg.data, want) {
+			t.Errorf("message missing %q:\n%s", want, msg.data)
+		}
+	}
+}
+
+func TestNotifier_EmailHeaderInjection(t *testing.T) {
+	host, port, msgs := smtpStandIn(t)
+	n := notifierFor(t, config.ChannelConfig{Name: "ops-mail", Type: "email", SMTP: config.SMTPConfig{
+		Host: host,
+		Port: port,
+		From: "servprobe@example.com",
+		To:   []string{"ops@example.com"},
+	}})
+
+	e := downEvent()
+	e.Service = "api\r\nBcc: attacker@example.com\n\nfake body"
+	if err := n.Notify(context.Background(), e); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	msg := <-msgs
+	headers, _, _ := strings.Cut(msg.data, "\r\n\r\n")
+	for _, line := range strings.Split(headers, "\r\n") {
+		if strings.HasPrefix(line, "Bcc:") || strings.HasPrefix(line, "fake body") {
+			t.Errorf("service name injected a header line %q:\n%s", line, msg.data)
+		}
+	}
+	if !strings.Contains(headers, "Subject: [servprobe] api Bcc: attacker@example.com  fake body is DOWN") {
+		t.Errorf("expected the line breaks to become spaces:\n%s", headers)
+	}
+}
+
+func TestNotifier_EmailConnectionError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	n := notifierFor(t, config.ChannelConfig{Name: "mail", Type: "email", SMTP: config.SMTPConfig{
+		Host: "127.0.0.1", Port: port, From: "a@example.com", To: []string{"b@example.com"},
+	}})
+	err = n.Notify(context.Background(), downEvent())
+	if err == nil || !strings.Contains(err.Error(), "127.0.0.1:"+strconv.Itoa(port)) {
+		t.Errorf("expected connection error naming the address, got %v", err)
+	}
+}
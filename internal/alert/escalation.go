@@ -0,0 +1,272 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// OutageStore persists the reminder and escalation state of ongoing outages.
+type OutageStore interface {
+	SaveOutage(ctx context.Context, o storage.AlertOutage) error
+	DeleteOutage(ctx context.Context, service string) error
+	ListOutages(ctx context.Context) ([]storage.AlertOutage, error)
+}
+
+// outage is an ongoing outage of one service.
+type outage struct {
+	storage.AlertOutage
+	// inMaintenance is set while the service's checks fall in a maintenance
+	// window; reminders and escalation wait until it ends.
+	inMaintenance bool
+	// held is set by remind while reminders wait for the service to leave
+	// maintenance, be resumed or stop flapping. nextReminder leaves such
+	// outages out, and Run's regular poll notices when the wait is over.
+	held bool
+}
+
+// SetEscalation sets the reminder interval and escalation steps for ongoing
+// outages. A zero interval disables reminders.
+func (a *Alerter) SetEscalation(reminder time.Duration, steps []config.EscalationStep) {
+	a.mu.Lock()
+	a.reminder = reminder
+	a.escalation = steps
+	a.mu.Unlock()
+}
+
+// SetOutageStore sets where outage state is persisted.
+func (a *Alerter) SetOutageStore(s OutageStore) {
+	a.mu.Lock()
+	a.outageStore = s
+	a.mu.Unlock()
+}
+
+// LoadOutages restores the outages stored by a previous run, so reminders and
+// escalation continue where they left off.
+func (a *Alerter) LoadOutages(ctx context.Context) error {
+	a.mu.Lock()
+	store := a.outageStore
+	a.mu.Unlock()
+	if store == nil {
+		return nil
+	}
+	stored, err := store.ListOutages(ctx)
+	if err != nil {
+		return fmt.Errorf("loading outages: %w", err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, o := range stored {
+		a.outages[o.Service] = &outage{AlertOutage: o}
+	}
+	return nil
+}
+
+// trackOutage starts, updates or ends the outage of result's service. It
+// returns the channels escalated to during an outage that result ended.
+func (a *Alerter) trackOutage(result checker.CheckResult, prev *checker.Status) (escalated []string) {
+	name := result.ServiceName
+	a.mu.Lock()
+	store := a.outageStore
+	o := a.outages[name]
+	var save *storage.AlertOutage
+	ended := false
+	switch {
+	case result.Status == checker.StatusDown && o == nil && prev != nil && *prev == checker.StatusUp:
+		o = &outage{AlertOutage: storage.AlertOutage{
+			Service:        name,
+			StartedAt:      result.CheckedAt,
+			Error:          result.Error,
+			LastNotifiedAt: result.CheckedAt,
+		}}
+		a.outages[name] = o
+		saved := o.AlertOutage
+		save = &saved
+	case result.Status == checker.StatusDown && o != nil:
+		o.Error = result.Error
+	case result.Status == checker.StatusUp && o != nil:
+		// Also covers a service that recovered while servprobe was stopped.
+		delete(a.outages, name)
+		escalated = o.Escalated
+		ended = true
+	}
+	a.mu.Unlock()
+
+	if store == nil {
+		return escalated
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if save != nil {
+		if err := store.SaveOutage(ctx, *save); err != nil {
+			a.logger.Error("saving outage", "service", name, "error", err)
+		}
+	}
+	if ended {
+		if err := store.DeleteOutage(ctx, name); err != nil {
+			a.logger.Error("deleting outage", "service", name, "error", err)
+		}
+	}
+	return escalated
+}
+
+// remind sends the reminders and escalations that are due at now.
+func (a *Alerter) remind(now time.Time) {
+	a.mu.Lock()
+	lookup, store := a.lookup, a.outageStore
+	names := make([]string, 0, len(a.outages))
+	for name := range a.outages {
+		names = append(names, name)
+	}
+	a.mu.Unlock()
+	if len(names) == 0 {
+		return
+	}
+
+	// Look services up without holding the lock; see Notify.
+	services := make(map[string]config.Service, len(names))
+	var removed []string
+	for _, name := range names {
+		if lookup == nil {
+			continue
+		}
+		svc, ok := lookup(name)
+		if !ok {
+			removed = append(removed, name)
+			continue
+		}
+		services[name] = svc
+	}
+
+	type delivery struct {
+		notifiers []Notifier
+		event     Event
+	}
+	var (
+		deliveries []delivery
+		saves      []storage.AlertOutage
+	)
+	a.mu.Lock()
+	for _, name := range removed {
+		delete(a.outages, name)
+	}
+	for name, o := range a.outages {
+		svc := services[name]
+		o.held = o.inMaintenance || svc.Paused || a.flapping[name]
+		if o.held {
+			continue
+		}
+		e := Event{
+			Service:        name,
+			ServiceType:    svc.Type,
+			Target:         svc.Target,
+			Tags:           svc.Tags,
+			Status:         checker.StatusDown,
+			PreviousStatus: checker.StatusDown,
+			PreviousSince:  o.StartedAt,
+			Error:          o.Error,
+			CheckedAt:      now,
+			Reminder:       true,
+		}
+
+		wanted := make(map[string]bool)
+		if a.reminder > 0 && now.Sub(o.LastNotifiedAt) >= a.reminder {
+			// Remind the channels the outage was first routed to and
+			// those it has been escalated to.
+			first := e
+			first.PreviousStatus = checker.StatusUp
+			for _, n := range route(a.routes, a.notifiers, first) {
+				wanted[n.Name()] = true
+			}
+			for _, ch := range o.Escalated {
+				wanted[ch] = true
+			}
+			o.LastNotifiedAt = now
+		}
+		for o.EscalationStep < len(a.escalation) && now.Sub(o.StartedAt) >= a.escalation[o.EscalationStep].After.Duration {
+			for _, ch := range a.escalation[o.EscalationStep].Channels {
+				wanted[ch] = true
+				if !contains(o.Escalated, ch) {
+					o.Escalated = append(o.Escalated, ch)
+				}
+			}
+			o.EscalationStep++
+			o.LastNotifiedAt = now
+		}
+		if len(wanted) == 0 {
+			continue
+		}
+		deliveries = append(deliveries, delivery{notifiers: selectNotifiers(a.notifiers, wanted), event: e})
+		saves = append(saves, o.AlertOutage)
+	}
+	a.mu.Unlock()
+
+	if store != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, name := range removed {
+			if err := store.DeleteOutage(ctx, name); err != nil {
+				a.logger.Error("deleting outage", "service", name, "error", err)
+			}
+		}
+		for _, o := range saves {
+			if err := store.SaveOutage(ctx, o); err != nil {
+				a.logger.Error("saving outage", "service", o.Service, "error", err)
+			}
+		}
+	}
+	for _, d := range deliveries {
+		a.logger.Info("service still down; sending reminder", "service", d.event.Service, "down_for", d.event.OutageDuration().Round(time.Second))
+		a.dispatch(d.notifiers, d.event)
+	}
+}
+
+// nextReminder returns how long until the next reminder or escalation is
+// due, or 0 if none is pending. Held outages don't count: their reminders
+// may be overdue, and waking for them would only spin.
+func (a *Alerter) nextReminder(now time.Time) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var next time.Duration
+	consider := func(at time.Time) {
+		d := max(at.Sub(now), time.Millisecond)
+		if next == 0 || d < next {
+			next = d
+		}
+	}
+	for name, o := range a.outages {
+		if o.held || o.inMaintenance || a.flapping[name] {
+			continue
+		}
+		if a.reminder > 0 {
+			consider(o.LastNotifiedAt.Add(a.reminder))
+		}
+		if o.EscalationStep < len(a.escalation) {
+			consider(o.StartedAt.Add(a.escalation[o.EscalationStep].After.Duration))
+		}
+	}
+	return next
+}
+
+func selectNotifiers(notifiers []Notifier, names map[string]bool) []Notifier {
+	var selected []Notifier
+	for _, n := range notifiers {
+		if names[n.Name()] {
+			selected = append(selected, n)
+		}
+	}
+	return selected
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
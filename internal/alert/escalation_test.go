@@ -0,0 +1,225 @@
+package alert_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// memOutages is an in-memory alert.OutageStore.
+type memOutages struct {
+	mu   sync.Mutex
+	rows map[string]storage.AlertOutage
+}
+
+func newMemOutages(rows ...storage.AlertOutage) *memOutages {
+	m := &memOutages{rows: make(map[string]storage.AlertOutage)}
+	for _, o := range rows {
+		m.rows[o.Service] = o
+	}
+	return m
+}
+
+func (m *memOutages) SaveOutage(_ context.Context, o storage.AlertOutage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows[o.Service] = o
+	return nil
+}
+
+func (m *memOutages) DeleteOutage(_ context.Context, service string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rows, service)
+	return nil
+}
+
+func (m *memOutages) ListOutages(context.Context) ([]storage.AlertOutage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []storage.AlertOutage
+	for _, o := range m.rows {
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+func (m *memOutages) get(service string) (storage.AlertOutage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.rows[service]
+	return o, ok
+}
+
+func runAlerter(t *testing.T, a *alert.Alerter) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func count(channels []string, name string) int {
+	n := 0
+	for _, ch := range channels {
+		if ch == name {
+			n++
+		}
+	}
+	return n
+}
+
+// chatOnly routes every alert to the chat channel, leaving oncall for
+// escalation.
+var chatOnly = []config.AlertRoute{{Name: "all", Channels: []string{"chat"}}}
+
+func TestAlerter_RemindsAndEscalates(t *testing.T) {
+	a, log := routedAlerter(chatOnly)
+	store := newMemOutages()
+	a.SetOutageStore(store)
+	a.SetEscalation(40*time.Millisecond, []config.EscalationStep{
+		{After: config.Duration{Duration: 100 * time.Millisecond}, Channels: []string{"oncall"}},
+	})
+	runAlerter(t, a)
+
+	a.Notify(makeResult("web", checker.StatusDown), statusPtr(checker.StatusUp))
+	if _, ok := store.get("web"); !ok {
+		t.Fatal("expected the outage to be stored")
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	sent := log.channels()
+	if count(sent, "chat") < 3 {
+		t.Errorf("expected the alert and at least 2 reminders on chat, got %v", sent)
+	}
+	if n := count(sent, "oncall"); n < 1 {
+		t.Errorf("expected the outage to be escalated to oncall, got %v", sent)
+	}
+	if o, _ := store.get("web"); o.EscalationStep != 1 || len(o.Escalated) != 1 || o.Escalated[0] != "oncall" {
+		t.Errorf("expected escalation state to be stored, got %+v", o)
+	}
+
+	// The recovery goes to the escalated channel too, and reminders stop.
+	before := count(log.channels(), "oncall")
+	a.Notify(makeResult("web", checker.StatusUp), statusPtr(checker.StatusDown))
+	if n := count(log.channels(), "oncall"); n != before+1 {
+		t.Errorf("expected oncall to get the recovery, got %d deliveries (was %d)", n, before)
+	}
+	if _, ok := store.get("web"); ok {
+		t.Error("expected the outage to be deleted on recovery")
+	}
+	settled := len(log.channels())
+	time.Sleep(100 * time.Millisecond)
+	if n := len(log.channels()); n != settled {
+		t.Errorf("expected no reminders after recovery, got %d more", n-settled)
+	}
+}
+
+func TestAlerter_EscalationResumesFromStore(t *testing.T) {
+	started := time.Now().Add(-time.Hour)
+	store := newMemOutages(storage.AlertOutage{
+		Service:        "web",
+		StartedAt:      started,
+		Error:          "refused",
+		LastNotifiedAt: started,
+		EscalationStep: 1,
+		Escalated:      []string{"lowprio"},
+	})
+	a, log := routedAlerter(chatOnly)
+	a.SetOutageStore(store)
+	a.SetEscalation(0, []config.EscalationStep{
+		{After: config.Duration{Duration: 10 * time.Minute}, Channels: []string{"lowprio"}},
+		{After: config.Duration{Duration: 30 * time.Minute}, Channels: []string{"oncall"}},
+	})
+	if err := a.LoadOutages(context.Background()); err != nil {
+		t.Fatalf("LoadOutages: %v", err)
+	}
+	runAlerter(t, a)
+
+	// Only the second step is still due; the first was taken before the restart.
+	if sent := log.channels(); !equal(sent, []string{"oncall"}) {
+		t.Errorf("expected only oncall to be notified, got %v", sent)
+	}
+	if o, _ := store.get("web"); o.EscalationStep != 2 || !equal(o.Escalated, []string{"lowprio", "oncall"}) {
+		t.Errorf("unexpected stored outage: %+v", o)
+	}
+}
+
+func TestAlerter_NoRemindersDuringMaintenance(t *testing.T) {
+	a, log := routedAlerter(chatOnly)
+	a.SetEscalation(30*time.Millisecond, nil)
+	runAlerter(t, a)
+
+	a.Notify(makeResult("web", checker.StatusDown), statusPtr(checker.StatusUp))
+	maint := makeResult("web", checker.StatusDown)
+	maint.Maintenance = true
+	a.Notify(maint, statusPtr(checker.StatusDown))
+	time.Sleep(100 * time.Millisecond)
+
+	if sent := log.channels(); !equal(sent, []string{"chat"}) {
+		t.Errorf("expected only the initial alert, got %v", sent)
+	}
+}
+
+func TestAlerter_NoBusyLoopWhileRemindersHeld(t *testing.T) {
+	down := statusPtr(checker.StatusDown)
+	maint := makeResult("web", checker.StatusDown)
+	maint.Maintenance = true
+	tests := []struct {
+		name  string
+		setup func(a *alert.Alerter)
+	}{
+		{"paused", func(a *alert.Alerter) {
+			a.SetServiceLookup(func(name string) (config.Service, bool) {
+				return config.Service{Name: name, Paused: true}, true
+			})
+		}},
+		{"maintenance", func(a *alert.Alerter) {
+			a.Notify(maint, down)
+		}},
+		{"flapping", func(a *alert.Alerter) {
+			a.SetStateSource(stateSource{"web": {Status: checker.StatusDown, Flapping: true}})
+			a.Notify(makeResult("web", checker.StatusDown), down)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The reminder is long overdue, but held back.
+			started := time.Now().Add(-time.Hour)
+			a, _ := routedAlerter(chatOnly)
+			a.SetOutageStore(newMemOutages(storage.AlertOutage{
+				Service:        "web",
+				StartedAt:      started,
+				LastNotifiedAt: started,
+			}))
+			if err := a.LoadOutages(context.Background()); err != nil {
+				t.Fatalf("LoadOutages: %v", err)
+			}
+			a.SetEscalation(10*time.Minute, nil)
+			outbox := &memOutbox{}
+			a.SetOutbox(outbox)
+			tt.setup(a)
+			runAlerter(t, a)
+			time.Sleep(200 * time.Millisecond)
+
+			outbox.mu.Lock()
+			claims := outbox.claims
+			outbox.mu.Unlock()
+			if claims > 3 {
+				t.Errorf("expected Run to wait for the outbox poll, got %d claims in 200ms", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+)
+
+// Values of Event.Flapping.
+const (
+	FlappingStarted = "started"
+	FlappingStopped = "stopped"
+)
+
+// checkFlapping alerts when result's service starts or stops flapping, as
+// reported by the state source. It reports whether result's own transition,
+// if any, must not be alerted: the flapping alert stands in for it, or the
+// service is flapping.
+func (a *Alerter) checkFlapping(result checker.CheckResult, prev *checker.Status) bool {
+	a.mu.Lock()
+	states, lookup := a.states, a.lookup
+	a.mu.Unlock()
+	if states == nil {
+		return false
+	}
+	st, ok := states.Get(result.ServiceName)
+	if !ok || st.Status != result.Status {
+		return false
+	}
+
+	name := result.ServiceName
+	a.mu.Lock()
+	was := a.flapping[name]
+	if st.Flapping == was {
+		a.mu.Unlock()
+		if was && prev != nil && *prev != result.Status {
+			a.logger.Info("alert suppressed: service is flapping", "service", name, "status", result.Status)
+		}
+		return was
+	}
+	if st.Flapping {
+		a.flapping[name] = true
+	} else {
+		delete(a.flapping, name)
+	}
+	// The flapping alert reports the current status, so changes held back
+	// by the cooldown are covered by it.
+	delete(a.held, name)
+	a.lastAlert[name] = time.Now()
+	a.mu.Unlock()
+
+	e := newEvent(result, result.Status)
+	if prev != nil {
+		e.PreviousStatus = *prev
+	}
+	e.StateChange = st.StateChange
+	e.Flapping = FlappingStopped
+	if st.Flapping {
+		e.Flapping = FlappingStarted
+	}
+	if lookup != nil {
+		if svc, ok := lookup(name); ok {
+			e.ServiceType = svc.Type
+			e.Target = svc.Target
+			e.Tags = svc.Tags
+		}
+	}
+
+	// Route like a change to the current status, so transition matchers
+	// apply even when the status did not change on this check.
+	routed := e
+	routed.PreviousStatus = opposite(e.Status)
+	a.mu.Lock()
+	notifiers := route(a.routes, a.notifiers, routed)
+	a.mu.Unlock()
+
+	a.logger.Info("service flapping "+e.Flapping, "service", name, "state_change", st.StateChange)
+	if len(notifiers) > 0 {
+		a.dispatch(notifiers, e)
+	}
+	return true
+}
+
+func opposite(s checker.Status) checker.Status {
+	if s == checker.StatusUp {
+		return checker.StatusDown
+	}
+	return checker.StatusUp
+}
@@ -0,0 +1,99 @@
+package alert_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/state"
+)
+
+func TestAlerter_Flapping(t *testing.T) {
+	tracker := state.New()
+	tracker.SetFlapping(config.FlappingConfig{Window: 11, LowThreshold: 25, HighThreshold: 50})
+	events := make(capture, 50)
+	a := alert.New("", 0, nil)
+	a.SetNotifiers([]alert.Notifier{events}, 0)
+	a.SetStateSource(tracker)
+
+	// check records a result and notifies the alerter, as the scheduler does.
+	t0 := time.Now()
+	n := 0
+	check := func(status checker.Status) {
+		result := makeResult("api", status)
+		result.CheckedAt = t0.Add(time.Duration(n) * time.Minute)
+		n++
+		prev, ok := tracker.Record(result)
+		if ok {
+			a.Notify(result, &prev.Status)
+		} else {
+			a.Notify(result, nil)
+		}
+	}
+	drain := func() []alert.Event {
+		time.Sleep(50 * time.Millisecond)
+		var out []alert.Event
+		for {
+			select {
+			case e := <-events:
+				out = append(out, e)
+			default:
+				return out
+			}
+		}
+	}
+
+	for i := 0; i < 12; i++ {
+		if i%2 == 0 {
+			check(checker.StatusUp)
+		} else {
+			check(checker.StatusDown)
+		}
+	}
+	// Alerts are sent concurrently, so find the flapping one by content.
+	sent := drain()
+	var started []alert.Event
+	for _, e := range sent {
+		if e.Flapping != "" {
+			started = append(started, e)
+		}
+	}
+	if len(started) != 1 {
+		t.Fatalf("expected a single flapping alert, got %+v", sent)
+	}
+	if e := started[0]; e.Flapping != alert.FlappingStarted || e.Title() != "api is flapping" || e.StateChange < 50 {
+		t.Fatalf("expected an alert that api started flapping, got %+v", e)
+	}
+	for _, e := range sent {
+		if e.CheckedAt.After(started[0].CheckedAt) {
+			t.Errorf("expected transitions after flapping started to be suppressed, got %+v", e)
+		}
+	}
+
+	// More transitions while flapping alert nothing.
+	check(checker.StatusUp)
+	check(checker.StatusDown)
+	if sent := drain(); len(sent) != 0 {
+		t.Errorf("expected no alerts while flapping, got %+v", sent)
+	}
+
+	// Steady checks end flapping with one alert giving the final status.
+	for i := 0; i < 11; i++ {
+		check(checker.StatusDown)
+	}
+	sent = drain()
+	if len(sent) != 1 || sent[0].Flapping != alert.FlappingStopped || sent[0].Status != checker.StatusDown {
+		t.Fatalf("expected one alert that api stopped flapping while down, got %+v", sent)
+	}
+	if got := sent[0].Title(); got != "api stopped flapping and is DOWN" {
+		t.Errorf("Title() = %q", got)
+	}
+
+	// Afterwards transitions alert as usual.
+	check(checker.StatusUp)
+	if sent := drain(); len(sent) != 1 || sent[0].Flapping != "" || sent[0].Status != checker.StatusUp {
+		t.Errorf("expected a normal recovery alert, got %+v", sent)
+	}
+}
@@ -0,0 +1,273 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// Notifier delivers alerts to one channel.
+type Notifier interface {
+	// Name is the channel name from the config.
+	Name() string
+	// Notify delivers e, returning an error if the channel rejected it.
+	Notify(ctx context.Context, e Event) error
+}
+
+// Renderer is implemented by notifiers that can show what they would send
+// for an event without sending it.
+type Renderer interface {
+	Render(e Event) (Message, error)
+}
+
+// Message is what a channel sends: an HTTP request, or for email the mail
+// headers and body.
+type Message struct {
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Event is a service state change to notify about.
+type Event struct {
+	Service        string         `json:"service"`
+	ServiceType    string         `json:"service_type"`
+	Target         string         `json:"target"`
+	Tags           []string       `json:"tags"`
+	Status         checker.Status `json:"status"`
+	PreviousStatus checker.Status `json:"previous_status"`
+	// PreviousSince is when the service got its previous status, if known.
+	PreviousSince time.Time     `json:"previous_since"`
+	Error         string        `json:"error"`
+	ResponseTime  time.Duration `json:"response_time"`
+	CheckedAt     time.Time     `json:"checked_at"`
+	// Reminder is set for repeated alerts about an ongoing outage,
+	// including escalations.
+	Reminder bool `json:"reminder"`
+	// Suppressed is the number of state changes held back by the cooldown
+	// that this alert summarizes. PreviousStatus is then the status the last
+	// alert reported.
+	Suppressed int `json:"suppressed"`
+	// Flapping is FlappingStarted or FlappingStopped for alerts about the
+	// service starting or stopping to flap, with StateChange the percent
+	// state change that triggered it.
+	Flapping    string  `json:"flapping"`
+	StateChange float64 `json:"state_change"`
+}
+
+// Title is a one-line summary of e, e.g. "api is DOWN".
+func (e Event) Title() string {
+	switch e.Flapping {
+	case FlappingStarted:
+		return fmt.Sprintf("%s is flapping", e.Service)
+	case FlappingStopped:
+		return fmt.Sprintf("%s stopped flapping and is %s", e.Service, strings.ToUpper(string(e.Status)))
+	}
+	if e.Reminder {
+		return fmt.Sprintf("%s is still %s", e.Service, strings.ToUpper(string(e.Status)))
+	}
+	if e.Status == e.PreviousStatus {
+		return fmt.Sprintf("%s is %s again", e.Service, strings.ToUpper(string(e.Status)))
+	}
+	if e.Status == checker.StatusUp {
+		return fmt.Sprintf("%s is back UP", e.Service)
+	}
+	return fmt.Sprintf("%s is %s", e.Service, strings.ToUpper(string(e.Status)))
+}
+
+// Text is a plain-text description of e, one fact per line.
+func (e Event) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Status: %s (was %s)\n", e.Status, e.PreviousStatus)
+	if e.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", e.Error)
+	}
+	if d := e.OutageDuration(); d > 0 {
+		fmt.Fprintf(&b, "Down for: %s\n", d.Round(time.Second))
+	}
+	if e.Flapping != "" {
+		fmt.Fprintf(&b, "State change: %.0f%% of recent checks\n", e.StateChange)
+	}
+	if e.Suppressed > 0 {
+		fmt.Fprintf(&b, "Changes during cooldown: %d\n", e.Suppressed)
+	}
+	fmt.Fprintf(&b, "Response time: %s\n", e.ResponseTime.Round(time.Millisecond))
+	fmt.Fprintf(&b, "Checked at: %s", e.CheckedAt.UTC().Format(time.RFC3339))
+	return b.String()
+}
+
+// OutageDuration is how long the service has been down, for a recovery or
+// reminder whose outage start is known. It is zero otherwise.
+func (e Event) OutageDuration() time.Duration {
+	if e.PreviousStatus != checker.StatusDown || e.PreviousSince.IsZero() {
+		return 0
+	}
+	return e.CheckedAt.Sub(e.PreviousSince)
+}
+
+func (e Event) up() bool {
+	return e.Status == checker.StatusUp
+}
+
+func newEvent(result checker.CheckResult, prev checker.Status) Event {
+	return Event{
+		Service:        result.ServiceName,
+		Status:         result.Status,
+		PreviousStatus: prev,
+		Error:          result.Error,
+		ResponseTime:   result.ResponseTime,
+		CheckedAt:      result.CheckedAt,
+	}
+}
+
+// SampleEvent returns a made-up event for svc with the given status, for
+// previewing channel messages.
+func SampleEvent(svc config.Service, status checker.Status) Event {
+	now := time.Now()
+	e := Event{
+		Service:        svc.Name,
+		ServiceType:    svc.Type,
+		Target:         svc.Target,
+		Tags:           svc.Tags,
+		Status:         status,
+		PreviousStatus: checker.StatusUp,
+		PreviousSince:  now.Add(-3 * time.Hour),
+		Error:          "connection refused",
+		ResponseTime:   1234 * time.Millisecond,
+		CheckedAt:      now,
+	}
+	if status == checker.StatusUp {
+		e.PreviousStatus = checker.StatusDown
+		e.PreviousSince = now.Add(-12 * time.Minute)
+		e.Error = ""
+		e.ResponseTime = 87 * time.Millisecond
+	}
+	return e
+}
+
+// NewNotifiers builds a Notifier for every channel in cfg. cfg must have
+// been validated by config.Load.
+func NewNotifiers(cfg config.AlertsConfig) ([]Notifier, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	notifiers := make([]Notifier, 0, len(cfg.Channels))
+	for _, ch := range cfg.Channels {
+		n, err := newNotifier(ch, cfg.DashboardURL, client)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+func newNotifier(ch config.ChannelConfig, dashboardURL string, client *http.Client) (Notifier, error) {
+	var tmpl *messageTemplate
+	if ch.Template != nil {
+		var err error
+		if tmpl, err = newMessageTemplate(*ch.Template, dashboardURL); err != nil {
+			return nil, fmt.Errorf("alert channel %q: %w", ch.Name, err)
+		}
+	}
+
+	var build func(Event) (Message, error)
+	var auth *webhookAuth
+	switch ch.Type {
+	case "webhook":
+		build = webhookMessage(ch.URL, ch.Headers)
+		if ch.Auth != nil || ch.Secret != "" {
+			auth = &webhookAuth{auth: ch.Auth, secret: ch.Secret}
+		}
+	case "slack":
+		build = slackMessage(ch.URL)
+	case "discord":
+		build = discordMessage(ch.URL)
+	case "teams":
+		build = teamsMessage(ch.URL)
+	case "telegram":
+		base := ch.URL
+		if base == "" {
+			base = "https://api.telegram.org"
+		}
+		build = telegramMessage(strings.TrimRight(base, "/"), ch.Token, ch.ChatID)
+	case "gotify":
+		build = gotifyMessage(strings.TrimRight(ch.URL, "/"), ch.Token)
+	case "ntfy":
+		build = ntfyMessage(ch.URL, ch.Token)
+	case "email":
+		return &emailNotifier{name: ch.Name, smtp: ch.SMTP, template: tmpl}, nil
+	default:
+		return nil, fmt.Errorf("alert channel %q: unsupported type %q", ch.Name, ch.Type)
+	}
+	return &httpNotifier{name: ch.Name, client: client, build: build, template: tmpl, auth: auth}, nil
+}
+
+// httpNotifier POSTs the message built for each event, rewritten by the
+// channel's template if it has one and then authenticated.
+type httpNotifier struct {
+	name     string
+	client   *http.Client
+	build    func(Event) (Message, error)
+	template *messageTemplate
+	auth     *webhookAuth
+}
+
+func (n *httpNotifier) Name() string { return n.name }
+
+func (n *httpNotifier) Render(e Event) (Message, error) {
+	msg, err := n.build(e)
+	if err != nil {
+		return msg, err
+	}
+	if n.template != nil {
+		if msg, err = n.template.apply(msg, e); err != nil {
+			return msg, err
+		}
+	}
+	if n.auth != nil {
+		n.auth.apply(&msg, time.Now())
+	}
+	return msg, nil
+}
+
+func (n *httpNotifier) Notify(ctx context.Context, e Event) error {
+	msg, err := n.Render(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.URL, bytes.NewReader(msg.Body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header = msg.Header
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// jsonMessage builds a message with v encoded as its JSON body.
+func jsonMessage(url string, header http.Header, v interface{}) (Message, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return Message{}, fmt.Errorf("encoding payload: %w", err)
+	}
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json")
+	return Message{URL: url, Header: header, Body: body}, nil
+}
@@ -0,0 +1,315 @@
+package alert_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/alert/signature"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// captured is a request received by a stand-in server.
+type captured struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// standIn starts a server that records each request and answers with status.
+func standIn(t *testing.T, status int) (*httptest.Server, <-chan captured) {
+	t.Helper()
+	reqs := make(chan captured, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- captured{path: r.URL.Path, header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, reqs
+}
+
+func downEvent() alert.Event {
+	return alert.Event{
+		Service:        "api",
+		Status:         checker.StatusDown,
+		PreviousStatus: checker.StatusUp,
+		Error:          "connection refused",
+		ResponseTime:   42 * time.Millisecond,
+		CheckedAt:      time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func notifierFor(t *testing.T, ch config.ChannelConfig) alert.Notifier {
+	t.Helper()
+	notifiers, err := alert.NewNotifiers(config.AlertsConfig{Channels: []config.ChannelConfig{ch}})
+	if err != nil {
+		t.Fatalf("NewNotifiers: %v", err)
+	}
+	if len(notifiers) != 1 || notifiers[0].Name() != ch.Name {
+		t.Fatalf("expected one notifier named %q, got %v", ch.Name, notifiers)
+	}
+	return notifiers[0]
+}
+
+func notifyOnce(t *testing.T, n alert.Notifier, reqs <-chan captured) captured {
+	t.Helper()
+	if err := n.Notify(context.Background(), downEvent()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	select {
+	case req := <-reqs:
+		return req
+	default:
+		t.Fatal("stand-in received no request")
+		return captured{}
+	}
+}
+
+func decodeBody(t *testing.T, req captured) map[string]interface{} {
+	t.Helper()
+	if ct := req.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(req.body, &m); err != nil {
+		t.Fatalf("decoding body %q: %v", req.body, err)
+	}
+	return m
+}
+
+func TestEvent_TitleAndText(t *testing.T) {
+	e := downEvent()
+	if got := e.Title(); got != "api is DOWN" {
+		t.Errorf("Title() = %q", got)
+	}
+	text := e.Text()
+	for _, want := range []string{"Status: down (was up)", "Error: connection refused", "Response time: 42ms", "2026-03-01T12:00:00Z"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+
+	e.Status, e.PreviousStatus, e.Error = checker.StatusUp, checker.StatusDown, ""
+	if got := e.Title(); got != "api is back UP" {
+		t.Errorf("Title() for recovery = %q", got)
+	}
+	if strings.Contains(e.Text(), "Error:") {
+		t.Errorf("expected no error line for recovery:\n%s", e.Text())
+	}
+	reminder := downEvent()
+	reminder.PreviousStatus, reminder.Reminder = checker.StatusDown, true
+	reminder.PreviousSince = reminder.CheckedAt.Add(-90 * time.Minute)
+	if got := reminder.Title(); got != "api is still DOWN" {
+		t.Errorf("Title() for reminder = %q", got)
+	}
+	if got := reminder.OutageDuration(); got != 90*time.Minute {
+		t.Errorf("OutageDuration() for reminder = %v", got)
+	}
+}
+
+func TestNotifier_Webhook(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{Name: "hook", Type: "webhook", URL: srv.URL})
+
+	body := decodeBody(t, notifyOnce(t, n, reqs))
+	if body["service"] != "api" || body["status"] != "down" || body["previous_status"] != "up" {
+		t.Errorf("unexpected payload: %v", body)
+	}
+	if body["response_time_ms"] != float64(42) || body["source"] != "servprobe" {
+		t.Errorf("unexpected payload: %v", body)
+	}
+}
+
+func TestNotifier_WebhookHeadersAndSignature(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{
+		Name:    "hook",
+		Type:    "webhook",
+		URL:     srv.URL,
+		Headers: map[string]string{"X-Env": "prod"},
+		Auth:    &config.WebhookAuth{Bearer: "tok"},
+		Secret:  "s3cret",
+		// The signature must cover the templated body.
+		Template: &config.TemplateConfig{Body: `{"summary": {{json .Title}}}`},
+	})
+
+	req := notifyOnce(t, n, reqs)
+	if req.header.Get("X-Env") != "prod" || req.header.Get("Authorization") != "Bearer tok" {
+		t.Errorf("expected custom header and bearer token, got %v", req.header)
+	}
+	if string(req.body) != `{"summary": "api is DOWN"}` {
+		t.Errorf("unexpected body %s", req.body)
+	}
+	sig := req.header.Get(signature.Header)
+	if err := signature.Verify("s3cret", sig, req.body, signature.DefaultTolerance, time.Now()); err != nil {
+		t.Errorf("signature %q does not verify: %v", sig, err)
+	}
+}
+
+func TestNotifier_WebhookBasicAuth(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{
+		Name: "hook",
+		Type: "webhook",
+		URL:  srv.URL,
+		Auth: &config.WebhookAuth{Username: "servprobe", Password: "pw"},
+	})
+
+	req := notifyOnce(t, n, reqs)
+	r := &http.Request{Header: req.header}
+	if user, pass, ok := r.BasicAuth(); !ok || user != "servprobe" || pass != "pw" {
+		t.Errorf("expected basic auth credentials, got %q", req.header.Get("Authorization"))
+	}
+	if req.header.Get(signature.Header) != "" {
+		t.Error("expected no signature without a secret")
+	}
+}
+
+func TestNotifier_Slack(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{Name: "chat", Type: "slack", URL: srv.URL})
+
+	body := decodeBody(t, notifyOnce(t, n, reqs))
+	if body["text"] != "api is DOWN" {
+		t.Errorf("expected fallback text, got %v", body["text"])
+	}
+	attachments, ok := body["attachments"].([]interface{})
+	if !ok || len(attachments) != 1 {
+		t.Fatalf("expected one attachment, got %v", body["attachments"])
+	}
+	att := attachments[0].(map[string]interface{})
+	if att["color"] != "#e01e5a" {
+		t.Errorf("expected red attachment, got %v", att["color"])
+	}
+	if !strings.Contains(att["text"].(string), "connection refused") {
+		t.Errorf("expected error in attachment text, got %v", att["text"])
+	}
+}
+
+func TestNotifier_Discord(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusNoContent)
+	n := notifierFor(t, config.ChannelConfig{Name: "chat", Type: "discord", URL: srv.URL})
+
+	body := decodeBody(t, notifyOnce(t, n, reqs))
+	embeds, ok := body["embeds"].([]interface{})
+	if !ok || len(embeds) != 1 {
+		t.Fatalf("expected one embed, got %v", body["embeds"])
+	}
+	embed := embeds[0].(map[string]interface{})
+	if embed["title"] != "api is DOWN" || embed["timestamp"] != "2026-03-01T12:00:00Z" {
+		t.Errorf("unexpected embed: %v", embed)
+	}
+	if embed["color"] != float64(0xe01e5a) {
+		t.Errorf("expected red embed, got %v", embed["color"])
+	}
+}
+
+func TestNotifier_Teams(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{Name: "chat", Type: "teams", URL: srv.URL})
+
+	body := decodeBody(t, notifyOnce(t, n, reqs))
+	if body["@type"] != "MessageCard" || body["title"] != "api is DOWN" {
+		t.Errorf("unexpected card: %v", body)
+	}
+	sections := body["sections"].([]interface{})
+	facts := sections[0].(map[string]interface{})["facts"].([]interface{})
+	if len(facts) != 5 {
+		t.Errorf("expected 5 facts including the error, got %d", len(facts))
+	}
+}
+
+func TestNotifier_Telegram(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{Name: "phone", Type: "telegram", URL: srv.URL, Token: "123:abc", ChatID: "-100"})
+
+	req := notifyOnce(t, n, reqs)
+	if req.path != "/bot123:abc/sendMessage" {
+		t.Errorf("unexpected path %q", req.path)
+	}
+	body := decodeBody(t, req)
+	if body["chat_id"] != "-100" {
+		t.Errorf("expected chat_id -100, got %v", body["chat_id"])
+	}
+	if !strings.HasPrefix(body["text"].(string), "api is DOWN\n") {
+		t.Errorf("unexpected text %q", body["text"])
+	}
+}
+
+func TestNotifier_Gotify(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{Name: "push", Type: "gotify", URL: srv.URL + "/", Token: "secret"})
+
+	req := notifyOnce(t, n, reqs)
+	if req.path != "/message" {
+		t.Errorf("unexpected path %q", req.path)
+	}
+	if req.header.Get("X-Gotify-Key") != "secret" {
+		t.Errorf("expected app token header, got %q", req.header.Get("X-Gotify-Key"))
+	}
+	body := decodeBody(t, req)
+	if body["title"] != "api is DOWN" || body["priority"] != float64(8) {
+		t.Errorf("unexpected message: %v", body)
+	}
+}
+
+func TestNotifier_Ntfy(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{Name: "push", Type: "ntfy", URL: srv.URL + "/alerts", Token: "tk"})
+
+	req := notifyOnce(t, n, reqs)
+	if req.path != "/alerts" {
+		t.Errorf("unexpected path %q", req.path)
+	}
+	if req.header.Get("Title") != "api is DOWN" || req.header.Get("Priority") != "high" {
+		t.Errorf("unexpected headers: %v", req.header)
+	}
+	if req.header.Get("Authorization") != "Bearer tk" {
+		t.Errorf("expected bearer token, got %q", req.header.Get("Authorization"))
+	}
+	if !strings.Contains(string(req.body), "Status: down (was up)") {
+		t.Errorf("unexpected body %q", req.body)
+	}
+}
+
+func TestNotifier_ErrorStatus(t *testing.T) {
+	srv, _ := standIn(t, http.StatusBadRequest)
+	n := notifierFor(t, config.ChannelConfig{Name: "chat", Type: "slack", URL: srv.URL})
+
+	err := n.Notify(context.Background(), downEvent())
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expected error mentioning status 400, got %v", err)
+	}
+}
+
+func TestAlerter_SetNotifiers_SendsToEveryChannel(t *testing.T) {
+	first, firstReqs := standIn(t, http.StatusOK)
+	second, secondReqs := standIn(t, http.StatusOK)
+	notifiers, err := alert.NewNotifiers(config.AlertsConfig{Channels: []config.ChannelConfig{
+		{Name: "hook", Type: "webhook", URL: first.URL},
+		{Name: "chat", Type: "slack", URL: second.URL},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := alert.New("", 0, nil)
+	a.SetNotifiers(notifiers, time.Hour)
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
+
+	for _, reqs := range []<-chan captured{firstReqs, secondReqs} {
+		select {
+		case <-reqs:
+		case <-time.After(time.Second):
+			t.Fatal("expected every channel to be notified")
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+const (
+	// outboxPoll is the longest Run waits before looking for due deliveries.
+	outboxPoll = 5 * time.Second
+	// outboxBatch is the most deliveries attempted at once.
+	outboxBatch = 50
+	// outboxLease is how long a claimed delivery is left to its attempt
+	// before another run, or another instance, may claim it again.
+	outboxLease = 2 * sendTimeout
+)
+
+// Outbox persists alert deliveries so they survive restarts and can be
+// retried.
+type Outbox interface {
+	EnqueueAlert(ctx context.Context, a storage.AlertDelivery) (storage.AlertDelivery, error)
+	ClaimDueAlerts(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]storage.AlertDelivery, error)
+	MarkAlertSent(ctx context.Context, id int64, attempts int, at time.Time) error
+	MarkAlertFailed(ctx context.Context, id int64, attempts int, lastErr string, next time.Time) error
+}
+
+// SetOutbox makes Notify queue alerts in o instead of sending them directly.
+// Queued alerts are delivered, and retried on failure, by Run.
+func (a *Alerter) SetOutbox(o Outbox) {
+	a.mu.Lock()
+	a.outbox = o
+	a.mu.Unlock()
+}
+
+// SetRetry sets how failed deliveries from the outbox are retried.
+func (a *Alerter) SetRetry(r config.RetryConfig) {
+	a.mu.Lock()
+	a.retry = r
+	a.mu.Unlock()
+}
+
+// Run sends reminders and escalations for ongoing outages, summarizes
+// changes held back by the cooldown once it ends, and delivers queued
+// alerts, including those left pending by a previous run, until ctx is done.
+func (a *Alerter) Run(ctx context.Context) {
+	for {
+		a.remind(time.Now())
+		a.flushHeld(time.Now())
+		a.deliverDue(ctx)
+
+		a.mu.Lock()
+		wait := outboxPoll
+		if a.outbox != nil && a.retry.Backoff.Duration > 0 {
+			wait = min(wait, a.retry.Backoff.Duration)
+		}
+		a.mu.Unlock()
+		if next := a.nextReminder(time.Now()); next > 0 {
+			wait = min(wait, next)
+		}
+		if next := a.nextHeld(time.Now()); next > 0 {
+			wait = min(wait, next)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.wake:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// enqueue queues e for each notifier and wakes Run.
+func (a *Alerter) enqueue(o Outbox, notifiers []Notifier, e Event) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		a.logger.Error("encoding alert", "service", e.Service, "error", err)
+		return
+	}
+	for _, n := range notifiers {
+		_, err := o.EnqueueAlert(context.Background(), storage.AlertDelivery{
+			Service: e.Service,
+			Channel: n.Name(),
+			Payload: string(payload),
+		})
+		if err != nil {
+			// Better to try once than to lose the alert.
+			a.logger.Error("queueing alert; sending without retries", "service", e.Service, "channel", n.Name(), "error", err)
+			go a.send(n, e)
+		}
+	}
+	a.wakeRun()
+}
+
+func (a *Alerter) deliverDue(ctx context.Context) {
+	a.mu.Lock()
+	o := a.outbox
+	a.mu.Unlock()
+	if o == nil {
+		return
+	}
+	due, err := o.ClaimDueAlerts(ctx, time.Now(), outboxLease, outboxBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.logger.Error("loading queued alerts", "error", err)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.deliver(ctx, o, d)
+		}()
+	}
+	wg.Wait()
+}
+
+// deliver attempts one queued delivery and records the outcome.
+func (a *Alerter) deliver(ctx context.Context, o Outbox, d storage.AlertDelivery) {
+	a.mu.Lock()
+	var n Notifier
+	for _, candidate := range a.notifiers {
+		if candidate.Name() == d.Channel {
+			n = candidate
+			break
+		}
+	}
+	retry := a.retry
+	a.mu.Unlock()
+
+	attempts := d.Attempts + 1
+	err := errors.New("channel is no longer configured")
+	giveUp := n == nil
+	if n != nil {
+		var e Event
+		if err = json.Unmarshal([]byte(d.Payload), &e); err != nil {
+			err = fmt.Errorf("decoding queued alert: %w", err)
+			giveUp = true
+		} else {
+			sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+			err = n.Notify(sendCtx, e)
+			cancel()
+		}
+	}
+
+	// Marks use their own context so the outcome of an attempt that
+	// finished during shutdown is still recorded.
+	markCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err == nil {
+		a.delivered(d.Channel, DeliverySent)
+		if err := o.MarkAlertSent(markCtx, d.ID, attempts, time.Now()); err != nil {
+			a.logger.Error("recording alert delivery", "id", d.ID, "error", err)
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		// Interrupted by shutdown: leave it pending, due again once its
+		// lease ends.
+		return
+	}
+
+	var next time.Time
+	if !giveUp && attempts < retry.MaxAttempts {
+		next = time.Now().Add(backoff(retry, attempts))
+		a.logger.Warn("alert delivery failed; will retry",
+			"service", d.Service, "channel", d.Channel, "attempt", attempts, "retry_at", next, "error", err)
+		a.delivered(d.Channel, DeliveryRetry)
+	} else {
+		a.logger.Error("alert delivery failed; giving up",
+			"service", d.Service, "channel", d.Channel, "attempts", attempts, "error", err)
+		a.delivered(d.Channel, DeliveryFailed)
+	}
+	if err := o.MarkAlertFailed(markCtx, d.ID, attempts, err.Error(), next); err != nil {
+		a.logger.Error("recording alert failure", "id", d.ID, "error", err)
+	}
+}
+
+// backoff returns the delay before the retry following the given number of
+// failed attempts.
+func backoff(r config.RetryConfig, attempts int) time.Duration {
+	d := r.Backoff.Duration
+	for i := 1; i < attempts && d < r.MaxBackoff.Duration; i++ {
+		d *= 2
+	}
+	return min(d, r.MaxBackoff.Duration)
+}
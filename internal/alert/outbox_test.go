@@ -0,0 +1,229 @@
+package alert_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// memOutbox is an in-memory alert.Outbox.
+type memOutbox struct {
+	mu     sync.Mutex
+	nextID int64
+	rows   []storage.AlertDelivery
+	// claims counts ClaimDueAlerts calls.
+	claims int
+}
+
+func (m *memOutbox) EnqueueAlert(_ context.Context, a storage.AlertDelivery) (storage.AlertDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	a.ID = m.nextID
+	a.Status = storage.AlertPending
+	a.CreatedAt = time.Now()
+	a.NextAttemptAt = a.CreatedAt
+	m.rows = append(m.rows, a)
+	return a, nil
+}
+
+func (m *memOutbox) ClaimDueAlerts(_ context.Context, now time.Time, lease time.Duration, limit int) ([]storage.AlertDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.claims++
+	var due []storage.AlertDelivery
+	for i, a := range m.rows {
+		if a.Status == storage.AlertPending && !a.NextAttemptAt.After(now) && len(due) < limit {
+			m.rows[i].NextAttemptAt = now.Add(lease)
+			due = append(due, m.rows[i])
+		}
+	}
+	return due, nil
+}
+
+func (m *memOutbox) MarkAlertSent(_ context.Context, id int64, attempts int, at time.Time) error {
+	return m.update(id, func(a *storage.AlertDelivery) {
+		a.Status, a.Attempts, a.LastError, a.SentAt = storage.AlertSent, attempts, "", &at
+	})
+}
+
+func (m *memOutbox) MarkAlertFailed(_ context.Context, id int64, attempts int, lastErr string, next time.Time) error {
+	return m.update(id, func(a *storage.AlertDelivery) {
+		a.Attempts, a.LastError = attempts, lastErr
+		if next.IsZero() {
+			a.Status = storage.AlertFailed
+		} else {
+			a.NextAttemptAt = next
+		}
+	})
+}
+
+func (m *memOutbox) update(id int64, fn func(*storage.AlertDelivery)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.rows {
+		if m.rows[i].ID == id {
+			fn(&m.rows[i])
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (m *memOutbox) snapshot() []storage.AlertDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]storage.AlertDelivery(nil), m.rows...)
+}
+
+// waitFor polls the outbox until cond holds for its only delivery.
+func (m *memOutbox) waitFor(t *testing.T, cond func(storage.AlertDelivery) bool) storage.AlertDelivery {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if rows := m.snapshot(); len(rows) == 1 && cond(rows[0]) {
+			return rows[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("condition not met within 2s: %+v", m.snapshot())
+	return storage.AlertDelivery{}
+}
+
+// flaky fails its first failures calls.
+type flaky struct {
+	failures int32
+	calls    int32
+	last     atomic.Value
+}
+
+func (f *flaky) Name() string { return "ops" }
+
+func (f *flaky) Notify(_ context.Context, e alert.Event) error {
+	n := atomic.AddInt32(&f.calls, 1)
+	f.last.Store(e)
+	if n <= f.failures {
+		return errors.New("503 service unavailable")
+	}
+	return nil
+}
+
+var fastRetry = config.RetryConfig{
+	MaxAttempts: 3,
+	Backoff:     config.Duration{Duration: 10 * time.Millisecond},
+	MaxBackoff:  config.Duration{Duration: 20 * time.Millisecond},
+}
+
+func startAlerter(t *testing.T, outbox alert.Outbox, n alert.Notifier) *alert.Alerter {
+	t.Helper()
+	a := alert.New("", 0, nil)
+	a.SetNotifiers([]alert.Notifier{n}, 0)
+	a.SetRetry(fastRetry)
+	a.SetOutbox(outbox)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return a
+}
+
+func TestOutbox_RetriesUntilDelivered(t *testing.T) {
+	outbox := &memOutbox{}
+	n := &flaky{failures: 2}
+	a := startAlerter(t, outbox, n)
+
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
+
+	d := outbox.waitFor(t, func(d storage.AlertDelivery) bool { return d.Status == storage.AlertSent })
+	if d.Attempts != 3 || d.Channel != "ops" || d.Service != "api" {
+		t.Errorf("unexpected delivery: %+v", d)
+	}
+	e := n.last.Load().(alert.Event)
+	if e.Service != "api" || e.Status != checker.StatusDown || e.PreviousStatus != checker.StatusUp || e.ResponseTime != 10*time.Millisecond {
+		t.Errorf("event did not survive the outbox intact: %+v", e)
+	}
+}
+
+func TestOutbox_GivesUpAfterMaxAttempts(t *testing.T) {
+	outbox := &memOutbox{}
+	n := &flaky{failures: 100}
+	a := startAlerter(t, outbox, n)
+
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
+
+	d := outbox.waitFor(t, func(d storage.AlertDelivery) bool { return d.Status == storage.AlertFailed })
+	if d.Attempts != 3 || d.LastError != "503 service unavailable" {
+		t.Errorf("unexpected delivery: %+v", d)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if calls := atomic.LoadInt32(&n.calls); calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestOutbox_ReportsDeliveryOutcomes(t *testing.T) {
+	outbox := &memOutbox{}
+	a := startAlerter(t, outbox, &flaky{failures: 1})
+	var (
+		mu       sync.Mutex
+		outcomes []string
+	)
+	a.SetOnDelivery(func(channel, outcome string) {
+		mu.Lock()
+		outcomes = append(outcomes, channel+":"+outcome)
+		mu.Unlock()
+	})
+
+	a.Notify(makeResult("api", checker.StatusDown), statusPtr(checker.StatusUp))
+
+	outbox.waitFor(t, func(d storage.AlertDelivery) bool { return d.Status == storage.AlertSent })
+	mu.Lock()
+	defer mu.Unlock()
+	if len(outcomes) != 2 || outcomes[0] != "ops:retry" || outcomes[1] != "ops:sent" {
+		t.Errorf("expected a retry then a delivery, got %v", outcomes)
+	}
+}
+
+func TestOutbox_DeliversPendingFromPreviousRun(t *testing.T) {
+	outbox := &memOutbox{}
+	// Left behind by a previous process.
+	outbox.EnqueueAlert(context.Background(), storage.AlertDelivery{
+		Service: "api",
+		Channel: "ops",
+		Payload: `{"service":"api","status":"down","previous_status":"up"}`,
+	})
+
+	n := &flaky{}
+	startAlerter(t, outbox, n)
+
+	outbox.waitFor(t, func(d storage.AlertDelivery) bool { return d.Status == storage.AlertSent })
+	if e := n.last.Load().(alert.Event); e.Service != "api" || e.Status != checker.StatusDown {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+func TestOutbox_UnknownChannelFails(t *testing.T) {
+	outbox := &memOutbox{}
+	outbox.EnqueueAlert(context.Background(), storage.AlertDelivery{Service: "api", Channel: "removed", Payload: "{}"})
+
+	startAlerter(t, outbox, &flaky{})
+
+	d := outbox.waitFor(t, func(d storage.AlertDelivery) bool { return d.Status == storage.AlertFailed })
+	if d.Attempts != 1 || d.LastError == "" {
+		t.Errorf("unexpected delivery: %+v", d)
+	}
+}
@@ -0,0 +1,52 @@
+package alert
+
+import (
+	"net/http"
+)
+
+// telegramMessage sends a message through the Telegram Bot API.
+func telegramMessage(base, token, chatID string) func(Event) (Message, error) {
+	return func(e Event) (Message, error) {
+		return jsonMessage(base+"/bot"+token+"/sendMessage", nil, map[string]interface{}{
+			"chat_id": chatID,
+			"text":    e.Title() + "\n\n" + e.Text(),
+		})
+	}
+}
+
+// gotifyMessage pushes a message to a Gotify server.
+func gotifyMessage(url, token string) func(Event) (Message, error) {
+	return func(e Event) (Message, error) {
+		priority := 8
+		if e.up() {
+			priority = 4
+		}
+		header := http.Header{}
+		header.Set("X-Gotify-Key", token)
+		return jsonMessage(url+"/message", header, map[string]interface{}{
+			"title":    e.Title(),
+			"message":  e.Text(),
+			"priority": priority,
+		})
+	}
+}
+
+// ntfyMessage publishes to an ntfy topic URL.
+func ntfyMessage(url, token string) func(Event) (Message, error) {
+	return func(e Event) (Message, error) {
+		header := http.Header{}
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("Title", e.Title())
+		if e.up() {
+			header.Set("Priority", "default")
+			header.Set("Tags", "white_check_mark")
+		} else {
+			header.Set("Priority", "high")
+			header.Set("Tags", "rotating_light")
+		}
+		if token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+		return Message{URL: url, Header: header, Body: []byte(e.Text())}, nil
+	}
+}
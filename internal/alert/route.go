@@ -0,0 +1,85 @@
+package alert
+
+import (
+	"path"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// Severity is "critical" for a service going down and "info" for a recovery.
+func (e Event) Severity() string {
+	if e.up() {
+		return "info"
+	}
+	return "critical"
+}
+
+// routeMatches reports whether e matches every non-empty matcher of r. Tag
+// matchers are checked against e.Tags.
+func routeMatches(r config.AlertRoute, e Event) bool {
+	if len(r.Services) > 0 && !anyMatch(r.Services, func(pattern string) bool {
+		ok, _ := path.Match(pattern, e.Service)
+		return ok
+	}) {
+		return false
+	}
+	if len(r.Tags) > 0 && !anyMatch(r.Tags, func(tag string) bool {
+		for _, t := range e.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}) {
+		return false
+	}
+	if len(r.Transitions) > 0 {
+		matched := false
+		for _, tr := range r.Transitions {
+			if (tr.From == "" || tr.From == string(e.PreviousStatus)) && (tr.To == "" || tr.To == string(e.Status)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.Severities) > 0 && !anyMatch(r.Severities, func(sev string) bool { return sev == e.Severity() }) {
+		return false
+	}
+	if r.During != nil && !r.During.Contains(e.CheckedAt) {
+		return false
+	}
+	return true
+}
+
+func anyMatch(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// route returns the notifiers that should receive e. With no routes every
+// notifier does.
+func route(routes []config.AlertRoute, notifiers []Notifier, e Event) []Notifier {
+	if len(routes) == 0 {
+		return notifiers
+	}
+	wanted := make(map[string]bool)
+	for _, r := range routes {
+		if !routeMatches(r, e) {
+			continue
+		}
+		for _, name := range r.Channels {
+			wanted[name] = true
+		}
+		if !r.Continue {
+			break
+		}
+	}
+	return selectNotifiers(notifiers, wanted)
+}
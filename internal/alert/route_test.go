@@ -0,0 +1,148 @@
+package alert_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// recorder is a Notifier that records which channels were notified.
+type recorder struct {
+	name string
+	log  *deliveries
+}
+
+type deliveries struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recorder) Name() string { return r.name }
+
+func (r *recorder) Notify(_ context.Context, e alert.Event) error {
+	r.log.mu.Lock()
+	r.log.sent = append(r.log.sent, r.name)
+	r.log.mu.Unlock()
+	return nil
+}
+
+func (d *deliveries) channels() []string {
+	time.Sleep(50 * time.Millisecond)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := append([]string(nil), d.sent...)
+	sort.Strings(out)
+	return out
+}
+
+var testServices = map[string]config.Service{
+	"payments-api": {Name: "payments-api", Tags: []string{"prod"}},
+	"search":       {Name: "search", Tags: []string{"staging"}},
+	"web":          {Name: "web", Tags: []string{"prod"}},
+}
+
+func routedAlerter(routes []config.AlertRoute) (*alert.Alerter, *deliveries) {
+	log := &deliveries{}
+	a := alert.New("", 0, nil)
+	a.SetNotifiers([]alert.Notifier{
+		&recorder{name: "oncall", log: log},
+		&recorder{name: "lowprio", log: log},
+		&recorder{name: "chat", log: log},
+	}, 0)
+	a.SetRoutes(routes)
+	a.SetServiceLookup(func(name string) (config.Service, bool) {
+		svc, ok := testServices[name]
+		return svc, ok
+	})
+	return a, log
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAlerter_Routes(t *testing.T) {
+	routes := []config.AlertRoute{
+		{Name: "recoveries", Transitions: []config.Transition{{To: "up"}}, Channels: []string{"chat"}},
+		{Name: "payments", Services: []string{"payments-*"}, Channels: []string{"oncall"}, Continue: true},
+		{Name: "staging", Tags: []string{"staging"}, Channels: []string{"lowprio"}},
+		{Name: "prod down", Tags: []string{"prod"}, Severities: []string{"critical"}, Channels: []string{"chat"}},
+	}
+	tests := []struct {
+		name    string
+		service string
+		status  checker.Status
+		prev    checker.Status
+		want    []string
+	}{
+		{"payments down continues to prod route", "payments-api", checker.StatusDown, checker.StatusUp, []string{"chat", "oncall"}},
+		{"staging down", "search", checker.StatusDown, checker.StatusUp, []string{"lowprio"}},
+		{"recovery only to chat", "payments-api", checker.StatusUp, checker.StatusDown, []string{"chat"}},
+		{"prod down", "web", checker.StatusDown, checker.StatusUp, []string{"chat"}},
+		{"unknown service matches no route", "other", checker.StatusDown, checker.StatusUp, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, log := routedAlerter(routes)
+			a.Notify(makeResult(tt.service, tt.status), statusPtr(tt.prev))
+			if got := log.channels(); !equal(got, tt.want) {
+				t.Errorf("expected channels %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAlerter_NoRoutesSendsEverywhere(t *testing.T) {
+	a, log := routedAlerter(nil)
+	a.Notify(makeResult("web", checker.StatusDown), statusPtr(checker.StatusUp))
+	if got := log.channels(); len(got) != 3 {
+		t.Errorf("expected all 3 channels, got %v", got)
+	}
+}
+
+func TestAlerter_RouteDuring(t *testing.T) {
+	now := time.Now().UTC()
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	offset := now.Sub(midnight)
+	// A two-hour range around now; it wraps past midnight when needed.
+	day := 24 * time.Hour
+	inside := &config.TimeRange{Start: (offset - time.Hour + day) % day, End: (offset + time.Hour) % day, Location: time.UTC}
+
+	a, log := routedAlerter([]config.AlertRoute{
+		{During: inside, Channels: []string{"oncall"}},
+		{Channels: []string{"lowprio"}},
+	})
+	a.Notify(makeResult("web", checker.StatusDown), statusPtr(checker.StatusUp))
+	if got := log.channels(); !equal(got, []string{"oncall"}) {
+		t.Errorf("expected business-hours route, got %v", got)
+	}
+}
+
+func TestAlerter_UnroutedAlertKeepsCooldownFree(t *testing.T) {
+	a, log := routedAlerter([]config.AlertRoute{
+		{Transitions: []config.Transition{{From: "up", To: "down"}}, Channels: []string{"oncall"}},
+	})
+	a.SetNotifiers([]alert.Notifier{&recorder{name: "oncall", log: log}}, time.Hour)
+
+	// The recovery matches no route, so it must not start the cooldown.
+	a.Notify(makeResult("web", checker.StatusUp), statusPtr(checker.StatusDown))
+	a.Notify(makeResult("web", checker.StatusDown), statusPtr(checker.StatusUp))
+	if got := log.channels(); !equal(got, []string{"oncall"}) {
+		t.Errorf("expected down alert to be sent, got %v", got)
+	}
+}
@@ -0,0 +1,64 @@
+package signature_test
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert/signature"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"service":"api","status":"down"}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := signature.Sign("s3cret", body, signedAt)
+	if !strings.HasPrefix(header, "t=1700000000,v1=") {
+		t.Fatalf("unexpected header %q", header)
+	}
+
+	now := signedAt.Add(time.Minute)
+	if err := signature.Verify("s3cret", header, body, signature.DefaultTolerance, now); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   string
+		now    time.Time
+		want   error
+	}{
+		{name: "wrong secret", secret: "other", header: header, body: string(body), now: now, want: signature.ErrMismatch},
+		{name: "tampered body", secret: "s3cret", header: header, body: `{"service":"api","status":"up"}`, now: now, want: signature.ErrMismatch},
+		{name: "replayed later", secret: "s3cret", header: header, body: string(body), now: signedAt.Add(time.Hour), want: signature.ErrExpired},
+		{name: "timestamp changed", secret: "s3cret", header: strings.Replace(header, "t=1700000000", "t=1700003600", 1), body: string(body), now: signedAt.Add(time.Hour), want: signature.ErrMismatch},
+		{name: "missing", secret: "s3cret", header: "", body: string(body), now: now, want: signature.ErrMissing},
+		{name: "malformed", secret: "s3cret", header: "v1=zz", body: string(body), now: now, want: signature.ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signature.Verify(tt.secret, tt.header, []byte(tt.body), signature.DefaultTolerance, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRequest_KeepsBody(t *testing.T) {
+	body := `{"service":"api"}`
+	r := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	r.Header.Set(signature.Header, signature.Sign("s3cret", []byte(body), time.Now()))
+
+	if err := signature.VerifyRequest(r, "s3cret", signature.DefaultTolerance); err != nil {
+		t.Fatalf("VerifyRequest: %v", err)
+	}
+	got, _ := io.ReadAll(r.Body)
+	if string(got) != body {
+		t.Errorf("expected body to be readable after verification, got %q", got)
+	}
+}
@@ -0,0 +1,140 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// TemplateData is what channel templates are executed with.
+type TemplateData struct {
+	Service        TemplateService
+	Status         string
+	PreviousStatus string
+	Error          string
+	ResponseTime   time.Duration
+	CheckedAt      time.Time
+	// OutageDuration is how long the service has been down, set on
+	// recoveries and reminders.
+	OutageDuration time.Duration
+	// Reminder is set for repeated alerts about an ongoing outage.
+	Reminder bool
+	// Suppressed is the number of changes held back by the cooldown that
+	// the alert summarizes.
+	Suppressed int
+	// Flapping is "started" or "stopped" for alerts about flapping, with
+	// StateChange the percent state change of recent checks.
+	Flapping    string
+	StateChange float64
+	// Title and Text are the built-in summary and description.
+	Title        string
+	Text         string
+	DashboardURL string
+}
+
+// TemplateService describes the service an alert is about.
+type TemplateService struct {
+	Name   string
+	Type   string
+	Target string
+	Tags   []string
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}
+
+// messageTemplate rewrites a channel's built-in message.
+type messageTemplate struct {
+	body         *template.Template // nil keeps the built-in body
+	headers      map[string]*template.Template
+	contentType  string
+	dashboardURL string
+}
+
+func newMessageTemplate(cfg config.TemplateConfig, dashboardURL string) (*messageTemplate, error) {
+	t := &messageTemplate{
+		headers:      make(map[string]*template.Template, len(cfg.Headers)),
+		contentType:  cfg.ContentType,
+		dashboardURL: dashboardURL,
+	}
+	if cfg.Body != "" {
+		body, err := template.New("body").Funcs(templateFuncs).Option("missingkey=error").Parse(cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("parsing body template: %w", err)
+		}
+		t.body = body
+	}
+	for name, text := range cfg.Headers {
+		h, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template for header %s: %w", name, err)
+		}
+		t.headers[name] = h
+	}
+	return t, nil
+}
+
+func (t *messageTemplate) data(e Event) TemplateData {
+	return TemplateData{
+		Service: TemplateService{
+			Name:   e.Service,
+			Type:   e.ServiceType,
+			Target: e.Target,
+			Tags:   e.Tags,
+		},
+		Status:         string(e.Status),
+		PreviousStatus: string(e.PreviousStatus),
+		Error:          e.Error,
+		ResponseTime:   e.ResponseTime,
+		CheckedAt:      e.CheckedAt,
+		OutageDuration: e.OutageDuration(),
+		Reminder:       e.Reminder,
+		Suppressed:     e.Suppressed,
+		Flapping:       e.Flapping,
+		StateChange:    e.StateChange,
+		Title:          e.Title(),
+		Text:           e.Text(),
+		DashboardURL:   t.dashboardURL,
+	}
+}
+
+// apply executes the templates for e over msg.
+func (t *messageTemplate) apply(msg Message, e Event) (Message, error) {
+	data := t.data(e)
+	header := http.Header{}
+	for k, v := range msg.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	if t.body != nil {
+		var b bytes.Buffer
+		if err := t.body.Execute(&b, data); err != nil {
+			return msg, fmt.Errorf("executing body template: %w", err)
+		}
+		msg.Body = b.Bytes()
+	}
+	for name, h := range t.headers {
+		var b strings.Builder
+		if err := h.Execute(&b, data); err != nil {
+			return msg, fmt.Errorf("executing template for header %s: %w", name, err)
+		}
+		header.Set(name, b.String())
+	}
+	if t.contentType != "" {
+		header.Set("Content-Type", t.contentType)
+	}
+	msg.Header = header
+	return msg, nil
+}
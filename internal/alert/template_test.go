@@ -0,0 +1,177 @@
+package alert_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/state"
+)
+
+func recoveryEvent() alert.Event {
+	checked := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return alert.Event{
+		Service:        "api",
+		ServiceType:    "http",
+		Target:         "https://api.example.com/health",
+		Tags:           []string{"prod", "payments"},
+		Status:         checker.StatusUp,
+		PreviousStatus: checker.StatusDown,
+		PreviousSince:  checked.Add(-90 * time.Second),
+		ResponseTime:   42 * time.Millisecond,
+		CheckedAt:      checked,
+	}
+}
+
+func TestTemplate_Webhook(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{Name: "incidents", Type: "webhook", URL: srv.URL, Template: &config.TemplateConfig{
+		Body: `{"summary": {{json .Title}}, "resolved": {{eq .Status "up"}}, "outage_s": {{.OutageDuration.Seconds}},` +
+			` "tags": {{json .Service.Tags}}, "target": {{json .Service.Target}}, "link": {{json .DashboardURL}}}`,
+		Headers:     map[string]string{"X-Service": "{{.Service.Name}}", "X-Severity": "{{if eq .Status \"down\"}}critical{{else}}ok{{end}}"},
+		ContentType: "application/vnd.incident+json",
+	}})
+
+	if err := n.Notify(context.Background(), recoveryEvent()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	req := <-reqs
+	if ct := req.header.Get("Content-Type"); ct != "application/vnd.incident+json" {
+		t.Errorf("expected templated content type, got %q", ct)
+	}
+	if req.header.Get("X-Service") != "api" || req.header.Get("X-Severity") != "ok" {
+		t.Errorf("unexpected templated headers: %v", req.header)
+	}
+	var body struct {
+		Summary  string   `json:"summary"`
+		Resolved bool     `json:"resolved"`
+		OutageS  float64  `json:"outage_s"`
+		Tags     []string `json:"tags"`
+		Target   string   `json:"target"`
+	}
+	if err := json.Unmarshal(req.body, &body); err != nil {
+		t.Fatalf("templated body is not valid JSON: %v\n%s", err, req.body)
+	}
+	if body.Summary != "api is back UP" || !body.Resolved || body.OutageS != 90 || len(body.Tags) != 2 || body.Target != "https://api.example.com/health" {
+		t.Errorf("unexpected body: %+v", body)
+	}
+}
+
+func TestTemplate_HeadersOnlyKeepBuiltInBody(t *testing.T) {
+	srv, reqs := standIn(t, http.StatusOK)
+	n := notifierFor(t, config.ChannelConfig{Name: "chat", Type: "slack", URL: srv.URL, Template: &config.TemplateConfig{
+		Headers: map[string]string{"X-Env": "prod"},
+	}})
+
+	body := decodeBody(t, notifyOnce(t, n, reqs))
+	if body["text"] != "api is DOWN" {
+		t.Errorf("expected built-in Slack body, got %v", body)
+	}
+}
+
+func TestTemplate_DashboardURL(t *testing.T) {
+	notifiers, err := alert.NewNotifiers(config.AlertsConfig{
+		DashboardURL: "https://status.example.com",
+		Channels: []config.ChannelConfig{{Name: "chat", Type: "ntfy", URL: "http://ntfy.invalid/alerts", Template: &config.TemplateConfig{
+			Body: "{{.Title}} ({{.OutageDuration}}) {{.DashboardURL}}",
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := notifiers[0].(alert.Renderer).Render(recoveryEvent())
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := string(msg.Body); got != "api is back UP (1m30s) https://status.example.com" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
+
+func TestTemplate_EmailSubject(t *testing.T) {
+	notifiers, err := alert.NewNotifiers(config.AlertsConfig{Channels: []config.ChannelConfig{{
+		Name: "mail",
+		Type: "email",
+		SMTP: config.SMTPConfig{Host: "smtp.example.com", Port: 587, From: "a@example.com", To: []string{"b@example.com"}},
+		Template: &config.TemplateConfig{
+			Body:    "{{.Service.Name}} recovered after {{.OutageDuration}}",
+			Headers: map[string]string{"Subject": "RESOLVED: {{.Service.Name}}"},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := notifiers[0].(alert.Renderer).Render(recoveryEvent())
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if msg.Header.Get("Subject") != "RESOLVED: api" || msg.Header.Get("From") != "a@example.com" {
+		t.Errorf("unexpected headers: %v", msg.Header)
+	}
+	if string(msg.Body) != "api recovered after 1m30s" {
+		t.Errorf("unexpected body %q", msg.Body)
+	}
+}
+
+func TestTemplate_Errors(t *testing.T) {
+	_, err := alert.NewNotifiers(config.AlertsConfig{Channels: []config.ChannelConfig{
+		{Name: "bad", Type: "webhook", URL: "http://x.invalid", Template: &config.TemplateConfig{Body: "{{.Title"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), `alert channel "bad"`) {
+		t.Errorf("expected parse error naming the channel, got %v", err)
+	}
+
+	n := notifierFor(t, config.ChannelConfig{Name: "bad", Type: "webhook", URL: "http://x.invalid", Template: &config.TemplateConfig{Body: "{{.NoSuchField}}"}})
+	if _, err := n.(alert.Renderer).Render(downEvent()); err == nil {
+		t.Error("expected execution error for unknown field")
+	}
+}
+
+// stateSource is a fixed alert.StateSource.
+type stateSource map[string]state.ServiceState
+
+func (s stateSource) Get(name string) (state.ServiceState, bool) {
+	st, ok := s[name]
+	return st, ok
+}
+
+// capture is a Notifier that hands every event to a channel.
+type capture chan alert.Event
+
+func (c capture) Name() string { return "capture" }
+
+func (c capture) Notify(_ context.Context, e alert.Event) error {
+	c <- e
+	return nil
+}
+
+func TestAlerter_FillsServiceMetadataAndOutageStart(t *testing.T) {
+	downSince := time.Now().Add(-time.Hour)
+	events := make(capture, 1)
+	a := alert.New("", 0, nil)
+	a.SetNotifiers([]alert.Notifier{events}, 0)
+	a.SetServiceLookup(func(name string) (config.Service, bool) {
+		return config.Service{Name: name, Type: "tcp", Target: "db:5432", Tags: []string{"prod"}}, true
+	})
+	a.SetStateSource(stateSource{"db": {Status: checker.StatusUp, PreviousSince: downSince}})
+
+	a.Notify(makeResult("db", checker.StatusUp), statusPtr(checker.StatusDown))
+
+	select {
+	case e := <-events:
+		if e.ServiceType != "tcp" || e.Target != "db:5432" || len(e.Tags) != 1 {
+			t.Errorf("expected service metadata, got %+v", e)
+		}
+		if !e.PreviousSince.Equal(downSince) || e.OutageDuration() < time.Hour {
+			t.Errorf("expected outage of about an hour, got %v", e.OutageDuration())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an alert")
+	}
+}
@@ -0,0 +1,67 @@
+package alert
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert/signature"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+type webhookPayload struct {
+	Service        string `json:"service"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status"`
+	Error          string `json:"error"`
+	ResponseTimeMs int64  `json:"response_time_ms"`
+	CheckedAt      string `json:"checked_at"`
+	Source         string `json:"source"`
+	Reminder       bool   `json:"reminder,omitempty"`
+	Suppressed     int    `json:"suppressed,omitempty"`
+	Flapping       string `json:"flapping,omitempty"`
+}
+
+// webhookMessage POSTs a generic JSON payload with the given extra headers.
+func webhookMessage(url string, headers map[string]string) func(Event) (Message, error) {
+	return func(e Event) (Message, error) {
+		header := http.Header{}
+		for k, v := range headers {
+			header.Set(k, v)
+		}
+		return jsonMessage(url, header, webhookPayload{
+			Service:        e.Service,
+			Status:         string(e.Status),
+			PreviousStatus: string(e.PreviousStatus),
+			Error:          e.Error,
+			ResponseTimeMs: e.ResponseTime.Milliseconds(),
+			CheckedAt:      e.CheckedAt.UTC().Format(time.RFC3339),
+			Source:         "servprobe",
+			Reminder:       e.Reminder,
+			Suppressed:     e.Suppressed,
+			Flapping:       e.Flapping,
+		})
+	}
+}
+
+// webhookAuth adds credentials and a signature to a webhook channel's
+// requests. It runs after the channel's template, so the signature covers
+// the body actually sent.
+type webhookAuth struct {
+	auth   *config.WebhookAuth
+	secret string
+}
+
+func (w webhookAuth) apply(msg *Message, now time.Time) {
+	if w.auth != nil {
+		// SetBasicAuth needs a request; build the header through one.
+		req := &http.Request{Header: msg.Header}
+		if w.auth.Bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+w.auth.Bearer)
+		} else {
+			req.SetBasicAuth(w.auth.Username, w.auth.Password)
+		}
+	}
+	if w.secret != "" {
+		msg.Header.Set(signature.Header, signature.Sign(w.secret, msg.Body, now))
+	}
+}
@@ -0,0 +1,327 @@
+// Package catalog keeps the list of monitored services: those from the
+// config file, which are read-only, merged with those managed at runtime
+// through the API and persisted in storage.
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// Errors returned by Catalog mutations.
+var (
+	ErrNotFound = errors.New("service not found")
+	ErrExists   = errors.New("service already exists")
+	ErrReadOnly = errors.New("service is defined in the config file and is read-only")
+
+	errNoStore = errors.New("runtime-managed services are not supported")
+)
+
+// Service sources.
+const (
+	SourceConfig = "config"
+	SourceAPI    = "api"
+)
+
+// Store persists services managed at runtime and pause state.
+type Store interface {
+	InsertService(ctx context.Context, svc config.Service) error
+	UpdateService(ctx context.Context, svc config.Service) error
+	DeleteService(ctx context.Context, name string) error
+	ListServices(ctx context.Context) ([]config.Service, error)
+	PauseService(ctx context.Context, name string, at time.Time) error
+	ResumeService(ctx context.Context, name string, at time.Time) error
+	ListPaused(ctx context.Context) (map[string]time.Time, error)
+}
+
+// Catalog is safe for concurrent use.
+type Catalog struct {
+	mu       sync.Mutex
+	static   []config.Service
+	dynamic  []config.Service
+	paused   map[string]time.Time
+	store    Store
+	onChange func([]config.Service)
+	version  uint64 // incremented on every change
+	logger   *slog.Logger
+
+	// notifyMu serializes onChange calls, which run without mu held: the
+	// callback may wait on goroutines that look services up.
+	notifyMu sync.Mutex
+	notified uint64 // version last passed to onChange
+}
+
+// New creates a Catalog. Pass nil logger to use the default logger.
+func New(static []config.Service, store Store, logger *slog.Logger) *Catalog {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Catalog{static: static, paused: make(map[string]time.Time), store: store, logger: logger}
+}
+
+// Load reads the runtime-managed services and pause state from the store.
+// With a nil store only config services are available.
+func (c *Catalog) Load(ctx context.Context) error {
+	if c.store == nil {
+		return nil
+	}
+	services, err := c.store.ListServices(ctx)
+	if err != nil {
+		return fmt.Errorf("loading services: %w", err)
+	}
+	paused, err := c.store.ListPaused(ctx)
+	if err != nil {
+		return fmt.Errorf("loading paused services: %w", err)
+	}
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dynamic = services
+	c.paused = paused
+	c.changedLocked()
+	return nil
+}
+
+// OnChange sets the callback invoked with the merged service list after every
+// change. Calls are serialized.
+func (c *Catalog) OnChange(fn func([]config.Service)) {
+	c.mu.Lock()
+	c.onChange = fn
+	c.mu.Unlock()
+}
+
+// Services returns config services followed by runtime-managed services.
+// A runtime service whose name is also in the config file is shadowed.
+func (c *Catalog) Services() []config.Service {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mergedLocked()
+}
+
+// Lookup returns the service with the given name.
+func (c *Catalog) Lookup(name string) (config.Service, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, svc := range c.mergedLocked() {
+		if svc.Name == name {
+			return svc, true
+		}
+	}
+	return config.Service{}, false
+}
+
+// Source reports where the named service is defined: SourceConfig,
+// SourceAPI, or "" if it does not exist.
+func (c *Catalog) Source(name string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if indexOf(c.static, name) >= 0 {
+		return SourceConfig
+	}
+	if indexOf(c.dynamic, name) >= 0 {
+		return SourceAPI
+	}
+	return ""
+}
+
+// SetStatic replaces the services from the config file, e.g. after a reload.
+func (c *Catalog) SetStatic(services []config.Service) {
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.static = services
+	c.changedLocked()
+}
+
+// Add persists and schedules a new runtime-managed service.
+func (c *Catalog) Add(ctx context.Context, svc config.Service) error {
+	if c.store == nil {
+		return errNoStore
+	}
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if indexOf(c.static, svc.Name) >= 0 || indexOf(c.dynamic, svc.Name) >= 0 {
+		return ErrExists
+	}
+	if err := c.store.InsertService(ctx, svc); err != nil {
+		if errors.Is(err, storage.ErrExists) {
+			return ErrExists
+		}
+		return err
+	}
+	c.dynamic = append(c.dynamic, svc)
+	c.changedLocked()
+	return nil
+}
+
+// Update replaces a runtime-managed service.
+func (c *Catalog) Update(ctx context.Context, svc config.Service) error {
+	if c.store == nil {
+		return errNoStore
+	}
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if indexOf(c.static, svc.Name) >= 0 {
+		return ErrReadOnly
+	}
+	i := indexOf(c.dynamic, svc.Name)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := c.store.UpdateService(ctx, svc); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	dynamic := append([]config.Service(nil), c.dynamic...)
+	dynamic[i] = svc
+	c.dynamic = dynamic
+	c.changedLocked()
+	return nil
+}
+
+// Remove deletes a runtime-managed service.
+func (c *Catalog) Remove(ctx context.Context, name string) error {
+	if c.store == nil {
+		return errNoStore
+	}
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if indexOf(c.static, name) >= 0 {
+		return ErrReadOnly
+	}
+	i := indexOf(c.dynamic, name)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := c.store.DeleteService(ctx, name); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if _, ok := c.paused[name]; ok {
+		if err := c.store.ResumeService(ctx, name, time.Now()); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			c.logger.Warn("clearing pause of removed service", "service", name, "error", err)
+		}
+		delete(c.paused, name)
+	}
+	dynamic := append([]config.Service(nil), c.dynamic[:i]...)
+	c.dynamic = append(dynamic, c.dynamic[i+1:]...)
+	c.changedLocked()
+	return nil
+}
+
+// Pause stops checks of the named service until Resume is called. The pause
+// survives restarts. Pausing a paused service does nothing.
+func (c *Catalog) Pause(ctx context.Context, name string) error {
+	if c.store == nil {
+		return errNoStore
+	}
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if indexOf(c.static, name) < 0 && indexOf(c.dynamic, name) < 0 {
+		return ErrNotFound
+	}
+	if _, ok := c.paused[name]; ok {
+		return nil
+	}
+	now := time.Now()
+	if err := c.store.PauseService(ctx, name, now); err != nil {
+		return err
+	}
+	c.paused[name] = now
+	c.changedLocked()
+	return nil
+}
+
+// Resume restarts checks of a paused service. Resuming a service that is not
+// paused does nothing.
+func (c *Catalog) Resume(ctx context.Context, name string) error {
+	if c.store == nil {
+		return errNoStore
+	}
+	defer c.notify()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if indexOf(c.static, name) < 0 && indexOf(c.dynamic, name) < 0 {
+		return ErrNotFound
+	}
+	if _, ok := c.paused[name]; !ok {
+		return nil
+	}
+	if err := c.store.ResumeService(ctx, name, time.Now()); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	delete(c.paused, name)
+	c.changedLocked()
+	return nil
+}
+
+// PausedSince returns when the named service was paused, if it is.
+func (c *Catalog) PausedSince(name string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.paused[name]
+	return t, ok
+}
+
+func (c *Catalog) mergedLocked() []config.Service {
+	merged := make([]config.Service, 0, len(c.static)+len(c.dynamic))
+	merged = append(merged, c.static...)
+	for _, svc := range c.dynamic {
+		if indexOf(c.static, svc.Name) < 0 {
+			merged = append(merged, svc)
+		}
+	}
+	for i := range merged {
+		_, merged[i].Paused = c.paused[merged[i].Name]
+	}
+	return merged
+}
+
+// changedLocked records a change, to be passed to onChange by notify once
+// mu is released.
+func (c *Catalog) changedLocked() {
+	for _, svc := range c.dynamic {
+		if indexOf(c.static, svc.Name) >= 0 {
+			c.logger.Warn("runtime service shadowed by config service with the same name", "service", svc.Name)
+		}
+	}
+	c.version++
+}
+
+// notify calls onChange with the merged service list if it changed since
+// the last call. mu must not be held. When changes race, a caller may find
+// its change already passed on with a later one, and skip it.
+func (c *Catalog) notify() {
+	c.notifyMu.Lock()
+	defer c.notifyMu.Unlock()
+	c.mu.Lock()
+	fn, version, merged := c.onChange, c.version, c.mergedLocked()
+	c.mu.Unlock()
+	if fn == nil || version == c.notified {
+		return
+	}
+	c.notified = version
+	fn(merged)
+}
+
+func indexOf(services []config.Service, name string) int {
+	for i, svc := range services {
+		if svc.Name == name {
+			return i
+		}
+	}
+	return -1
+}
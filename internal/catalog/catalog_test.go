@@ -0,0 +1,297 @@
+package catalog_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/catalog"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/scheduler"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// memStore implements catalog.Store in memory.
+type memStore struct {
+	services []config.Service
+	paused   map[string]time.Time
+}
+
+func (m *memStore) InsertService(_ context.Context, svc config.Service) error {
+	m.services = append(m.services, svc)
+	return nil
+}
+
+func (m *memStore) UpdateService(_ context.Context, svc config.Service) error {
+	for i := range m.services {
+		if m.services[i].Name == svc.Name {
+			m.services[i] = svc
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *memStore) DeleteService(_ context.Context, name string) error {
+	for i := range m.services {
+		if m.services[i].Name == name {
+			m.services = append(m.services[:i], m.services[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *memStore) ListServices(context.Context) ([]config.Service, error) {
+	return append([]config.Service(nil), m.services...), nil
+}
+
+func (m *memStore) PauseService(_ context.Context, name string, at time.Time) error {
+	if m.paused == nil {
+		m.paused = make(map[string]time.Time)
+	}
+	m.paused[name] = at
+	return nil
+}
+
+func (m *memStore) ResumeService(_ context.Context, name string, _ time.Time) error {
+	if _, ok := m.paused[name]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.paused, name)
+	return nil
+}
+
+func (m *memStore) ListPaused(context.Context) (map[string]time.Time, error) {
+	out := make(map[string]time.Time, len(m.paused))
+	for name, t := range m.paused {
+		out[name] = t
+	}
+	return out, nil
+}
+
+func svc(name string) config.Service {
+	return config.Service{
+		Name:     name,
+		Type:     "tcp",
+		Target:   "localhost:1",
+		Interval: config.Duration{Duration: time.Minute},
+		Timeout:  config.Duration{Duration: time.Second},
+	}
+}
+
+func names(services []config.Service) []string {
+	out := make([]string, len(services))
+	for i, s := range services {
+		out[i] = s.Name
+	}
+	return out
+}
+
+func TestCatalog_LoadMergesStoredServices(t *testing.T) {
+	store := &memStore{services: []config.Service{svc("db"), svc("api")}}
+	c := catalog.New([]config.Service{svc("api")}, store, nil)
+	if err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	got := names(c.Services())
+	if len(got) != 2 || got[0] != "api" || got[1] != "db" {
+		t.Errorf("expected [api db] with the stored api shadowed, got %v", got)
+	}
+	if src := c.Source("api"); src != catalog.SourceConfig {
+		t.Errorf("expected api from config, got %q", src)
+	}
+	if src := c.Source("db"); src != catalog.SourceAPI {
+		t.Errorf("expected db from api, got %q", src)
+	}
+}
+
+func TestCatalog_AddUpdateRemove(t *testing.T) {
+	store := &memStore{}
+	c := catalog.New([]config.Service{svc("api")}, store, nil)
+	ctx := context.Background()
+
+	var changes [][]string
+	c.OnChange(func(list []config.Service) { changes = append(changes, names(list)) })
+
+	if err := c.Add(ctx, svc("db")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	updated := svc("db")
+	updated.Target = "localhost:2"
+	if err := c.Update(ctx, updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := c.Lookup("db"); got.Target != "localhost:2" {
+		t.Errorf("expected updated target, got %q", got.Target)
+	}
+	if store.services[0].Target != "localhost:2" {
+		t.Error("expected update to be persisted")
+	}
+	if err := c.Remove(ctx, "db"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if len(store.services) != 0 {
+		t.Error("expected removal to be persisted")
+	}
+
+	if len(changes) != 3 || len(changes[0]) != 2 || len(changes[2]) != 1 {
+		t.Errorf("expected three change notifications, got %v", changes)
+	}
+}
+
+func TestCatalog_Errors(t *testing.T) {
+	c := catalog.New([]config.Service{svc("api")}, &memStore{}, nil)
+	ctx := context.Background()
+
+	if err := c.Add(ctx, svc("api")); !errors.Is(err, catalog.ErrExists) {
+		t.Errorf("Add existing: expected ErrExists, got %v", err)
+	}
+	if err := c.Update(ctx, svc("api")); !errors.Is(err, catalog.ErrReadOnly) {
+		t.Errorf("Update config service: expected ErrReadOnly, got %v", err)
+	}
+	if err := c.Remove(ctx, "api"); !errors.Is(err, catalog.ErrReadOnly) {
+		t.Errorf("Remove config service: expected ErrReadOnly, got %v", err)
+	}
+	if err := c.Update(ctx, svc("ghost")); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("Update missing: expected ErrNotFound, got %v", err)
+	}
+	if err := c.Remove(ctx, "ghost"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("Remove missing: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCatalog_SetStatic(t *testing.T) {
+	c := catalog.New([]config.Service{svc("api")}, &memStore{}, nil)
+	if err := c.Add(context.Background(), svc("db")); err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetStatic([]config.Service{svc("web")})
+	got := names(c.Services())
+	if len(got) != 2 || got[0] != "web" || got[1] != "db" {
+		t.Errorf("expected [web db], got %v", got)
+	}
+}
+
+func TestCatalog_PauseResume(t *testing.T) {
+	store := &memStore{}
+	c := catalog.New([]config.Service{svc("api"), svc("db")}, store, nil)
+	ctx := context.Background()
+
+	var last []config.Service
+	c.OnChange(func(list []config.Service) { last = list })
+
+	if err := c.Pause(ctx, "api"); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if !last[0].Paused || last[1].Paused {
+		t.Errorf("expected only api to be paused, got %+v", last)
+	}
+	if _, ok := store.paused["api"]; !ok {
+		t.Error("expected pause to be persisted")
+	}
+	if _, ok := c.PausedSince("api"); !ok {
+		t.Error("expected PausedSince to report api")
+	}
+
+	// A new catalog picks the pause up from the store.
+	reloaded := catalog.New([]config.Service{svc("api"), svc("db")}, store, nil)
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got, _ := reloaded.Lookup("api"); !got.Paused {
+		t.Error("expected pause to survive a restart")
+	}
+
+	if err := c.Resume(ctx, "api"); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if last[0].Paused {
+		t.Error("expected api to be resumed")
+	}
+	if err := c.Resume(ctx, "api"); err != nil {
+		t.Errorf("expected resuming a running service to be a no-op, got %v", err)
+	}
+	if err := c.Pause(ctx, "ghost"); !errors.Is(err, catalog.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// manualKey marks the context of a manual check, which gatedChecker holds
+// until released.
+type manualKey struct{}
+
+type gatedChecker struct {
+	name    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedChecker) Check(ctx context.Context) checker.CheckResult {
+	if ctx.Value(manualKey{}) != nil {
+		close(g.started)
+		<-g.release
+	}
+	return checker.CheckResult{ServiceName: g.name, Status: checker.StatusUp, CheckedAt: time.Now()}
+}
+
+type nopCheckStore struct{}
+
+func (nopCheckStore) InsertCheck(context.Context, checker.CheckResult) error { return nil }
+func (nopCheckStore) CurrentRuns(context.Context) ([]storage.ServiceRun, error) {
+	return nil, nil
+}
+
+func TestCatalog_PauseDuringManualCheck(t *testing.T) {
+	// Wired as serve wires them: the scheduler follows the catalog, and
+	// results look their service up in it.
+	api := svc("api")
+	api.Interval = config.Duration{Duration: 5 * time.Millisecond}
+	c := catalog.New([]config.Service{api}, &memStore{}, nil)
+	gate := &gatedChecker{name: "api", started: make(chan struct{}), release: make(chan struct{})}
+	sched := scheduler.New(c.Services(), nopCheckStore{}, func(config.Service) (checker.Checker, error) { return gate, nil }, nil)
+	sched.SetOnResult(func(r checker.CheckResult, _ *checker.Status) { c.Lookup(r.ServiceName) })
+	c.OnChange(sched.Update)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		// After a deadlock the goroutines never exit.
+		if !t.Failed() {
+			sched.Wait()
+		}
+	}()
+	sched.Start(ctx)
+
+	manual := make(chan error, 1)
+	go func() {
+		_, err := sched.CheckNow(context.WithValue(ctx, manualKey{}, true), "api")
+		manual <- err
+	}()
+	<-gate.started
+	// Let a scheduled check queue up behind the manual one.
+	time.Sleep(20 * time.Millisecond)
+
+	paused := make(chan error, 1)
+	go func() { paused <- c.Pause(ctx, "api") }()
+	time.Sleep(20 * time.Millisecond)
+	close(gate.release)
+
+	for _, ch := range []chan error{manual, paused} {
+		select {
+		case err := <-ch:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("deadlock: pausing a service during its manual check never returned")
+		}
+	}
+	if got := sched.Stats().Running; got != 0 {
+		t.Errorf("expected the paused service to stop, got %d running", got)
+	}
+}
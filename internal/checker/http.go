@@ -6,6 +6,9 @@ import (
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
 	"github.com/hazz-dev/servprobe/internal/config"
 )
 
@@ -38,6 +41,8 @@ func (c *httpChecker) Check(ctx context.Context) CheckResult {
 	for k, v := range c.svc.Headers {
 		req.Header.Set(k, v)
 	}
+	// With tracing set up, this adds the check's traceparent header.
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	resp, err := c.client.Do(req)
 	result.ResponseTime = time.Since(start)
@@ -17,4 +17,7 @@ type CheckResult struct {
 	ResponseTime time.Duration
 	Error        string
 	CheckedAt    time.Time
+	// Maintenance is set by the scheduler when the check ran inside a
+	// maintenance window.
+	Maintenance bool
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -35,35 +37,674 @@ type Service struct {
 	Timeout        Duration          `yaml:"timeout"`
 	ExpectedStatus int               `yaml:"expected_status"`
 	Headers        map[string]string `yaml:"headers"`
+	Tags           []string          `yaml:"tags"`
+	SLA            SLA               `yaml:"sla"`
+	// Paused is set at runtime for services paused through the API; it is
+	// not part of the service definition.
+	Paused bool `yaml:"-"`
 }
 
-// WebhookConfig holds alert webhook settings.
+// Spec returns the unparsed form of the service.
+func (s Service) Spec() ServiceSpec {
+	return ServiceSpec{
+		Name:           s.Name,
+		Type:           s.Type,
+		Target:         s.Target,
+		Interval:       s.Interval.Duration.String(),
+		Timeout:        s.Timeout.Duration.String(),
+		ExpectedStatus: s.ExpectedStatus,
+		Headers:        s.Headers,
+		Tags:           s.Tags,
+		SLA:            s.SLA,
+	}
+}
+
+// SLA is an availability target of a service.
+type SLA struct {
+	// Target is the uptime percentage to meet, e.g. 99.9. Zero means the
+	// service has no SLA.
+	Target float64 `yaml:"target" json:"target"`
+	// Window is the rolling uptime window the target applies to, one of
+	// UptimeWindows. Defaults to 30d.
+	Window string `yaml:"window" json:"window"`
+}
+
+// UptimeWindow is a rolling window uptime is reported for.
+type UptimeWindow struct {
+	Name     string
+	Duration time.Duration
+}
+
+// UptimeWindows are the windows uptime is reported for, shortest first.
+var UptimeWindows = []UptimeWindow{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+	{"90d", 90 * 24 * time.Hour},
+}
+
+// LookupUptimeWindow returns the uptime window called name.
+func LookupUptimeWindow(name string) (UptimeWindow, bool) {
+	for _, w := range UptimeWindows {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return UptimeWindow{}, false
+}
+
+func (s *SLA) normalize() error {
+	if s.Target == 0 && s.Window == "" {
+		return nil
+	}
+	if s.Target <= 0 || s.Target >= 100 {
+		return fmt.Errorf("sla.target must be between 0 and 100 (exclusive)")
+	}
+	if s.Window == "" {
+		s.Window = "30d"
+	}
+	if _, ok := LookupUptimeWindow(s.Window); !ok {
+		return fmt.Errorf("invalid sla.window %q (must be 24h, 7d, 30d or 90d)", s.Window)
+	}
+	return nil
+}
+
+// HasTag reports whether the service is labelled with tag.
+func (s Service) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MaintenanceSchedule describes a recurring maintenance window, e.g. every
+// Sunday at 02:00 for one hour.
+type MaintenanceSchedule struct {
+	Days     []time.Weekday // empty means every day
+	At       time.Duration  // offset from local midnight
+	Duration time.Duration
+	Location *time.Location
+}
+
+// MaintenanceWindow is a planned maintenance period during which alerts are
+// suppressed for the matching services. Exactly one of Start/End (one-off) or
+// Schedule (recurring) is set. Empty Services and Tags match every service.
+type MaintenanceWindow struct {
+	Name     string
+	Services []string
+	Tags     []string
+	Start    time.Time
+	End      time.Time
+	Schedule *MaintenanceSchedule
+}
+
+// WebhookConfig holds alert webhook settings. It predates Channels and is
+// kept as a shorthand for a single webhook channel named "webhook".
 type WebhookConfig struct {
 	URL      string   `yaml:"url"`
 	Cooldown Duration `yaml:"cooldown"`
 }
 
+// ChannelConfig configures one named notification channel. Which fields are
+// used depends on Type.
+type ChannelConfig struct {
+	Name string `yaml:"name"`
+	// Type is one of webhook, slack, discord, teams, telegram, gotify, ntfy
+	// or email.
+	Type string `yaml:"type"`
+	// URL is the webhook URL (webhook, slack, discord, teams), the server
+	// URL (gotify), the topic URL (ntfy) or an alternative Bot API URL
+	// (telegram).
+	URL string `yaml:"url"`
+	// Token is the bot token (telegram), app token (gotify) or access
+	// token (ntfy, optional).
+	Token  string     `yaml:"token"`
+	ChatID string     `yaml:"chat_id"`
+	SMTP   SMTPConfig `yaml:"smtp"`
+	// Template replaces the channel's built-in message format.
+	Template *TemplateConfig `yaml:"template"`
+	// Headers, Auth and Secret are for webhook channels: extra request
+	// headers, credentials, and the key that signs each request body.
+	Headers map[string]string `yaml:"headers"`
+	Auth    *WebhookAuth      `yaml:"auth"`
+	Secret  string            `yaml:"secret"`
+}
+
+// WebhookAuth holds the credentials a webhook channel sends: a bearer
+// token, or a username and password for basic auth.
+type WebhookAuth struct {
+	Bearer   string `yaml:"bearer"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// TemplateConfig customizes the message a channel sends. Body and header
+// values are Go text/template strings.
+type TemplateConfig struct {
+	Body        string            `yaml:"body"`
+	Headers     map[string]string `yaml:"headers"`
+	ContentType string            `yaml:"content_type"`
+}
+
+// SMTPConfig holds settings for email channels.
+type SMTPConfig struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
 // AlertsConfig holds all alert configuration.
 type AlertsConfig struct {
-	Webhook WebhookConfig `yaml:"webhook"`
+	// Cooldown is the minimum time between alerts for the same service.
+	// Defaults to webhook.cooldown.
+	Cooldown Duration        `yaml:"cooldown"`
+	Webhook  WebhookConfig   `yaml:"webhook"`
+	Channels []ChannelConfig `yaml:"channels"`
+	// Routes pick the channels for each alert. With no routes every
+	// channel receives every alert.
+	Routes []AlertRoute `yaml:"routes"`
+	Retry  RetryConfig  `yaml:"retry"`
+	// DashboardURL is the public address of the dashboard, for links in
+	// alert templates.
+	DashboardURL string `yaml:"dashboard_url"`
+	// ReminderInterval repeats the alert for a service that stays down to
+	// the channels it was routed to. Zero disables reminders.
+	ReminderInterval Duration `yaml:"reminder_interval"`
+	// Escalation notifies further channels while an outage lasts.
+	Escalation []EscalationStep `yaml:"escalation"`
+}
+
+// EscalationStep notifies Channels once a service has been down for After.
+type EscalationStep struct {
+	After    Duration `yaml:"after"`
+	Channels []string `yaml:"channels"`
+}
+
+// RetryConfig controls redelivery of alerts a channel failed to accept. The
+// delay starts at Backoff and doubles after every failure up to MaxBackoff.
+type RetryConfig struct {
+	MaxAttempts int      `yaml:"max_attempts"`
+	Backoff     Duration `yaml:"backoff"`
+	MaxBackoff  Duration `yaml:"max_backoff"`
+}
+
+// AlertRoute sends alerts that match all of its non-empty matchers to
+// Channels. Routes are evaluated in order and the first match wins unless
+// Continue is set.
+type AlertRoute struct {
+	Name string
+	// Services are name globs, e.g. "payments-*".
+	Services    []string
+	Tags        []string
+	Transitions []Transition
+	// Severities are "critical" (a service going down) or "info" (a
+	// recovery).
+	Severities []string
+	During     *TimeRange
+	Channels   []string
+	Continue   bool
+}
+
+// Transition matches a status change. An empty From or To matches any status.
+type Transition struct {
+	From string
+	To   string
+}
+
+// TimeRange is a daily time span, e.g. weekdays 09:00 to 18:00. End before
+// Start wraps past midnight.
+type TimeRange struct {
+	Days     []time.Weekday // empty means every day
+	Start    time.Duration  // offset from local midnight
+	End      time.Duration
+	Location *time.Location
+}
+
+// Contains reports whether t falls inside the range. Days refer to the
+// local day the range starts on, so the part of a wrapping range after
+// midnight belongs to the day before.
+func (r *TimeRange) Contains(t time.Time) bool {
+	local := t.In(r.Location)
+	// The wall clock, not the time since midnight, which is off by the
+	// shift on days the clocks change.
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	day := local.Weekday()
+	if r.Start <= r.End {
+		return r.onDay(day) && offset >= r.Start && offset < r.End
+	}
+	if offset >= r.Start {
+		return r.onDay(day)
+	}
+	return offset < r.End && r.onDay((day+6)%7)
+}
+
+// onDay reports whether the range applies on day.
+func (r *TimeRange) onDay(day time.Weekday) bool {
+	if len(r.Days) == 0 {
+		return true
+	}
+	for _, d := range r.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	routeStatuses = map[string]bool{"": true, "up": true, "down": true}
+	severities    = map[string]bool{"critical": true, "info": true}
+)
+
+func (r *AlertRoute) UnmarshalYAML(value *yaml.Node) error {
+	var raw struct {
+		Name        string   `yaml:"name"`
+		Services    []string `yaml:"services"`
+		Tags        []string `yaml:"tags"`
+		Transitions []string `yaml:"transitions"`
+		Severities  []string `yaml:"severity"`
+		During      *struct {
+			Days     []string `yaml:"days"`
+			From     string   `yaml:"from"`
+			To       string   `yaml:"to"`
+			Timezone string   `yaml:"timezone"`
+		} `yaml:"during"`
+		Channels []string `yaml:"channels"`
+		Continue bool     `yaml:"continue"`
+	}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+
+	*r = AlertRoute{
+		Name:       raw.Name,
+		Services:   raw.Services,
+		Tags:       raw.Tags,
+		Severities: raw.Severities,
+		Channels:   raw.Channels,
+		Continue:   raw.Continue,
+	}
+	for _, pattern := range raw.Services {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid service pattern %q: %w", pattern, err)
+		}
+	}
+	for _, tr := range raw.Transitions {
+		from, to, ok := strings.Cut(tr, "->")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if from == "*" {
+			from = ""
+		}
+		if to == "*" {
+			to = ""
+		}
+		if !ok || !routeStatuses[from] || !routeStatuses[to] {
+			return fmt.Errorf("invalid transition %q (must look like up->down, with * for any status)", tr)
+		}
+		r.Transitions = append(r.Transitions, Transition{From: from, To: to})
+	}
+	for _, sev := range raw.Severities {
+		if !severities[sev] {
+			return fmt.Errorf("invalid severity %q (must be critical or info)", sev)
+		}
+	}
+	if rd := raw.During; rd != nil {
+		tr := &TimeRange{Location: time.UTC}
+		for _, d := range rd.Days {
+			wd, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return fmt.Errorf("invalid day %q (must be sun, mon, tue, wed, thu, fri, or sat)", d)
+			}
+			tr.Days = append(tr.Days, wd)
+		}
+		var err error
+		if tr.Start, err = parseClock(rd.From); err != nil {
+			return err
+		}
+		if tr.End, err = parseClock(rd.To); err != nil {
+			return err
+		}
+		if tr.Start == tr.End {
+			return fmt.Errorf("during: from and to must differ")
+		}
+		if rd.Timezone != "" {
+			loc, err := time.LoadLocation(rd.Timezone)
+			if err != nil {
+				return fmt.Errorf("invalid timezone %q: %w", rd.Timezone, err)
+			}
+			tr.Location = loc
+		}
+		r.During = tr
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (must be HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+var channelTypes = map[string]bool{
+	"webhook": true, "slack": true, "discord": true, "teams": true,
+	"telegram": true, "gotify": true, "ntfy": true, "email": true,
+}
+
+// normalize applies defaults, folds the legacy webhook into Channels and
+// validates every channel.
+func (a *AlertsConfig) normalize() error {
+	if a.Cooldown.Duration == 0 {
+		a.Cooldown = a.Webhook.Cooldown
+	}
+	if a.Retry.MaxAttempts == 0 {
+		a.Retry.MaxAttempts = 10
+	}
+	if a.Retry.Backoff.Duration == 0 {
+		a.Retry.Backoff = Duration{30 * time.Second}
+	}
+	if a.Retry.MaxBackoff.Duration == 0 {
+		a.Retry.MaxBackoff = Duration{30 * time.Minute}
+	}
+	if a.Retry.MaxAttempts < 0 || a.Retry.Backoff.Duration < 0 || a.Retry.MaxBackoff.Duration < a.Retry.Backoff.Duration {
+		return fmt.Errorf("alerts.retry: max_attempts and backoff must be positive and max_backoff at least backoff")
+	}
+	if a.Webhook.URL != "" {
+		a.Channels = append([]ChannelConfig{{Name: "webhook", Type: "webhook", URL: a.Webhook.URL}}, a.Channels...)
+	}
+
+	names := make(map[string]bool, len(a.Channels))
+	for i := range a.Channels {
+		ch := &a.Channels[i]
+		if ch.Name == "" {
+			return fmt.Errorf("alerts.channels[%d]: name is required", i)
+		}
+		if names[ch.Name] {
+			return fmt.Errorf("duplicate alert channel name %q", ch.Name)
+		}
+		names[ch.Name] = true
+		if !channelTypes[ch.Type] {
+			return fmt.Errorf("alert channel %q: invalid type %q", ch.Name, ch.Type)
+		}
+
+		var missing string
+		switch ch.Type {
+		case "webhook", "slack", "discord", "teams", "ntfy":
+			if ch.URL == "" {
+				missing = "url"
+			}
+		case "gotify":
+			if ch.URL == "" {
+				missing = "url"
+			} else if ch.Token == "" {
+				missing = "token"
+			}
+		case "telegram":
+			if ch.Token == "" {
+				missing = "token"
+			} else if ch.ChatID == "" {
+				missing = "chat_id"
+			}
+		case "email":
+			switch {
+			case ch.SMTP.Host == "":
+				missing = "smtp.host"
+			case ch.SMTP.From == "":
+				missing = "smtp.from"
+			case len(ch.SMTP.To) == 0:
+				missing = "smtp.to"
+			}
+			if ch.SMTP.Port == 0 {
+				ch.SMTP.Port = 587
+			}
+		}
+		if missing != "" {
+			return fmt.Errorf("alert channel %q: %s is required for type %s", ch.Name, missing, ch.Type)
+		}
+		if ch.Type != "webhook" && (len(ch.Headers) > 0 || ch.Auth != nil || ch.Secret != "") {
+			return fmt.Errorf("alert channel %q: headers, auth and secret are only supported for type webhook", ch.Name)
+		}
+		if ch.Auth != nil && (ch.Auth.Bearer == "") == (ch.Auth.Username == "") {
+			return fmt.Errorf("alert channel %q: auth needs either bearer or username", ch.Name)
+		}
+	}
+
+	if a.ReminderInterval.Duration < 0 {
+		return fmt.Errorf("alerts.reminder_interval must not be negative")
+	}
+	for i, step := range a.Escalation {
+		if step.After.Duration <= 0 {
+			return fmt.Errorf("alerts.escalation[%d]: after must be positive", i)
+		}
+		if i > 0 && step.After.Duration <= a.Escalation[i-1].After.Duration {
+			return fmt.Errorf("alerts.escalation[%d]: steps must be in increasing order of after", i)
+		}
+		if len(step.Channels) == 0 {
+			return fmt.Errorf("alerts.escalation[%d]: at least one channel is required", i)
+		}
+		for _, name := range step.Channels {
+			if !names[name] {
+				return fmt.Errorf("alerts.escalation[%d]: unknown channel %q", i, name)
+			}
+		}
+	}
+
+	for i, r := range a.Routes {
+		label := fmt.Sprintf("alerts.routes[%d]", i)
+		if r.Name != "" {
+			label = fmt.Sprintf("alert route %q", r.Name)
+		}
+		if len(r.Channels) == 0 {
+			return fmt.Errorf("%s: at least one channel is required", label)
+		}
+		for _, name := range r.Channels {
+			if !names[name] {
+				return fmt.Errorf("%s: unknown channel %q", label, name)
+			}
+		}
+	}
+	return nil
+}
+
+// FlappingConfig tunes flap detection. A service whose percent state change
+// over its last Window checks reaches HighThreshold is flapping until it
+// drops below LowThreshold.
+type FlappingConfig struct {
+	Disabled      bool    `yaml:"disabled"`
+	Window        int     `yaml:"window"`
+	LowThreshold  float64 `yaml:"low_threshold"`
+	HighThreshold float64 `yaml:"high_threshold"`
+}
+
+func (f *FlappingConfig) normalize() error {
+	if f.Window == 0 {
+		f.Window = 21
+	}
+	if f.LowThreshold == 0 {
+		f.LowThreshold = 25
+	}
+	if f.HighThreshold == 0 {
+		f.HighThreshold = 50
+	}
+	if f.Window < 3 {
+		return fmt.Errorf("flapping.window must be at least 3")
+	}
+	if f.LowThreshold < 0 || f.HighThreshold > 100 || f.LowThreshold >= f.HighThreshold {
+		return fmt.Errorf("flapping thresholds must satisfy 0 <= low_threshold < high_threshold <= 100")
+	}
+	return nil
 }
 
 // ServerConfig holds HTTP server settings.
 type ServerConfig struct {
-	Address string `yaml:"address"`
+	Address  string `yaml:"address"`
+	APIToken string `yaml:"api_token"`
 }
 
+// Storage drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
 // StorageConfig holds storage settings.
 type StorageConfig struct {
+	// Driver is sqlite (the default) or postgres.
+	Driver string `yaml:"driver"`
+	// Path is the SQLite database file.
 	Path string `yaml:"path"`
+	// DSN is the PostgreSQL connection string, as a URL or key=value pairs.
+	DSN       string          `yaml:"dsn"`
+	Retention RetentionConfig `yaml:"retention"`
+	Writes    WriteConfig     `yaml:"writes"`
+}
+
+func (s *StorageConfig) validate() error {
+	switch s.Driver {
+	case DriverSQLite:
+	case DriverPostgres:
+		if s.DSN == "" {
+			return fmt.Errorf("storage.dsn is required for the postgres driver")
+		}
+	default:
+		return fmt.Errorf("storage.driver %q: must be sqlite or postgres", s.Driver)
+	}
+	if err := s.Retention.validate(); err != nil {
+		return err
+	}
+	return s.Writes.normalize()
+}
+
+// WriteConfig tunes how check results are written. Results are queued and
+// written in batches, one transaction per batch, so that a slow database
+// doesn't hold up checks.
+type WriteConfig struct {
+	// FlushInterval is how often queued results are written.
+	FlushInterval Duration `yaml:"flush_interval"`
+	// BatchSize is the most results written in one transaction; a full
+	// batch is written without waiting for the interval.
+	BatchSize int `yaml:"batch_size"`
+	// QueueSize is how many results may wait to be written. When the queue
+	// is full, checks wait up to one flush interval for room before their
+	// result is dropped.
+	QueueSize int `yaml:"queue_size"`
+}
+
+// Defaults for WriteConfig.
+const (
+	DefaultFlushInterval = time.Second
+	DefaultBatchSize     = 100
+	DefaultQueueSize     = 1000
+)
+
+func (w *WriteConfig) normalize() error {
+	if w.FlushInterval.Duration < 0 || w.BatchSize < 0 || w.QueueSize < 0 {
+		return fmt.Errorf("storage.writes: settings must not be negative")
+	}
+	if w.FlushInterval.Duration == 0 {
+		w.FlushInterval.Duration = DefaultFlushInterval
+	}
+	if w.BatchSize == 0 {
+		w.BatchSize = DefaultBatchSize
+	}
+	if w.QueueSize == 0 {
+		w.QueueSize = DefaultQueueSize
+	}
+	return nil
+}
+
+// OTLP protocols.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// TelemetryConfig sets up export of check metrics and traces to an
+// OpenTelemetry collector over OTLP. Export is off without an endpoint.
+type TelemetryConfig struct {
+	// Endpoint is the collector's host:port.
+	Endpoint string `yaml:"endpoint"`
+	// Protocol is grpc (the default) or http.
+	Protocol string `yaml:"protocol"`
+	// Insecure sends without TLS.
+	Insecure bool              `yaml:"insecure"`
+	Headers  map[string]string `yaml:"headers"`
+	// ServiceName is the service.name resource attribute.
+	ServiceName string `yaml:"service_name"`
+	// MetricInterval is how often metrics are exported.
+	MetricInterval Duration `yaml:"metric_interval"`
+}
+
+// Defaults for TelemetryConfig.
+const (
+	DefaultTelemetryServiceName = "servprobe"
+	DefaultMetricInterval       = time.Minute
+)
+
+// Enabled reports whether telemetry is exported.
+func (t TelemetryConfig) Enabled() bool {
+	return t.Endpoint != ""
+}
+
+func (t *TelemetryConfig) normalize() error {
+	if t.Protocol == "" {
+		t.Protocol = ProtocolGRPC
+	}
+	if t.Protocol != ProtocolGRPC && t.Protocol != ProtocolHTTP {
+		return fmt.Errorf("telemetry.protocol %q: must be grpc or http", t.Protocol)
+	}
+	if strings.Contains(t.Endpoint, "://") {
+		return fmt.Errorf("telemetry.endpoint %q: use host:port, without a scheme", t.Endpoint)
+	}
+	if t.MetricInterval.Duration < 0 {
+		return fmt.Errorf("telemetry.metric_interval must not be negative")
+	}
+	if t.MetricInterval.Duration == 0 {
+		t.MetricInterval.Duration = DefaultMetricInterval
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = DefaultTelemetryServiceName
+	}
+	return nil
+}
+
+// RetentionConfig sets how many days of data are kept; 0 keeps it forever.
+// Raw checks are rolled up into hourly and daily aggregates before they are
+// deleted.
+type RetentionConfig struct {
+	RawDays    int `yaml:"raw_days"`
+	HourlyDays int `yaml:"hourly_days"`
+	DailyDays  int `yaml:"daily_days"`
+}
+
+// DefaultRetention keeps raw checks for 30 days and hourly rollups for a
+// year. Daily rollups are kept forever.
+var DefaultRetention = RetentionConfig{RawDays: 30, HourlyDays: 365}
+
+func (r RetentionConfig) validate() error {
+	if r.RawDays < 0 || r.HourlyDays < 0 || r.DailyDays < 0 {
+		return fmt.Errorf("storage.retention: days must not be negative")
+	}
+	return nil
 }
 
 // Config is the root application configuration.
 type Config struct {
-	Services []Service     `yaml:"services"`
-	Alerts   AlertsConfig  `yaml:"alerts"`
-	Server   ServerConfig  `yaml:"server"`
-	Storage  StorageConfig `yaml:"storage"`
+	Services    []Service           `yaml:"services"`
+	Alerts      AlertsConfig        `yaml:"alerts"`
+	Flapping    FlappingConfig      `yaml:"flapping"`
+	Server      ServerConfig        `yaml:"server"`
+	Storage     StorageConfig       `yaml:"storage"`
+	Telemetry   TelemetryConfig     `yaml:"telemetry"`
+	Maintenance []MaintenanceWindow `yaml:"-"`
 }
 
 var validTypes = map[string]bool{
@@ -81,23 +722,19 @@ func Load(path string) (*Config, error) {
 	}
 
 	// Unmarshal into a raw intermediate to detect YAML parse errors vs duration errors.
-	type rawService struct {
-		Name           string            `yaml:"name"`
-		Type           string            `yaml:"type"`
-		Target         string            `yaml:"target"`
-		Interval       string            `yaml:"interval"`
-		Timeout        string            `yaml:"timeout"`
-		ExpectedStatus int               `yaml:"expected_status"`
-		Headers        map[string]string `yaml:"headers"`
-	}
 	type rawConfig struct {
-		Services []rawService  `yaml:"services"`
-		Alerts   AlertsConfig  `yaml:"alerts"`
-		Server   ServerConfig  `yaml:"server"`
-		Storage  StorageConfig `yaml:"storage"`
+		Services    []ServiceSpec    `yaml:"services"`
+		Alerts      AlertsConfig     `yaml:"alerts"`
+		Flapping    FlappingConfig   `yaml:"flapping"`
+		Server      ServerConfig     `yaml:"server"`
+		Storage     StorageConfig    `yaml:"storage"`
+		Telemetry   TelemetryConfig  `yaml:"telemetry"`
+		Maintenance []rawMaintenance `yaml:"maintenance"`
 	}
 
-	var raw rawConfig
+	// Retention defaults are set up front so that 0, which keeps data
+	// forever, can be told apart from a missing setting.
+	raw := rawConfig{Storage: StorageConfig{Retention: DefaultRetention}}
 	if err := yaml.Unmarshal(data, &raw); err != nil {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
@@ -106,18 +743,32 @@ func Load(path string) (*Config, error) {
 	if raw.Server.Address == "" {
 		raw.Server.Address = ":8080"
 	}
+	if raw.Storage.Driver == "" {
+		raw.Storage.Driver = DriverSQLite
+	}
 	if raw.Storage.Path == "" {
 		raw.Storage.Path = "servprobe.db"
 	}
+	if err := raw.Storage.validate(); err != nil {
+		return nil, err
+	}
+	if err := raw.Telemetry.normalize(); err != nil {
+		return nil, err
+	}
 
-	if len(raw.Services) == 0 {
-		return nil, fmt.Errorf("at least one service must be configured")
+	if err := raw.Alerts.normalize(); err != nil {
+		return nil, err
+	}
+	if err := raw.Flapping.normalize(); err != nil {
+		return nil, err
 	}
 
 	cfg := &Config{
-		Alerts:  raw.Alerts,
-		Server:  raw.Server,
-		Storage: raw.Storage,
+		Alerts:    raw.Alerts,
+		Flapping:  raw.Flapping,
+		Server:    raw.Server,
+		Storage:   raw.Storage,
+		Telemetry: raw.Telemetry,
 	}
 
 	names := make(map[string]bool, len(raw.Services))
@@ -130,50 +781,179 @@ func Load(path string) (*Config, error) {
 		}
 		names[rs.Name] = true
 
-		if rs.Target == "" {
-			return nil, fmt.Errorf("service %q: target is required", rs.Name)
-		}
-		if !validTypes[rs.Type] {
-			return nil, fmt.Errorf("service %q: invalid type %q (must be http, tcp, ping, or docker)", rs.Name, rs.Type)
-		}
-
-		svc := Service{
-			Name:           rs.Name,
-			Type:           rs.Type,
-			Target:         rs.Target,
-			ExpectedStatus: rs.ExpectedStatus,
-			Headers:        rs.Headers,
+		svc, err := rs.Parse()
+		if err != nil {
+			return nil, err
 		}
+		cfg.Services = append(cfg.Services, svc)
+	}
 
-		// Parse interval with default.
-		if rs.Interval == "" {
-			svc.Interval = Duration{30 * time.Second}
-		} else {
-			d, err := time.ParseDuration(rs.Interval)
-			if err != nil {
-				return nil, fmt.Errorf("service %q: invalid interval %q: %w", rs.Name, rs.Interval, err)
+	for i, rm := range raw.Maintenance {
+		w, err := rm.parse()
+		if err != nil {
+			if rm.Name != "" {
+				return nil, fmt.Errorf("maintenance %q: %w", rm.Name, err)
 			}
-			svc.Interval = Duration{d}
+			return nil, fmt.Errorf("maintenance[%d]: %w", i, err)
 		}
+		cfg.Maintenance = append(cfg.Maintenance, w)
+	}
 
-		// Parse timeout with default.
-		if rs.Timeout == "" {
-			svc.Timeout = Duration{5 * time.Second}
-		} else {
-			d, err := time.ParseDuration(rs.Timeout)
-			if err != nil {
-				return nil, fmt.Errorf("service %q: invalid timeout %q: %w", rs.Name, rs.Timeout, err)
-			}
-			svc.Timeout = Duration{d}
+	return cfg, nil
+}
+
+// ServiceSpec is the unparsed form of a Service, as written in the config
+// file or sent to the API.
+type ServiceSpec struct {
+	Name           string            `yaml:"name" json:"name"`
+	Type           string            `yaml:"type" json:"type"`
+	Target         string            `yaml:"target" json:"target"`
+	Interval       string            `yaml:"interval" json:"interval"`
+	Timeout        string            `yaml:"timeout" json:"timeout"`
+	ExpectedStatus int               `yaml:"expected_status" json:"expected_status"`
+	Headers        map[string]string `yaml:"headers" json:"headers"`
+	Tags           []string          `yaml:"tags" json:"tags"`
+	SLA            SLA               `yaml:"sla" json:"sla"`
+}
+
+// Parse validates the spec and returns the Service with defaults applied.
+func (rs ServiceSpec) Parse() (Service, error) {
+	if rs.Name == "" {
+		return Service{}, fmt.Errorf("service name is required")
+	}
+	if rs.Target == "" {
+		return Service{}, fmt.Errorf("service %q: target is required", rs.Name)
+	}
+	if !validTypes[rs.Type] {
+		return Service{}, fmt.Errorf("service %q: invalid type %q (must be http, tcp, ping, or docker)", rs.Name, rs.Type)
+	}
+
+	svc := Service{
+		Name:           rs.Name,
+		Type:           rs.Type,
+		Target:         rs.Target,
+		ExpectedStatus: rs.ExpectedStatus,
+		Headers:        rs.Headers,
+		Tags:           rs.Tags,
+		SLA:            rs.SLA,
+	}
+	if err := svc.SLA.normalize(); err != nil {
+		return Service{}, fmt.Errorf("service %q: %w", rs.Name, err)
+	}
+
+	// Parse interval with default.
+	if rs.Interval == "" {
+		svc.Interval = Duration{30 * time.Second}
+	} else {
+		d, err := time.ParseDuration(rs.Interval)
+		if err != nil {
+			return Service{}, fmt.Errorf("service %q: invalid interval %q: %w", rs.Name, rs.Interval, err)
+		}
+		if d <= 0 {
+			return Service{}, fmt.Errorf("service %q: interval must be positive", rs.Name)
 		}
+		svc.Interval = Duration{d}
+	}
 
-		// Default expected_status for HTTP.
-		if rs.Type == "http" && svc.ExpectedStatus == 0 {
-			svc.ExpectedStatus = 200
+	// Parse timeout with default.
+	if rs.Timeout == "" {
+		svc.Timeout = Duration{5 * time.Second}
+	} else {
+		d, err := time.ParseDuration(rs.Timeout)
+		if err != nil {
+			return Service{}, fmt.Errorf("service %q: invalid timeout %q: %w", rs.Name, rs.Timeout, err)
 		}
+		svc.Timeout = Duration{d}
+	}
 
-		cfg.Services = append(cfg.Services, svc)
+	// Default expected_status for HTTP.
+	if rs.Type == "http" && svc.ExpectedStatus == 0 {
+		svc.ExpectedStatus = 200
 	}
 
-	return cfg, nil
+	return svc, nil
+}
+
+type rawMaintenance struct {
+	Name     string   `yaml:"name"`
+	Services []string `yaml:"services"`
+	Tags     []string `yaml:"tags"`
+	Start    string   `yaml:"start"`
+	End      string   `yaml:"end"`
+	Schedule *struct {
+		Days     []string `yaml:"days"`
+		At       string   `yaml:"at"`
+		Duration string   `yaml:"duration"`
+		Timezone string   `yaml:"timezone"`
+	} `yaml:"schedule"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func (rm rawMaintenance) parse() (MaintenanceWindow, error) {
+	w := MaintenanceWindow{
+		Name:     rm.Name,
+		Services: rm.Services,
+		Tags:     rm.Tags,
+	}
+	oneOff := rm.Start != "" || rm.End != ""
+	if oneOff == (rm.Schedule != nil) {
+		return w, fmt.Errorf("exactly one of start/end or schedule is required")
+	}
+
+	if oneOff {
+		start, err := time.Parse(time.RFC3339, rm.Start)
+		if err != nil {
+			return w, fmt.Errorf("invalid start %q: %w", rm.Start, err)
+		}
+		end, err := time.Parse(time.RFC3339, rm.End)
+		if err != nil {
+			return w, fmt.Errorf("invalid end %q: %w", rm.End, err)
+		}
+		if !end.After(start) {
+			return w, fmt.Errorf("end must be after start")
+		}
+		w.Start, w.End = start, end
+		return w, nil
+	}
+
+	rs := rm.Schedule
+	sched := &MaintenanceSchedule{Location: time.UTC}
+	for _, d := range rs.Days {
+		wd, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return w, fmt.Errorf("invalid day %q (must be sun, mon, tue, wed, thu, fri, or sat)", d)
+		}
+		sched.Days = append(sched.Days, wd)
+	}
+	at, err := time.Parse("15:04", rs.At)
+	if err != nil {
+		return w, fmt.Errorf("invalid schedule time %q (must be HH:MM)", rs.At)
+	}
+	sched.At = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	d, err := time.ParseDuration(rs.Duration)
+	if err != nil {
+		return w, fmt.Errorf("invalid duration %q: %w", rs.Duration, err)
+	}
+	if d <= 0 {
+		return w, fmt.Errorf("duration must be positive")
+	}
+	sched.Duration = d
+	if rs.Timezone != "" {
+		loc, err := time.LoadLocation(rs.Timezone)
+		if err != nil {
+			return w, fmt.Errorf("invalid timezone %q: %w", rs.Timezone, err)
+		}
+		sched.Location = loc
+	}
+	w.Schedule = sched
+	return w, nil
 }
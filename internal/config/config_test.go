@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hazz-dev/servprobe/internal/config"
 )
@@ -101,6 +102,9 @@ services:
 	if cfg.Storage.Path != "servprobe.db" {
 		t.Errorf("expected default storage path servprobe.db, got %q", cfg.Storage.Path)
 	}
+	if cfg.Storage.Driver != config.DriverSQLite {
+		t.Errorf("expected default storage driver sqlite, got %q", cfg.Storage.Driver)
+	}
 }
 
 func TestLoad_MissingName(t *testing.T) {
@@ -187,12 +191,13 @@ func TestLoad_EmptyServices(t *testing.T) {
 	path := writeTemp(t, `
 services: []
 `)
-	_, err := config.Load(path)
-	if err == nil {
-		t.Fatal("expected error for empty services, got nil")
+	// Services can be added at runtime, so none need be configured.
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	if !strings.Contains(err.Error(), "service") {
-		t.Errorf("error should mention 'service': %v", err)
+	if len(cfg.Services) != 0 {
+		t.Errorf("expected no services, got %d", len(cfg.Services))
 	}
 }
 
@@ -246,3 +251,652 @@ services:
 		t.Fatalf("expected 4 services, got %d", len(cfg.Services))
 	}
 }
+
+func TestLoad_Tags(t *testing.T) {
+	path := writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+    tags: ["prod", "payments"]
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Services[0].HasTag("payments") {
+		t.Errorf("expected tag 'payments', got %v", cfg.Services[0].Tags)
+	}
+	if cfg.Services[0].HasTag("staging") {
+		t.Errorf("unexpected tag 'staging' in %v", cfg.Services[0].Tags)
+	}
+}
+
+func TestLoad_Maintenance(t *testing.T) {
+	path := writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+maintenance:
+  - name: "db upgrade"
+    services: ["api"]
+    start: "2026-03-01T02:00:00Z"
+    end: "2026-03-01T04:00:00Z"
+  - name: "weekly deploy"
+    tags: ["prod"]
+    schedule:
+      days: ["sat", "Sun"]
+      at: "02:30"
+      duration: "1h"
+      timezone: "UTC"
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Maintenance) != 2 {
+		t.Fatalf("expected 2 maintenance windows, got %d", len(cfg.Maintenance))
+	}
+
+	oneOff := cfg.Maintenance[0]
+	if oneOff.Schedule != nil {
+		t.Error("expected one-off window to have no schedule")
+	}
+	if oneOff.End.Sub(oneOff.Start) != 2*time.Hour {
+		t.Errorf("expected 2h window, got %v", oneOff.End.Sub(oneOff.Start))
+	}
+
+	rec := cfg.Maintenance[1]
+	if rec.Schedule == nil {
+		t.Fatal("expected recurring window to have a schedule")
+	}
+	if rec.Schedule.At != 2*time.Hour+30*time.Minute {
+		t.Errorf("expected at 02:30, got %v", rec.Schedule.At)
+	}
+	if rec.Schedule.Duration != time.Hour {
+		t.Errorf("expected 1h duration, got %v", rec.Schedule.Duration)
+	}
+	if len(rec.Schedule.Days) != 2 || rec.Schedule.Days[0] != time.Saturday || rec.Schedule.Days[1] != time.Sunday {
+		t.Errorf("expected days [Saturday Sunday], got %v", rec.Schedule.Days)
+	}
+}
+
+func TestLoad_MaintenanceInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		window string
+		want   string
+	}{
+		{
+			name: "start and schedule",
+			window: `
+    start: "2026-03-01T02:00:00Z"
+    end: "2026-03-01T04:00:00Z"
+    schedule:
+      at: "02:00"
+      duration: "1h"`,
+			want: "exactly one",
+		},
+		{
+			name: "end before start",
+			window: `
+    start: "2026-03-01T04:00:00Z"
+    end: "2026-03-01T02:00:00Z"`,
+			want: "end must be after start",
+		},
+		{
+			name: "bad day",
+			window: `
+    schedule:
+      days: ["funday"]
+      at: "02:00"
+      duration: "1h"`,
+			want: "invalid day",
+		},
+		{
+			name: "bad time",
+			window: `
+    schedule:
+      at: "25:00"
+      duration: "1h"`,
+			want: "HH:MM",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+maintenance:
+  - name: "w"`+tt.window+"\n")
+			_, err := config.Load(path)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error should mention %q: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_AlertChannels(t *testing.T) {
+	path := writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+alerts:
+  webhook:
+    url: "https://hooks.example.com/legacy"
+    cooldown: "5m"
+  channels:
+    - name: "ops-slack"
+      type: "slack"
+      url: "https://hooks.slack.com/services/x"
+    - name: "oncall-mail"
+      type: "email"
+      smtp:
+        host: "smtp.example.com"
+        from: "servprobe@example.com"
+        to: ["oncall@example.com"]
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Alerts.Cooldown.Duration != 5*time.Minute {
+		t.Errorf("expected cooldown to default to webhook cooldown, got %v", cfg.Alerts.Cooldown.Duration)
+	}
+	channels := cfg.Alerts.Channels
+	if len(channels) != 3 {
+		t.Fatalf("expected legacy webhook plus 2 channels, got %d", len(channels))
+	}
+	if channels[0].Name != "webhook" || channels[0].Type != "webhook" || channels[0].URL != "https://hooks.example.com/legacy" {
+		t.Errorf("unexpected legacy webhook channel: %+v", channels[0])
+	}
+	if channels[2].SMTP.Port != 587 {
+		t.Errorf("expected default SMTP port 587, got %d", channels[2].SMTP.Port)
+	}
+	if r := cfg.Alerts.Retry; r.MaxAttempts != 10 || r.Backoff.Duration != 30*time.Second || r.MaxBackoff.Duration != 30*time.Minute {
+		t.Errorf("unexpected retry defaults: %+v", r)
+	}
+}
+
+func TestLoad_AlertChannelsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		want    string
+	}{
+		{name: "missing name", channel: `type: "slack"
+      url: "https://x"`, want: "name is required"},
+		{name: "bad type", channel: `name: "c"
+      type: "pager"`, want: "invalid type"},
+		{name: "missing url", channel: `name: "c"
+      type: "discord"`, want: "url is required"},
+		{name: "gotify token", channel: `name: "c"
+      type: "gotify"
+      url: "https://push.example.com"`, want: "token is required"},
+		{name: "telegram chat", channel: `name: "c"
+      type: "telegram"
+      token: "123:abc"`, want: "chat_id is required"},
+		{name: "email recipients", channel: `name: "c"
+      type: "email"
+      smtp:
+        host: "smtp.example.com"
+        from: "a@example.com"`, want: "smtp.to is required"},
+		{name: "secret on slack", channel: `name: "c"
+      type: "slack"
+      url: "https://x"
+      secret: "s"`, want: "only supported for type webhook"},
+		{name: "auth without credentials", channel: `name: "c"
+      type: "webhook"
+      url: "https://x"
+      auth:
+        password: "pw"`, want: "either bearer or username"},
+		{name: "auth with both", channel: `name: "c"
+      type: "webhook"
+      url: "https://x"
+      auth:
+        bearer: "t"
+        username: "u"`, want: "either bearer or username"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+alerts:
+  channels:
+    - `+tt.channel+"\n")
+			_, err := config.Load(path)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error should mention %q: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_DuplicateAlertChannel(t *testing.T) {
+	path := writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+alerts:
+  webhook:
+    url: "https://hooks.example.com/a"
+  channels:
+    - name: "webhook"
+      type: "webhook"
+      url: "https://hooks.example.com/b"
+`)
+	_, err := config.Load(path)
+	if err == nil || !strings.Contains(err.Error(), "duplicate alert channel") {
+		t.Errorf("expected duplicate channel error, got %v", err)
+	}
+}
+
+func TestLoad_AlertRoutes(t *testing.T) {
+	path := writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+alerts:
+  channels:
+    - name: "oncall"
+      type: "webhook"
+      url: "https://hooks.example.com/oncall"
+    - name: "chat"
+      type: "slack"
+      url: "https://hooks.slack.com/services/x"
+  routes:
+    - name: "payments"
+      services: ["payments-*"]
+      transitions: ["up->down", "* -> up"]
+      severity: ["critical"]
+      during:
+        days: ["mon", "fri"]
+        from: "22:00"
+        to: "06:00"
+        timezone: "Europe/Berlin"
+      channels: ["oncall"]
+      continue: true
+    - channels: ["chat"]
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	routes := cfg.Alerts.Routes
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %d", len(routes))
+	}
+	r := routes[0]
+	if r.Name != "payments" || !r.Continue || r.Services[0] != "payments-*" {
+		t.Errorf("unexpected route: %+v", r)
+	}
+	if len(r.Transitions) != 2 || r.Transitions[0] != (config.Transition{From: "up", To: "down"}) || r.Transitions[1] != (config.Transition{To: "up"}) {
+		t.Errorf("unexpected transitions: %+v", r.Transitions)
+	}
+	if r.During == nil || r.During.Start != 22*time.Hour || r.During.End != 6*time.Hour || r.During.Location.String() != "Europe/Berlin" {
+		t.Errorf("unexpected time range: %+v", r.During)
+	}
+}
+
+func TestLoad_AlertRoutesInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		route string
+		want  string
+	}{
+		{name: "no channels", route: `services: ["api"]`, want: "at least one channel"},
+		{name: "unknown channel", route: `channels: ["pager"]`, want: "unknown channel"},
+		{name: "bad transition", route: `transitions: ["up=>down"]
+      channels: ["chat"]`, want: "invalid transition"},
+		{name: "bad status", route: `transitions: ["up->sideways"]
+      channels: ["chat"]`, want: "invalid transition"},
+		{name: "bad severity", route: `severity: ["urgent"]
+      channels: ["chat"]`, want: "invalid severity"},
+		{name: "bad glob", route: `services: ["[payments"]
+      channels: ["chat"]`, want: "invalid service pattern"},
+		{name: "bad time", route: `during: {from: "9am", to: "17:00"}
+      channels: ["chat"]`, want: "HH:MM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+alerts:
+  channels:
+    - name: "chat"
+      type: "slack"
+      url: "https://hooks.slack.com/services/x"
+  routes:
+    - `+tt.route+"\n")
+			_, err := config.Load(path)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error should mention %q: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestTimeRange_Contains(t *testing.T) {
+	day := &config.TimeRange{Start: 9 * time.Hour, End: 17 * time.Hour, Days: []time.Weekday{time.Monday}, Location: time.UTC}
+	night := &config.TimeRange{Start: 22 * time.Hour, End: 6 * time.Hour, Location: time.UTC}
+	friNight := &config.TimeRange{Start: 22 * time.Hour, End: 6 * time.Hour, Days: []time.Weekday{time.Friday}, Location: time.UTC}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlinDay := &config.TimeRange{Start: 9 * time.Hour, End: 17 * time.Hour, Location: berlin}
+	// 2026-03-02 is a Monday.
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name string
+		r    *config.TimeRange
+		t    time.Time
+		want bool
+	}{
+		{"inside day", day, at(2, 12, 0), true},
+		{"at start", day, at(2, 9, 0), true},
+		{"at end", day, at(2, 17, 0), false},
+		{"wrong weekday", day, at(3, 12, 0), false},
+		{"night before midnight", night, at(2, 23, 30), true},
+		{"night after midnight", night, at(3, 5, 59), true},
+		{"outside night", night, at(3, 12, 0), false},
+		{"friday night on friday", friNight, at(6, 23, 0), true},
+		{"friday night into saturday", friNight, at(7, 2, 0), true},
+		{"saturday night", friNight, at(7, 23, 0), false},
+		{"thursday night into friday", friNight, at(6, 2, 0), false},
+		// Berlin's clocks go forward on 2026-03-29: 07:30 UTC is 09:30 local.
+		{"clock change day", berlinDay, time.Date(2026, 3, 29, 7, 30, 0, 0, time.UTC), true},
+		{"before start on clock change day", berlinDay, time.Date(2026, 3, 29, 6, 30, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.Contains(tt.t); got != tt.want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceSpec_Parse(t *testing.T) {
+	svc, err := config.ServiceSpec{Name: "web", Type: "http", Target: "https://example.com"}.Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if svc.Interval.Duration != 30*time.Second || svc.Timeout.Duration != 5*time.Second || svc.ExpectedStatus != 200 {
+		t.Errorf("expected defaults to be applied, got %+v", svc)
+	}
+	if back := svc.Spec(); back.Interval != "30s" || back.Timeout != "5s" {
+		t.Errorf("expected Spec to round-trip durations, got %+v", back)
+	}
+
+	if _, err := (config.ServiceSpec{Name: "web", Type: "http", Target: "x", Interval: "0s"}).Parse(); err == nil {
+		t.Error("expected error for zero interval, got nil")
+	}
+}
+
+func TestLoad_AlertEscalation(t *testing.T) {
+	base := `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+alerts:
+  channels:
+    - name: "chat"
+      type: "slack"
+      url: "https://hooks.slack.com/services/x"
+    - name: "oncall"
+      type: "webhook"
+      url: "https://pager.example.com"
+  reminder_interval: "30m"
+`
+	cfg, err := config.Load(writeTemp(t, base+`  escalation:
+    - after: "15m"
+      channels: ["oncall"]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Alerts.ReminderInterval.Duration != 30*time.Minute {
+		t.Errorf("expected reminder interval 30m, got %v", cfg.Alerts.ReminderInterval.Duration)
+	}
+	if len(cfg.Alerts.Escalation) != 1 || cfg.Alerts.Escalation[0].After.Duration != 15*time.Minute {
+		t.Errorf("unexpected escalation: %+v", cfg.Alerts.Escalation)
+	}
+
+	tests := []struct {
+		name  string
+		steps string
+		want  string
+	}{
+		{name: "zero after", steps: `
+    - after: "0s"
+      channels: ["oncall"]`, want: "after must be positive"},
+		{name: "out of order", steps: `
+    - after: "1h"
+      channels: ["oncall"]
+    - after: "15m"
+      channels: ["chat"]`, want: "increasing order"},
+		{name: "no channels", steps: `
+    - after: "15m"`, want: "at least one channel"},
+		{name: "unknown channel", steps: `
+    - after: "15m"
+      channels: ["pager"]`, want: `unknown channel "pager"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Load(writeTemp(t, base+"  escalation:"+tt.steps+"\n"))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error should mention %q: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_Flapping(t *testing.T) {
+	const services = `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+`
+	cfg, err := config.Load(writeTemp(t, services))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f := cfg.Flapping; f.Disabled || f.Window != 21 || f.LowThreshold != 25 || f.HighThreshold != 50 {
+		t.Errorf("unexpected flapping defaults: %+v", f)
+	}
+
+	for _, bad := range []string{
+		"flapping:\n  window: 2\n",
+		"flapping:\n  low_threshold: 60\n",
+		"flapping:\n  high_threshold: 120\n",
+	} {
+		if _, err := config.Load(writeTemp(t, services+bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoad_Retention(t *testing.T) {
+	const services = `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+`
+	cfg, err := config.Load(writeTemp(t, services))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Storage.Retention != config.DefaultRetention {
+		t.Errorf("expected default retention, got %+v", cfg.Storage.Retention)
+	}
+
+	cfg, err = config.Load(writeTemp(t, services+"storage:\n  retention:\n    raw_days: 0\n    daily_days: 730\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := config.RetentionConfig{RawDays: 0, HourlyDays: 365, DailyDays: 730}
+	if cfg.Storage.Retention != want {
+		t.Errorf("expected %+v, got %+v", want, cfg.Storage.Retention)
+	}
+
+	if _, err := config.Load(writeTemp(t, services+"storage:\n  retention:\n    hourly_days: -1\n")); err == nil {
+		t.Error("expected error for negative retention")
+	}
+}
+
+func TestLoad_StorageDriver(t *testing.T) {
+	const services = `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+`
+	cfg, err := config.Load(writeTemp(t, services+"storage:\n  driver: postgres\n  dsn: postgres://servprobe@db/servprobe\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Storage.Driver != config.DriverPostgres || cfg.Storage.DSN != "postgres://servprobe@db/servprobe" {
+		t.Errorf("unexpected storage config: %+v", cfg.Storage)
+	}
+
+	for _, bad := range []string{
+		"storage:\n  driver: postgres\n",
+		"storage:\n  driver: mysql\n",
+	} {
+		if _, err := config.Load(writeTemp(t, services+bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoad_StorageWrites(t *testing.T) {
+	const services = `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+`
+	cfg, err := config.Load(writeTemp(t, services))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := config.WriteConfig{
+		FlushInterval: config.Duration{Duration: config.DefaultFlushInterval},
+		BatchSize:     config.DefaultBatchSize,
+		QueueSize:     config.DefaultQueueSize,
+	}
+	if cfg.Storage.Writes != want {
+		t.Errorf("expected default writes %+v, got %+v", want, cfg.Storage.Writes)
+	}
+
+	cfg, err = config.Load(writeTemp(t, services+"storage:\n  writes:\n    flush_interval: 250ms\n    queue_size: 50\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Storage.Writes.FlushInterval.Duration != 250*time.Millisecond || cfg.Storage.Writes.QueueSize != 50 || cfg.Storage.Writes.BatchSize != config.DefaultBatchSize {
+		t.Errorf("unexpected writes: %+v", cfg.Storage.Writes)
+	}
+
+	if _, err := config.Load(writeTemp(t, services+"storage:\n  writes:\n    batch_size: -1\n")); err == nil {
+		t.Error("expected error for negative batch size")
+	}
+}
+
+func TestLoad_Telemetry(t *testing.T) {
+	const services = `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+`
+	cfg, err := config.Load(writeTemp(t, services))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Telemetry.Enabled() {
+		t.Error("expected telemetry to be off without an endpoint")
+	}
+
+	cfg, err = config.Load(writeTemp(t, services+"telemetry:\n  endpoint: collector:4318\n  protocol: http\n  headers:\n    x-api-key: secret\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tc := cfg.Telemetry
+	if !tc.Enabled() || tc.Protocol != config.ProtocolHTTP || tc.Headers["x-api-key"] != "secret" ||
+		tc.ServiceName != config.DefaultTelemetryServiceName || tc.MetricInterval.Duration != config.DefaultMetricInterval {
+		t.Errorf("unexpected telemetry: %+v", tc)
+	}
+
+	for _, bad := range []string{
+		"telemetry:\n  endpoint: collector:4317\n  protocol: thrift\n",
+		"telemetry:\n  endpoint: http://collector:4318\n",
+		"telemetry:\n  endpoint: collector:4317\n  metric_interval: -1s\n",
+	} {
+		if _, err := config.Load(writeTemp(t, services+bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestLoad_ServiceSLA(t *testing.T) {
+	cfg, err := config.Load(writeTemp(t, `
+services:
+  - name: "api"
+    type: "http"
+    target: "https://example.com"
+    sla:
+      target: 99.9
+  - name: "db"
+    type: "tcp"
+    target: "db:5432"
+    sla:
+      target: 99.5
+      window: "7d"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Services[0].SLA; got != (config.SLA{Target: 99.9, Window: "30d"}) {
+		t.Errorf("expected 30d window by default, got %+v", got)
+	}
+	if got := cfg.Services[1].SLA; got != (config.SLA{Target: 99.5, Window: "7d"}) {
+		t.Errorf("unexpected SLA: %+v", got)
+	}
+
+	for _, bad := range []string{"target: 100", "target: -1", "window: \"7d\"", "target: 99\n      window: \"1y\""} {
+		_, err := config.Load(writeTemp(t, "services:\n  - name: api\n    type: http\n    target: https://example.com\n    sla:\n      "+bad+"\n"))
+		if err == nil {
+			t.Errorf("expected error for sla %q", bad)
+		}
+	}
+}
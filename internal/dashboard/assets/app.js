@@ -15,12 +15,18 @@ const detailType = document.getElementById('detail-type');
 const detailInfo = document.getElementById('detail-info');
 const detailTable = document.getElementById('detail-table');
 const detailChart = document.getElementById('chart');
+const incidentTimeline = document.getElementById('incident-timeline');
+const incidentList = document.getElementById('incident-list');
 const refreshInfo = document.getElementById('refresh-info');
 const closeBtn = document.getElementById('close-detail');
+const checkBtn = document.getElementById('check-now');
 const countUp = document.getElementById('count-up');
 const countDown = document.getElementById('count-down');
+const countPaused = document.getElementById('count-paused');
+const countFlapping = document.getElementById('count-flapping');
 
 closeBtn.addEventListener('click', closeDetail);
+checkBtn.addEventListener('click', checkNow);
 overlay.addEventListener('click', (e) => {
   if (e.target === overlay) closeDetail();
 });
@@ -42,9 +48,16 @@ async function apiFetch(path) {
   return body.data;
 }
 
+async function apiPost(path) {
+  const resp = await fetch(path, { method: 'POST' });
+  const body = await resp.json().catch(() => ({}));
+  if (!resp.ok || body.error) throw new Error(body.error || `HTTP ${resp.status}`);
+  return body.data;
+}
+
 // --- Render helpers ---
 function statusClass(status) {
-  return status === 'up' ? 'up' : status === 'down' ? 'down' : 'unknown';
+  return ['up', 'down', 'paused', 'flapping'].includes(status) ? status : 'unknown';
 }
 
 function fmtMs(ms) {
@@ -65,6 +78,20 @@ function fmtDateTime(ts) {
   return d.toLocaleString(undefined, { month: 'short', day: 'numeric', hour: '2-digit', minute: '2-digit', second: '2-digit' });
 }
 
+function fmtDuration(seconds) {
+  const s = Math.round(seconds);
+  if (s < 60) return `${s}s`;
+  const m = Math.floor(s / 60);
+  if (m < 60) return `${m}m ${s % 60}s`;
+  const h = Math.floor(m / 60);
+  if (h < 24) return `${h}h ${m % 60}m`;
+  return `${Math.floor(h / 24)}d ${h % 24}h`;
+}
+
+function fmtPct(pct) {
+  return pct != null ? pct.toFixed(2) + '%' : '—';
+}
+
 function uptimeClass(pct) {
   if (pct >= 99) return 'high';
   if (pct >= 90) return 'mid';
@@ -84,6 +111,7 @@ function renderCard(svc) {
       <div class="status-dot ${cls}"></div>
       <span class="card-name">${svc.name}</span>
       <span class="type-badge">${svc.type}</span>
+      ${svc.maintenance ? '<span class="maint-badge">Maintenance</span>' : ''}
     </div>
     <div class="card-meta">
       <div class="meta-item">
@@ -129,6 +157,12 @@ function renderGrid() {
   } else {
     countDown.style.display = 'none';
   }
+  const paused = services.filter(s => s.status === 'paused').length;
+  countPaused.textContent = `${paused} paused`;
+  countPaused.style.display = paused > 0 ? '' : 'none';
+  const flapping = services.filter(s => s.status === 'flapping').length;
+  countFlapping.textContent = `${flapping} flapping`;
+  countFlapping.style.display = flapping > 0 ? '' : 'none';
 }
 
 function drawChart(checks) {
@@ -202,6 +236,42 @@ function drawChart(checks) {
   });
 }
 
+const TIMELINE_DAYS = 7;
+
+// renderIncidents draws the incidents overlapping [from, to) as bars on a
+// timeline, one tick per day, and lists them below it.
+function renderIncidents(incidents, from, to) {
+  const span = to - from;
+  const pos = t => Math.min(Math.max((t - from) / span, 0), 1) * 100;
+
+  let html = '';
+  for (let i = 0; i <= TIMELINE_DAYS; i++) {
+    const day = new Date(from.getTime() + i * 86400000);
+    html += `<span class="timeline-tick" style="left:${pos(day)}%">${i < TIMELINE_DAYS ? day.toLocaleDateString(undefined, { weekday: 'short' }) : ''}</span>`;
+  }
+  incidents.forEach(inc => {
+    const start = new Date(inc.started_at);
+    const end = inc.resolved_at ? new Date(inc.resolved_at) : to;
+    const left = pos(start);
+    const width = Math.max(pos(end) - left, 0.3);
+    html += `<div class="timeline-bar ${inc.ongoing ? 'ongoing' : ''}" style="left:${left}%;width:${width}%" title="${fmtDateTime(inc.started_at)} · ${fmtDuration(inc.duration_seconds)}"></div>`;
+  });
+  incidentTimeline.innerHTML = html;
+
+  if (incidents.length === 0) {
+    incidentList.innerHTML = '<li class="incident-empty">No incidents.</li>';
+    return;
+  }
+  incidentList.innerHTML = incidents.map(inc => `
+    <li>
+      <span class="status-badge ${inc.ongoing ? 'down' : 'up'}">${inc.ongoing ? 'ONGOING' : 'RESOLVED'}</span>
+      <span>${fmtDateTime(inc.started_at)}</span>
+      <span class="incident-duration">${fmtDuration(inc.duration_seconds)}</span>
+      <span class="incident-error">${inc.first_error || '—'}</span>
+      <span class="incident-checks">${inc.check_count} checks</span>
+    </li>`).join('');
+}
+
 async function showDetail(name) {
   selectedService = name;
   overlay.classList.add('visible');
@@ -210,9 +280,12 @@ async function showDetail(name) {
   detailTable.querySelector('tbody').innerHTML = '';
 
   try {
-    const [svc, histResp] = await Promise.all([
+    const to = new Date();
+    const from = new Date(to.getTime() - TIMELINE_DAYS * 86400000);
+    const [svc, histResp, incidents] = await Promise.all([
       apiFetch(`/api/services/${encodeURIComponent(name)}`),
       apiFetch(`/api/services/${encodeURIComponent(name)}/history?limit=50`),
+      apiFetch(`/api/services/${encodeURIComponent(name)}/incidents?from=${from.toISOString()}&to=${to.toISOString()}`),
     ]);
 
     const cls = statusClass(svc.status);
@@ -222,7 +295,8 @@ async function showDetail(name) {
     detailInfo.innerHTML = `
       <div class="stat-card">
         <div class="stat-label">Status</div>
-        <div class="stat-value" style="color:var(--${cls === 'up' ? 'green' : cls === 'down' ? 'red' : 'text-muted'})">${(svc.status || 'unknown').toUpperCase()}</div>
+        <div class="stat-value" style="color:var(--${cls === 'up' ? 'green' : cls === 'down' ? 'red' : cls === 'flapping' ? 'amber' : 'text-muted'})">${(svc.status || 'unknown').toUpperCase()}</div>
+        ${svc.flapping_since ? `<div class="stat-sub">since ${fmtDateTime(svc.flapping_since)}, ${svc.state_change_percent.toFixed(0)}% state change</div>` : ''}
       </div>
       <div class="stat-card">
         <div class="stat-label">Target</div>
@@ -230,15 +304,25 @@ async function showDetail(name) {
       </div>
       <div class="stat-card">
         <div class="stat-label">Interval</div>
-        <div class="stat-value">${svc.interval}</div>
+        <div class="stat-value">${svc.paused ? 'Paused since ' + fmtDateTime(svc.paused_since) : svc.interval}</div>
+      </div>
+      <div class="stat-card">
+        <div class="stat-label">Uptime (24h)</div>
+        <div class="stat-value">${fmtPct((svc.uptime || {})['24h'])}</div>
+        <div class="stat-sub">7d ${fmtPct((svc.uptime || {})['7d'])} · 30d ${fmtPct((svc.uptime || {})['30d'])} · 90d ${fmtPct((svc.uptime || {})['90d'])}</div>
       </div>
+      ${svc.sla ? `
       <div class="stat-card">
-        <div class="stat-label">Uptime</div>
-        <div class="stat-value">${svc.uptime_percent != null ? svc.uptime_percent.toFixed(1) + '%' : '—'}</div>
-      </div>`;
+        <div class="stat-label">SLA ${svc.sla.target}% (${svc.sla.window})</div>
+        <div class="stat-value" style="color:var(--${svc.sla.met ? 'green' : 'red'})">${svc.sla.error_budget_remaining_percent.toFixed(0)}% budget left</div>
+        <div class="stat-sub">${svc.sla.error_budget_remaining_seconds >= 0
+          ? fmtDuration(svc.sla.error_budget_remaining_seconds) + ' of ' + fmtDuration(svc.sla.error_budget_seconds) + ' downtime left'
+          : 'over budget by ' + fmtDuration(-svc.sla.error_budget_remaining_seconds)}</div>
+      </div>` : ''}`;
 
     const checks = (histResp.checks || []).slice().reverse();
     drawChart(checks);
+    renderIncidents(incidents || [], from, to);
 
     const tbody = detailTable.querySelector('tbody');
     tbody.innerHTML = '';
@@ -256,6 +340,23 @@ async function showDetail(name) {
   }
 }
 
+// checkNow asks the server to check the open service immediately, then
+// refreshes so the fresh result shows up everywhere.
+async function checkNow() {
+  if (!selectedService) return;
+  checkBtn.disabled = true;
+  checkBtn.textContent = 'Checking…';
+  try {
+    await apiPost(`/api/services/${encodeURIComponent(selectedService)}/check`);
+    await refresh();
+  } catch (e) {
+    refreshInfo.textContent = `Check failed: ${e.message}`;
+  } finally {
+    checkBtn.disabled = false;
+    checkBtn.textContent = 'Check now';
+  }
+}
+
 // --- Main refresh loop ---
 async function refresh() {
   try {
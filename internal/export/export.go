@@ -0,0 +1,266 @@
+// Package export reads and writes check history as CSV or JSON Lines, for
+// moving it between databases and servprobe instances or into other tools.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// Format is an export file format.
+type Format string
+
+const (
+	CSV   Format = "csv"
+	JSONL Format = "jsonl"
+)
+
+// ParseFormat returns the format called name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case CSV, JSONL:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q: use csv or jsonl", name)
+}
+
+// FormatOf returns the format of the file at path, going by its extension.
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return CSV, nil
+	case ".jsonl", ".ndjson":
+		return JSONL, nil
+	}
+	return "", fmt.Errorf("can't tell the format of %q from its extension: use .csv or .jsonl", path)
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// columns are the CSV columns, in order. Database IDs aren't exported, as
+// they mean nothing to another database.
+var columns = []string{"service", "checked_at", "status", "response_ms", "error", "maintenance"}
+
+// record is a check in a JSON Lines export.
+type record struct {
+	Service     string    `json:"service"`
+	CheckedAt   time.Time `json:"checked_at"`
+	Status      string    `json:"status"`
+	ResponseMs  int64     `json:"response_ms"`
+	Error       string    `json:"error,omitempty"`
+	Maintenance bool      `json:"maintenance,omitempty"`
+}
+
+// Writer writes checks in a format. Call Flush when done.
+type Writer struct {
+	csv  *csv.Writer
+	json *json.Encoder
+	buf  *bufio.Writer
+}
+
+// NewWriter returns a Writer of format f to w. CSV output starts with a
+// header row.
+func NewWriter(w io.Writer, f Format) *Writer {
+	ew := &Writer{}
+	if f == CSV {
+		ew.csv = csv.NewWriter(w)
+		ew.csv.Write(columns)
+	} else {
+		ew.buf = bufio.NewWriter(w)
+		ew.json = json.NewEncoder(ew.buf)
+	}
+	return ew
+}
+
+// Write writes c. Output is buffered, so errors may only show on a later
+// Write or on Flush.
+func (w *Writer) Write(c storage.Check) error {
+	if w.csv != nil {
+		return w.csv.Write([]string{
+			c.Service,
+			c.CheckedAt.UTC().Format(time.RFC3339Nano),
+			c.Status,
+			strconv.FormatInt(c.ResponseMs, 10),
+			c.Error,
+			strconv.FormatBool(c.Maintenance),
+		})
+	}
+	return w.json.Encode(record{
+		Service:     c.Service,
+		CheckedAt:   c.CheckedAt.UTC(),
+		Status:      c.Status,
+		ResponseMs:  c.ResponseMs,
+		Error:       c.Error,
+		Maintenance: c.Maintenance,
+	})
+}
+
+// Flush writes out buffered checks.
+func (w *Writer) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return w.buf.Flush()
+}
+
+// Reader reads checks in a format.
+type Reader struct {
+	csv   *csv.Reader
+	index map[string]int // column positions by name, once the header is read
+	lines *bufio.Reader
+	line  int
+}
+
+// NewReader returns a Reader of format f from r. CSV input must start with a
+// header row naming its columns; they may come in any order, extra ones are
+// ignored, and only service, checked_at and status are required.
+func NewReader(r io.Reader, f Format) *Reader {
+	if f == CSV {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		return &Reader{csv: cr}
+	}
+	return &Reader{lines: bufio.NewReader(r)}
+}
+
+// Read returns the next check, or io.EOF after the last one. Errors name the
+// line at fault.
+func (r *Reader) Read() (storage.Check, error) {
+	var (
+		c   storage.Check
+		err error
+	)
+	if r.csv != nil {
+		c, err = r.readCSV()
+	} else {
+		c, err = r.readJSON()
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := validate(c); err != nil {
+		return c, fmt.Errorf("line %d: %w", r.line, err)
+	}
+	return c, nil
+}
+
+func (r *Reader) readCSV() (storage.Check, error) {
+	if r.index == nil {
+		header, err := r.csv.Read()
+		if err == io.EOF {
+			return storage.Check{}, io.EOF
+		}
+		if err != nil {
+			return storage.Check{}, fmt.Errorf("reading header: %w", err)
+		}
+		r.index = make(map[string]int, len(header))
+		for i, name := range header {
+			r.index[strings.TrimSpace(strings.ToLower(name))] = i
+		}
+		for _, name := range []string{"service", "checked_at", "status"} {
+			if _, ok := r.index[name]; !ok {
+				return storage.Check{}, fmt.Errorf("header has no %s column", name)
+			}
+		}
+	}
+
+	row, err := r.csv.Read()
+	if err == io.EOF {
+		return storage.Check{}, io.EOF
+	}
+	if err != nil {
+		return storage.Check{}, err
+	}
+	r.line, _ = r.csv.FieldPos(0)
+	field := func(name string) string {
+		if i, ok := r.index[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	c := storage.Check{Service: field("service"), Status: field("status"), Error: field("error")}
+	fail := func(name string, err error) (storage.Check, error) {
+		return c, fmt.Errorf("line %d: invalid %s %q: %w", r.line, name, field(name), err)
+	}
+	if c.CheckedAt, err = time.Parse(time.RFC3339Nano, field("checked_at")); err != nil {
+		return fail("checked_at", err)
+	}
+	if v := field("response_ms"); v != "" {
+		if c.ResponseMs, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return fail("response_ms", err)
+		}
+	}
+	if v := field("maintenance"); v != "" {
+		if c.Maintenance, err = strconv.ParseBool(v); err != nil {
+			return fail("maintenance", err)
+		}
+	}
+	return c, nil
+}
+
+func (r *Reader) readJSON() (storage.Check, error) {
+	for {
+		line, err := r.lines.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			return storage.Check{}, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return storage.Check{}, err
+		}
+		r.line++
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return storage.Check{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return storage.Check{
+			Service:     rec.Service,
+			Status:      rec.Status,
+			ResponseMs:  rec.ResponseMs,
+			Error:       rec.Error,
+			CheckedAt:   rec.CheckedAt,
+			Maintenance: rec.Maintenance,
+		}, nil
+	}
+}
+
+// validate rejects checks the database wouldn't take, or would take but
+// shouldn't.
+func validate(c storage.Check) error {
+	switch {
+	case c.Service == "":
+		return errors.New("missing service")
+	case c.CheckedAt.IsZero():
+		return errors.New("missing checked_at")
+	case c.ResponseMs < 0:
+		return fmt.Errorf("negative response_ms %d", c.ResponseMs)
+	}
+	switch checker.Status(c.Status) {
+	case checker.StatusUp, checker.StatusDown:
+		return nil
+	}
+	return fmt.Errorf("invalid status %q: use up or down", c.Status)
+}
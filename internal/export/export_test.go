@@ -0,0 +1,140 @@
+package export_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/export"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func makeChecks() []storage.Check {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	return []storage.Check{
+		{Service: "api", Status: "up", ResponseMs: 42, CheckedAt: t0},
+		{Service: "api", Status: "down", Error: `dial tcp: "refused", again`, CheckedAt: t0.Add(time.Minute)},
+		{Service: "db", Status: "up", ResponseMs: 3, CheckedAt: t0.Add(2 * time.Minute), Maintenance: true},
+	}
+}
+
+// readAll reads every check from in.
+func readAll(t *testing.T, in string, f export.Format) ([]storage.Check, error) {
+	t.Helper()
+	r := export.NewReader(strings.NewReader(in), f)
+	var checks []storage.Check
+	for {
+		c, err := r.Read()
+		if err == io.EOF {
+			return checks, nil
+		}
+		if err != nil {
+			return checks, err
+		}
+		checks = append(checks, c)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, f := range []export.Format{export.CSV, export.JSONL} {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := export.NewWriter(&buf, f)
+			// IDs aren't exported.
+			for i, c := range makeChecks() {
+				c.ID = int64(i + 1)
+				if err := w.Write(c); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+
+			got, err := readAll(t, buf.String(), f)
+			if err != nil {
+				t.Fatalf("reading %s:\n%s\n%v", f, buf.String(), err)
+			}
+			want := makeChecks()
+			if len(got) != len(want) {
+				t.Fatalf("expected %d checks, got %d", len(want), len(got))
+			}
+			for i := range want {
+				if !got[i].CheckedAt.Equal(want[i].CheckedAt) {
+					t.Errorf("check %d: expected checked_at %v, got %v", i, want[i].CheckedAt, got[i].CheckedAt)
+				}
+				got[i].CheckedAt = want[i].CheckedAt
+				if got[i] != want[i] {
+					t.Errorf("check %d: expected %+v, got %+v", i, want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriter_CSVHeader(t *testing.T) {
+	var buf bytes.Buffer
+	w := export.NewWriter(&buf, export.CSV)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := buf.String(); got != "service,checked_at,status,response_ms,error,maintenance\n" {
+		t.Errorf("expected just the header, got %q", got)
+	}
+}
+
+func TestReader_CSVColumns(t *testing.T) {
+	// Any order, extra columns ignored, optional ones left out.
+	in := "id,status,Service,checked_at\n7,down,api,2026-03-01T12:00:00Z\n"
+	got, err := readAll(t, in, export.CSV)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(got) != 1 || got[0].Service != "api" || got[0].Status != "down" || got[0].ID != 0 {
+		t.Errorf("unexpected checks: %+v", got)
+	}
+}
+
+func TestReader_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format export.Format
+		in     string
+		want   string
+	}{
+		{"csv missing column", export.CSV, "service,status\napi,up\n", "no checked_at column"},
+		{"csv bad time", export.CSV, "service,checked_at,status\napi,2026-03-01T12:00:00Z,up\napi,yesterday,up\n", "line 3: invalid checked_at"},
+		{"csv bad status", export.CSV, "service,checked_at,status\napi,2026-03-01T12:00:00Z,sideways\n", `line 2: invalid status "sideways"`},
+		{"csv bad response", export.CSV, "service,checked_at,status,response_ms\napi,2026-03-01T12:00:00Z,up,-1\n", "line 2: negative response_ms"},
+		{"jsonl syntax", export.JSONL, "\n{\"service\":\"api\"\n", "line 2:"},
+		{"jsonl missing service", export.JSONL, `{"checked_at":"2026-03-01T12:00:00Z","status":"up"}`, "line 1: missing service"},
+		{"jsonl missing time", export.JSONL, `{"service":"api","status":"up"}`, "line 1: missing checked_at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAll(t, tt.in, tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	if f, err := export.ParseFormat("CSV"); err != nil || f != export.CSV {
+		t.Errorf("ParseFormat(CSV) = %q, %v", f, err)
+	}
+	if _, err := export.ParseFormat("parquet"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	for path, want := range map[string]export.Format{"a.csv": export.CSV, "b.jsonl": export.JSONL, "c.ndjson": export.JSONL} {
+		if f, err := export.FormatOf(path); err != nil || f != want {
+			t.Errorf("FormatOf(%q) = %q, %v", path, f, err)
+		}
+	}
+	if _, err := export.FormatOf("history.json"); err == nil {
+		t.Error("expected an error for an unknown extension")
+	}
+}
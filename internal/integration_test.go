@@ -77,6 +77,7 @@ func TestIntegration_FullFlow(t *testing.T) {
 
 	// 7. Build API server
 	apiServer := server.New(db, services, nil)
+	apiServer.SetState(sched.State())
 
 	// 8. GET /api/health
 	t.Run("health endpoint", func(t *testing.T) {
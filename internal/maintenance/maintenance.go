@@ -0,0 +1,268 @@
+// Package maintenance decides whether a service is inside a planned
+// maintenance window. Windows come from the config file (one-off or
+// recurring) and from the API (ad-hoc, persisted in storage).
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// Store persists ad-hoc maintenance windows.
+type Store interface {
+	InsertMaintenance(ctx context.Context, w storage.MaintenanceWindow) (storage.MaintenanceWindow, error)
+	ListMaintenance(ctx context.Context, since time.Time) ([]storage.MaintenanceWindow, error)
+	DeleteMaintenance(ctx context.Context, id int64) error
+}
+
+// Window is a single maintenance occurrence as reported by the API.
+type Window struct {
+	ID        int64     `json:"id,omitempty"`
+	Name      string    `json:"name"`
+	Services  []string  `json:"services"`
+	Tags      []string  `json:"tags"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Recurring bool      `json:"recurring"`
+	Source    string    `json:"source"`
+	Active    bool      `json:"active"`
+}
+
+// Schedule holds the static windows from config and the ad-hoc windows
+// from storage. It is safe for concurrent use.
+type Schedule struct {
+	mu     sync.RWMutex
+	static []config.MaintenanceWindow
+	adhoc  []storage.MaintenanceWindow
+	store  Store
+}
+
+// New creates a Schedule. store may be nil, in which case ad-hoc windows are
+// not supported.
+func New(static []config.MaintenanceWindow, store Store) *Schedule {
+	return &Schedule{static: static, store: store}
+}
+
+// Load reads current and upcoming ad-hoc windows from the store.
+func (s *Schedule) Load(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+	windows, err := s.store.ListMaintenance(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("loading maintenance windows: %w", err)
+	}
+	s.mu.Lock()
+	s.adhoc = windows
+	s.mu.Unlock()
+	return nil
+}
+
+// Active reports whether svc is inside a maintenance window at t.
+func (s *Schedule) Active(svc config.Service, t time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, w := range s.static {
+		if matches(svc, w.Services, w.Tags) {
+			if _, _, ok := occurrence(w, t); ok {
+				return true
+			}
+		}
+	}
+	for _, w := range s.adhoc {
+		if matches(svc, w.Services, w.Tags) && !t.Before(w.StartsAt) && t.Before(w.EndsAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Create validates and persists an ad-hoc window.
+func (s *Schedule) Create(ctx context.Context, w storage.MaintenanceWindow) (storage.MaintenanceWindow, error) {
+	if s.store == nil {
+		return w, fmt.Errorf("ad-hoc maintenance windows are not supported")
+	}
+	if !w.EndsAt.After(w.StartsAt) {
+		return w, fmt.Errorf("end must be after start")
+	}
+	w, err := s.store.InsertMaintenance(ctx, w)
+	if err != nil {
+		return w, err
+	}
+	now := time.Now()
+	s.mu.Lock()
+	// Windows that have ended are dropped here, so the list stays as short
+	// as Load would leave it.
+	kept := s.adhoc[:0]
+	for _, old := range s.adhoc {
+		if !old.EndsAt.Before(now) {
+			kept = append(kept, old)
+		}
+	}
+	s.adhoc = append(kept, w)
+	s.mu.Unlock()
+	return w, nil
+}
+
+// Delete removes an ad-hoc window.
+func (s *Schedule) Delete(ctx context.Context, id int64) error {
+	if s.store == nil {
+		return fmt.Errorf("ad-hoc maintenance windows are not supported")
+	}
+	if err := s.store.DeleteMaintenance(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, w := range s.adhoc {
+		if w.ID == id {
+			s.adhoc = append(s.adhoc[:i], s.adhoc[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SetStatic replaces the windows loaded from config.
+func (s *Schedule) SetStatic(static []config.MaintenanceWindow) {
+	s.mu.Lock()
+	s.static = static
+	s.mu.Unlock()
+}
+
+// List returns active and upcoming windows at now, ordered by start. Recurring
+// windows are reported by their current or next occurrence.
+func (s *Schedule) List(now time.Time) []Window {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	windows := make([]Window, 0, len(s.static)+len(s.adhoc))
+	for _, w := range s.static {
+		start, end, active := occurrence(w, now)
+		if !active {
+			var ok bool
+			start, end, ok = next(w, now)
+			if !ok {
+				continue
+			}
+		}
+		windows = append(windows, Window{
+			Name:      w.Name,
+			Services:  nonNil(w.Services),
+			Tags:      nonNil(w.Tags),
+			Start:     start,
+			End:       end,
+			Recurring: w.Schedule != nil,
+			Source:    "config",
+			Active:    active,
+		})
+	}
+	for _, w := range s.adhoc {
+		if !now.Before(w.EndsAt) {
+			continue
+		}
+		windows = append(windows, Window{
+			ID:       w.ID,
+			Name:     w.Name,
+			Services: nonNil(w.Services),
+			Tags:     nonNil(w.Tags),
+			Start:    w.StartsAt,
+			End:      w.EndsAt,
+			Source:   "api",
+			Active:   !now.Before(w.StartsAt),
+		})
+	}
+	sort.SliceStable(windows, func(i, j int) bool {
+		return windows[i].Start.Before(windows[j].Start)
+	})
+	return windows
+}
+
+// matches reports whether svc is in scope. Empty services and tags match all.
+func matches(svc config.Service, services, tags []string) bool {
+	if len(services) == 0 && len(tags) == 0 {
+		return true
+	}
+	for _, name := range services {
+		if name == svc.Name {
+			return true
+		}
+	}
+	for _, tag := range tags {
+		if svc.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// occurrence returns the occurrence of w containing t, if any.
+func occurrence(w config.MaintenanceWindow, t time.Time) (start, end time.Time, ok bool) {
+	if w.Schedule == nil {
+		return w.Start, w.End, !t.Before(w.Start) && t.Before(w.End)
+	}
+	sched := w.Schedule
+	// An occurrence containing t started at most Duration ago, so walk back
+	// over every day that could have started one.
+	days := int(sched.Duration/(24*time.Hour)) + 1
+	local := t.In(sched.Location)
+	for i := 0; i <= days; i++ {
+		start := startOn(sched, local.AddDate(0, 0, -i))
+		if !runsOn(sched, start.Weekday()) {
+			continue
+		}
+		end := start.Add(sched.Duration)
+		if !t.Before(start) && t.Before(end) {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// next returns the first occurrence of w starting after t, if any.
+func next(w config.MaintenanceWindow, t time.Time) (start, end time.Time, ok bool) {
+	if w.Schedule == nil {
+		return w.Start, w.End, t.Before(w.Start)
+	}
+	sched := w.Schedule
+	local := t.In(sched.Location)
+	for i := 0; i <= 7; i++ {
+		start := startOn(sched, local.AddDate(0, 0, i))
+		if runsOn(sched, start.Weekday()) && start.After(t) {
+			return start, start.Add(sched.Duration), true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func startOn(sched *config.MaintenanceSchedule, day time.Time) time.Time {
+	y, m, d := day.Date()
+	hour := int(sched.At / time.Hour)
+	minute := int(sched.At % time.Hour / time.Minute)
+	return time.Date(y, m, d, hour, minute, 0, 0, sched.Location)
+}
+
+func runsOn(sched *config.MaintenanceSchedule, wd time.Weekday) bool {
+	if len(sched.Days) == 0 {
+		return true
+	}
+	for _, d := range sched.Days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+package maintenance_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/maintenance"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func openTestDB(t *testing.T) *storage.DB {
+	t.Helper()
+	db, err := storage.Open(":memory:")
+	if err != nil {
+		t.Fatalf("opening in-memory DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
+
+var (
+	api     = config.Service{Name: "api", Tags: []string{"prod"}}
+	staging = config.Service{Name: "staging-api", Tags: []string{"staging"}}
+)
+
+func TestActive_OneOff(t *testing.T) {
+	s := maintenance.New([]config.MaintenanceWindow{{
+		Name:     "upgrade",
+		Services: []string{"api"},
+		Start:    mustTime(t, "2026-03-01T02:00:00Z"),
+		End:      mustTime(t, "2026-03-01T04:00:00Z"),
+	}}, nil)
+
+	if !s.Active(api, mustTime(t, "2026-03-01T03:00:00Z")) {
+		t.Error("expected api to be in maintenance at 03:00")
+	}
+	if s.Active(api, mustTime(t, "2026-03-01T04:00:00Z")) {
+		t.Error("expected window end to be exclusive")
+	}
+	if s.Active(staging, mustTime(t, "2026-03-01T03:00:00Z")) {
+		t.Error("expected staging-api to be out of scope")
+	}
+}
+
+func TestActive_RecurringAcrossMidnight(t *testing.T) {
+	s := maintenance.New([]config.MaintenanceWindow{{
+		Name: "nightly",
+		Tags: []string{"prod"},
+		Schedule: &config.MaintenanceSchedule{
+			Days:     []time.Weekday{time.Saturday},
+			At:       23 * time.Hour,
+			Duration: 2 * time.Hour,
+			Location: time.UTC,
+		},
+	}}, nil)
+
+	// 2026-02-28 is a Saturday.
+	tests := []struct {
+		at   string
+		want bool
+	}{
+		{"2026-02-28T22:59:00Z", false},
+		{"2026-02-28T23:30:00Z", true},
+		{"2026-03-01T00:30:00Z", true}, // Sunday, still inside Saturday's window
+		{"2026-03-01T01:00:00Z", false},
+		{"2026-03-01T23:30:00Z", false}, // Sunday has no occurrence
+	}
+	for _, tt := range tests {
+		if got := s.Active(api, mustTime(t, tt.at)); got != tt.want {
+			t.Errorf("Active at %s = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+	if s.Active(staging, mustTime(t, "2026-02-28T23:30:00Z")) {
+		t.Error("expected staging-api (no prod tag) to be out of scope")
+	}
+}
+
+func TestActive_EmptyScopeMatchesAll(t *testing.T) {
+	s := maintenance.New([]config.MaintenanceWindow{{
+		Start: mustTime(t, "2026-03-01T02:00:00Z"),
+		End:   mustTime(t, "2026-03-01T04:00:00Z"),
+	}}, nil)
+	if !s.Active(staging, mustTime(t, "2026-03-01T03:00:00Z")) {
+		t.Error("expected a window without scope to match every service")
+	}
+}
+
+func TestCreate_PersistsAndReloads(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	s := maintenance.New(nil, db)
+	w, err := s.Create(ctx, storage.MaintenanceWindow{
+		Name:     "deploy",
+		Tags:     []string{"prod"},
+		StartsAt: now.Add(-time.Minute),
+		EndsAt:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !s.Active(api, now) {
+		t.Error("expected api to be in maintenance after Create")
+	}
+
+	// A fresh schedule picks the window up from storage.
+	reloaded := maintenance.New(nil, db)
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reloaded.Active(api, now) {
+		t.Error("expected reloaded schedule to include the ad-hoc window")
+	}
+
+	if err := s.Delete(ctx, w.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if s.Active(api, now) {
+		t.Error("expected api to be out of maintenance after Delete")
+	}
+}
+
+func TestCreate_DropsEndedWindows(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := maintenance.New(nil, openTestDB(t))
+
+	past := now.Add(-90 * time.Minute)
+	if _, err := s.Create(ctx, storage.MaintenanceWindow{Name: "done", StartsAt: past.Add(-time.Hour), EndsAt: past.Add(time.Hour)}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !s.Active(api, past) {
+		t.Fatal("expected the ended window to be held until the next Create")
+	}
+	if _, err := s.Create(ctx, storage.MaintenanceWindow{Name: "next", StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if s.Active(api, past) {
+		t.Error("expected the ended window to be dropped")
+	}
+}
+
+func TestCreate_RejectsEmptyWindow(t *testing.T) {
+	s := maintenance.New(nil, openTestDB(t))
+	now := time.Now()
+	_, err := s.Create(context.Background(), storage.MaintenanceWindow{StartsAt: now, EndsAt: now})
+	if err == nil {
+		t.Fatal("expected error for zero-length window, got nil")
+	}
+}
+
+func TestList(t *testing.T) {
+	now := mustTime(t, "2026-02-28T12:00:00Z") // Saturday
+	s := maintenance.New([]config.MaintenanceWindow{
+		{
+			Name:  "past",
+			Start: mustTime(t, "2026-02-01T00:00:00Z"),
+			End:   mustTime(t, "2026-02-01T01:00:00Z"),
+		},
+		{
+			Name: "daily",
+			Schedule: &config.MaintenanceSchedule{
+				At:       2 * time.Hour,
+				Duration: time.Hour,
+				Location: time.UTC,
+			},
+		},
+		{
+			Name:  "now",
+			Start: mustTime(t, "2026-02-28T11:00:00Z"),
+			End:   mustTime(t, "2026-02-28T13:00:00Z"),
+		},
+	}, nil)
+
+	windows := s.List(now)
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 windows (past one omitted), got %d: %+v", len(windows), windows)
+	}
+	if windows[0].Name != "now" || !windows[0].Active {
+		t.Errorf("expected active 'now' window first, got %+v", windows[0])
+	}
+	daily := windows[1]
+	if daily.Name != "daily" || daily.Active || !daily.Recurring {
+		t.Errorf("unexpected daily window: %+v", daily)
+	}
+	if want := mustTime(t, "2026-03-01T02:00:00Z"); !daily.Start.Equal(want) {
+		t.Errorf("expected next daily occurrence at %v, got %v", want, daily.Start)
+	}
+}
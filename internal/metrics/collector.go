@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+)
+
+var (
+	upDesc = prometheus.NewDesc("servprobe_up",
+		"Whether the service's last check found it up (1) or down (0).", serviceLabels, nil)
+	responseTimeDesc = prometheus.NewDesc("servprobe_last_response_time_seconds",
+		"Response time of the service's last check.", serviceLabels, nil)
+	lastCheckDesc = prometheus.NewDesc("servprobe_last_check_timestamp_seconds",
+		"Unix time of the service's last check.", serviceLabels, nil)
+
+	runningDesc = prometheus.NewDesc("servprobe_scheduler_services",
+		"Services being checked periodically.", nil, nil)
+	inFlightDesc = prometheus.NewDesc("servprobe_scheduler_checks_in_flight",
+		"Checks running right now.", nil, nil)
+	missedDesc = prometheus.NewDesc("servprobe_scheduler_missed_checks_total",
+		"Scheduled checks skipped because the previous check of the service outlasted its interval.", nil, nil)
+
+	queuedDesc = prometheus.NewDesc("servprobe_storage_write_queue_length",
+		"Check results waiting to be written.", nil, nil)
+	writeResultsDesc = prometheus.NewDesc("servprobe_storage_write_results_total",
+		"Check results handled by the write pipeline, by result (written, dropped or failed).", []string{"result"}, nil)
+	writeLagDesc = prometheus.NewDesc("servprobe_storage_write_lag_seconds",
+		"How long the oldest result of the last batch written waited in the queue.", nil, nil)
+
+	connectionsDesc = prometheus.NewDesc("servprobe_storage_connections",
+		"Database connections, by state (in_use or idle).", []string{"state"}, nil)
+	maxConnectionsDesc = prometheus.NewDesc("servprobe_storage_connections_max",
+		"Maximum number of open database connections, 0 for unlimited.", nil, nil)
+	waitsDesc = prometheus.NewDesc("servprobe_storage_connection_waits_total",
+		"Times a query waited for a free database connection.", nil, nil)
+	waitSecondsDesc = prometheus.NewDesc("servprobe_storage_connection_wait_seconds_total",
+		"Time spent waiting for a free database connection.", nil, nil)
+)
+
+// collector reads the gauges that describe current state when Prometheus
+// scrapes.
+type collector struct {
+	m *Metrics
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		upDesc, responseTimeDesc, lastCheckDesc,
+		runningDesc, inFlightDesc, missedDesc,
+		queuedDesc, writeResultsDesc, writeLagDesc,
+		connectionsDesc, maxConnectionsDesc, waitsDesc, waitSecondsDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	c.m.mu.RLock()
+	services := make([]string, 0, len(c.m.services))
+	for name := range c.m.services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	var labels [][]string
+	for _, name := range services {
+		labels = append(labels, labelValues(c.m.services[name]))
+	}
+	states, sched, writes, db := c.m.state, c.m.sched, c.m.writes, c.m.db
+	c.m.mu.RUnlock()
+
+	if states != nil {
+		for i, name := range services {
+			st, ok := states.Get(name)
+			if !ok {
+				continue
+			}
+			up := 0.0
+			if st.Status == checker.StatusUp {
+				up = 1
+			}
+			ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up, labels[i]...)
+			ch <- prometheus.MustNewConstMetric(responseTimeDesc, prometheus.GaugeValue, st.LastResult.ResponseTime.Seconds(), labels[i]...)
+			if !st.LastResult.CheckedAt.IsZero() {
+				ch <- prometheus.MustNewConstMetric(lastCheckDesc, prometheus.GaugeValue,
+					float64(st.LastResult.CheckedAt.UnixNano())/1e9, labels[i]...)
+			}
+		}
+	}
+
+	if sched != nil {
+		s := sched.Stats()
+		ch <- prometheus.MustNewConstMetric(runningDesc, prometheus.GaugeValue, float64(s.Running))
+		ch <- prometheus.MustNewConstMetric(inFlightDesc, prometheus.GaugeValue, float64(s.InFlight))
+		ch <- prometheus.MustNewConstMetric(missedDesc, prometheus.CounterValue, float64(s.Missed))
+	}
+
+	if writes != nil {
+		s := writes.Stats()
+		ch <- prometheus.MustNewConstMetric(queuedDesc, prometheus.GaugeValue, float64(s.Queued))
+		ch <- prometheus.MustNewConstMetric(writeResultsDesc, prometheus.CounterValue, float64(s.Written), "written")
+		ch <- prometheus.MustNewConstMetric(writeResultsDesc, prometheus.CounterValue, float64(s.Dropped), "dropped")
+		ch <- prometheus.MustNewConstMetric(writeResultsDesc, prometheus.CounterValue, float64(s.Failed), "failed")
+		ch <- prometheus.MustNewConstMetric(writeLagDesc, prometheus.GaugeValue, s.LagSeconds)
+	}
+
+	if db != nil {
+		s := db.DBStats()
+		ch <- prometheus.MustNewConstMetric(connectionsDesc, prometheus.GaugeValue, float64(s.InUse), "in_use")
+		ch <- prometheus.MustNewConstMetric(connectionsDesc, prometheus.GaugeValue, float64(s.Idle), "idle")
+		ch <- prometheus.MustNewConstMetric(maxConnectionsDesc, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+		ch <- prometheus.MustNewConstMetric(waitsDesc, prometheus.CounterValue, float64(s.WaitCount))
+		ch <- prometheus.MustNewConstMetric(waitSecondsDesc, prometheus.CounterValue, s.WaitDuration.Seconds())
+	}
+}
@@ -0,0 +1,194 @@
+// Package metrics exposes check results, alert deliveries and servprobe's
+// internals to Prometheus.
+package metrics
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/scheduler"
+	"github.com/hazz-dev/servprobe/internal/state"
+	"github.com/hazz-dev/servprobe/internal/writer"
+)
+
+// serviceLabels label every per-service metric. tags holds the service's
+// tags, sorted and comma-separated.
+var serviceLabels = []string{"service", "type", "tags"}
+
+// durationBuckets are the check duration histogram's buckets, in seconds.
+// They reach past the default buckets' 10s for slow checks with long
+// timeouts.
+var durationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// StateSource reports the current state of a service.
+type StateSource interface {
+	Get(name string) (state.ServiceState, bool)
+}
+
+// SchedulerStats reports on the scheduler.
+type SchedulerStats interface {
+	Stats() scheduler.Stats
+}
+
+// WriteStats reports on the write pipeline.
+type WriteStats interface {
+	Stats() writer.Stats
+}
+
+// DBStats reports on the database connection pool.
+type DBStats interface {
+	DBStats() sql.DBStats
+}
+
+// Metrics holds servprobe's metrics. Counters and histograms are updated as
+// checks run and alerts are delivered; gauges are read from the state, the
+// scheduler and storage when Prometheus scrapes.
+type Metrics struct {
+	registry   *prometheus.Registry
+	checks     *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+	durations  *prometheus.HistogramVec
+	deliveries *prometheus.CounterVec
+	logger     *slog.Logger
+
+	mu       sync.RWMutex
+	services map[string]config.Service
+	state    StateSource
+	sched    SchedulerStats
+	writes   WriteStats
+	db       DBStats
+}
+
+// New creates Metrics for services. Pass nil logger to use the default
+// logger.
+func New(services []config.Service, logger *slog.Logger) *Metrics {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "servprobe_checks_total",
+			Help: "Checks run, by service.",
+		}, serviceLabels),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "servprobe_check_failures_total",
+			Help: "Checks that found the service down, by service.",
+		}, serviceLabels),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "servprobe_check_duration_seconds",
+			Help:    "Response time of checks, by service.",
+			Buckets: durationBuckets,
+		}, serviceLabels),
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "servprobe_alert_deliveries_total",
+			Help: "Attempts to deliver an alert, by channel and outcome (sent, retry or failed).",
+		}, []string{"channel", "outcome"}),
+		logger: logger,
+	}
+	m.SetServices(services)
+	m.registry.MustRegister(
+		m.checks, m.failures, m.durations, m.deliveries,
+		collector{m},
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// SetServices replaces the service list. Series of services that were
+// removed, or whose type or tags changed, are dropped.
+func (m *Metrics) SetServices(services []config.Service) {
+	byName := make(map[string]config.Service, len(services))
+	for _, svc := range services {
+		byName[svc.Name] = svc
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, old := range m.services {
+		if svc, ok := byName[name]; ok && slices.Equal(labelValues(svc), labelValues(old)) {
+			continue
+		}
+		match := prometheus.Labels{"service": name}
+		m.checks.DeletePartialMatch(match)
+		m.failures.DeletePartialMatch(match)
+		m.durations.DeletePartialMatch(match)
+	}
+	m.services = byName
+}
+
+// SetState sets where each service's current status is read from.
+func (m *Metrics) SetState(s StateSource) {
+	m.mu.Lock()
+	m.state = s
+	m.mu.Unlock()
+}
+
+// SetSchedulerStats sets where scheduler metrics are read from.
+func (m *Metrics) SetSchedulerStats(s SchedulerStats) {
+	m.mu.Lock()
+	m.sched = s
+	m.mu.Unlock()
+}
+
+// SetWriteStats sets where write pipeline metrics are read from.
+func (m *Metrics) SetWriteStats(w WriteStats) {
+	m.mu.Lock()
+	m.writes = w
+	m.mu.Unlock()
+}
+
+// SetDBStats sets where connection pool metrics are read from.
+func (m *Metrics) SetDBStats(db DBStats) {
+	m.mu.Lock()
+	m.db = db
+	m.mu.Unlock()
+}
+
+// ObserveCheck counts a check result. Results of services not in the list
+// are ignored, so that a check finishing after its service was removed
+// doesn't bring its series back.
+func (m *Metrics) ObserveCheck(r checker.CheckResult) {
+	m.mu.RLock()
+	svc, ok := m.services[r.ServiceName]
+	m.mu.RUnlock()
+	if !ok {
+		return
+	}
+	labels := labelValues(svc)
+	m.checks.WithLabelValues(labels...).Inc()
+	if r.Status == checker.StatusDown {
+		m.failures.WithLabelValues(labels...).Inc()
+	}
+	m.durations.WithLabelValues(labels...).Observe(r.ResponseTime.Seconds())
+}
+
+// ObserveDelivery counts an attempt to deliver an alert to channel. It has
+// the signature of the alerter's OnDelivery callback.
+func (m *Metrics) ObserveDelivery(channel, outcome string) {
+	m.deliveries.WithLabelValues(channel, outcome).Inc()
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorLog: slog.NewLogLogger(m.logger.Handler(), slog.LevelError),
+	})
+}
+
+// labelValues returns svc's values of serviceLabels.
+func labelValues(svc config.Service) []string {
+	tags := slices.Clone(svc.Tags)
+	slices.Sort(tags)
+	return []string{svc.Name, svc.Type, strings.Join(tags, ",")}
+}
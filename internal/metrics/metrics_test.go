@@ -0,0 +1,136 @@
+package metrics_test
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/alert"
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/metrics"
+	"github.com/hazz-dev/servprobe/internal/scheduler"
+	"github.com/hazz-dev/servprobe/internal/state"
+	"github.com/hazz-dev/servprobe/internal/writer"
+)
+
+type mockState map[string]state.ServiceState
+
+func (m mockState) Get(name string) (state.ServiceState, bool) {
+	st, ok := m[name]
+	return st, ok
+}
+
+type mockScheduler scheduler.Stats
+
+func (m mockScheduler) Stats() scheduler.Stats { return scheduler.Stats(m) }
+
+type mockWrites writer.Stats
+
+func (m mockWrites) Stats() writer.Stats { return writer.Stats(m) }
+
+type mockDB sql.DBStats
+
+func (m mockDB) DBStats() sql.DBStats { return sql.DBStats(m) }
+
+func makeServices() []config.Service {
+	return []config.Service{
+		{Name: "api", Type: "http", Tags: []string{"prod", "critical"}},
+		{Name: "db", Type: "tcp"},
+	}
+}
+
+// scrape returns the metrics exposed by m.
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func expectLines(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetrics_Checks(t *testing.T) {
+	m := metrics.New(makeServices(), nil)
+	m.ObserveCheck(checker.CheckResult{ServiceName: "api", Status: checker.StatusUp, ResponseTime: 40 * time.Millisecond})
+	m.ObserveCheck(checker.CheckResult{ServiceName: "api", Status: checker.StatusDown, ResponseTime: 3 * time.Second})
+	m.ObserveCheck(checker.CheckResult{ServiceName: "db", Status: checker.StatusUp, ResponseTime: time.Millisecond})
+	m.ObserveCheck(checker.CheckResult{ServiceName: "gone", Status: checker.StatusUp})
+
+	body := scrape(t, m)
+	expectLines(t, body,
+		`servprobe_checks_total{service="api",tags="critical,prod",type="http"} 2`,
+		`servprobe_check_failures_total{service="api",tags="critical,prod",type="http"} 1`,
+		`servprobe_check_duration_seconds_bucket{service="api",tags="critical,prod",type="http",le="0.05"} 1`,
+		`servprobe_check_duration_seconds_bucket{service="api",tags="critical,prod",type="http",le="5"} 2`,
+		`servprobe_check_duration_seconds_count{service="api",tags="critical,prod",type="http"} 2`,
+		`servprobe_checks_total{service="db",tags="",type="tcp"} 1`,
+	)
+	if strings.Contains(body, `service="gone"`) {
+		t.Error("expected results of unknown services to be ignored")
+	}
+
+	// Removing a service, or changing its labels, drops its series.
+	m.SetServices([]config.Service{{Name: "api", Type: "http", Tags: []string{"critical", "prod"}}})
+	body = scrape(t, m)
+	expectLines(t, body, `servprobe_checks_total{service="api",tags="critical,prod",type="http"} 2`)
+	if strings.Contains(body, `service="db"`) {
+		t.Error("expected the removed service's series to be dropped")
+	}
+	m.SetServices([]config.Service{{Name: "api", Type: "http", Tags: []string{"staging"}}})
+	if body := scrape(t, m); strings.Contains(body, `service="api"`) {
+		t.Error("expected the relabeled service's series to be dropped")
+	}
+}
+
+func TestMetrics_State(t *testing.T) {
+	m := metrics.New(makeServices(), nil)
+	checkedAt := time.Unix(1767225600, 0)
+	m.SetState(mockState{
+		"api": {Status: checker.StatusUp, LastResult: checker.CheckResult{ResponseTime: 250 * time.Millisecond, CheckedAt: checkedAt}},
+		"db":  {Status: checker.StatusDown, LastResult: checker.CheckResult{ResponseTime: 5 * time.Second, CheckedAt: checkedAt}},
+	})
+
+	expectLines(t, scrape(t, m),
+		`servprobe_up{service="api",tags="critical,prod",type="http"} 1`,
+		`servprobe_up{service="db",tags="",type="tcp"} 0`,
+		`servprobe_last_response_time_seconds{service="api",tags="critical,prod",type="http"} 0.25`,
+		`servprobe_last_check_timestamp_seconds{service="db",tags="",type="tcp"} 1.7672256e+09`,
+	)
+}
+
+func TestMetrics_Internals(t *testing.T) {
+	m := metrics.New(nil, nil)
+	m.SetSchedulerStats(mockScheduler{Running: 3, InFlight: 1, Missed: 2})
+	m.SetWriteStats(mockWrites{Queued: 4, Written: 100, Dropped: 5, LagSeconds: 0.5})
+	m.SetDBStats(mockDB{InUse: 1, Idle: 2, MaxOpenConnections: 1, WaitCount: 7})
+	m.ObserveDelivery("ops", alert.DeliveryRetry)
+	m.ObserveDelivery("ops", alert.DeliverySent)
+	m.ObserveDelivery("ops", alert.DeliverySent)
+
+	expectLines(t, scrape(t, m),
+		`servprobe_scheduler_services 3`,
+		`servprobe_scheduler_checks_in_flight 1`,
+		`servprobe_scheduler_missed_checks_total 2`,
+		`servprobe_storage_write_queue_length 4`,
+		`servprobe_storage_write_results_total{result="written"} 100`,
+		`servprobe_storage_write_results_total{result="dropped"} 5`,
+		`servprobe_storage_write_lag_seconds 0.5`,
+		`servprobe_storage_connections{state="idle"} 2`,
+		`servprobe_storage_connection_waits_total 7`,
+		`servprobe_alert_deliveries_total{channel="ops",outcome="sent"} 2`,
+		`servprobe_alert_deliveries_total{channel="ops",outcome="retry"} 1`,
+	)
+}
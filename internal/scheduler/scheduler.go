@@ -2,32 +2,78 @@ package scheduler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hazz-dev/servprobe/internal/checker"
 	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/state"
 	"github.com/hazz-dev/servprobe/internal/storage"
 )
 
 // Store defines the storage operations required by the scheduler.
 type Store interface {
 	InsertCheck(ctx context.Context, r checker.CheckResult) error
-	LatestCheck(ctx context.Context, service string) (*storage.Check, error)
+	CurrentRuns(ctx context.Context) ([]storage.ServiceRun, error)
 }
 
+// Maintenance reports whether a service is inside a maintenance window.
+type Maintenance interface {
+	Active(svc config.Service, t time.Time) bool
+}
+
+// ErrUnknownService is returned by CheckNow for a service that is not scheduled.
+var ErrUnknownService = errors.New("unknown service")
+
 // CheckerFactory creates a Checker for a given service config.
 type CheckerFactory func(config.Service) (checker.Checker, error)
 
 // Scheduler runs health checks for each service in its own goroutine.
 type Scheduler struct {
-	services []config.Service
 	store    Store
 	factory  CheckerFactory
 	onResult func(checker.CheckResult, *checker.Status)
+	maint    Maintenance
+	state    *state.Tracker
 	logger   *slog.Logger
 	wg       sync.WaitGroup
+
+	// updateMu serializes Update, which releases mu while it waits for
+	// stopped runners.
+	updateMu sync.Mutex
+	mu       sync.Mutex
+	services []config.Service
+	ctx      context.Context // set by Start
+	runners  map[string]*runner
+
+	inFlight atomic.Int64
+	missed   atomic.Uint64
+}
+
+// Stats reports on the scheduler.
+type Stats struct {
+	// Running is the number of services being checked periodically.
+	Running int
+	// InFlight is the number of checks running right now.
+	InFlight int
+	// Missed counts scheduled checks skipped since startup because the
+	// previous check of the service took longer than its interval.
+	Missed uint64
+}
+
+// runner is the goroutine checking a single service.
+type runner struct {
+	svc     config.Service
+	checker checker.Checker
+	cancel  context.CancelFunc
+	done    chan struct{}
+	// mu serializes periodic and manual checks of the service.
+	mu sync.Mutex
 }
 
 // New creates a new Scheduler. Pass nil logger to discard logs.
@@ -39,7 +85,9 @@ func New(services []config.Service, store Store, factory CheckerFactory, logger
 		services: services,
 		store:    store,
 		factory:  factory,
+		state:    state.New(),
 		logger:   logger,
+		runners:  make(map[string]*runner),
 	}
 }
 
@@ -49,17 +97,169 @@ func (s *Scheduler) SetOnResult(fn func(checker.CheckResult, *checker.Status)) {
 	s.onResult = fn
 }
 
-// Start spawns one goroutine per service. It is non-blocking.
+// SetMaintenance sets the maintenance schedule used to flag results taken
+// during planned maintenance.
+func (s *Scheduler) SetMaintenance(m Maintenance) {
+	s.maint = m
+}
+
+// LoadState seeds the state tracker from storage, so the first check after a
+// restart is compared against the last stored one. Call it before Start.
+func (s *Scheduler) LoadState(ctx context.Context) error {
+	runs, err := s.store.CurrentRuns(ctx)
+	if err != nil {
+		return fmt.Errorf("loading service state: %w", err)
+	}
+	s.state.Seed(runs)
+	return nil
+}
+
+// State returns the tracker holding each service's current state. Callers
+// must treat it as read-only.
+func (s *Scheduler) State() *state.Tracker {
+	return s.state
+}
+
+// Start spawns one goroutine per service. Paused services are skipped.
+// It is non-blocking.
 func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
 	for _, svc := range s.services {
-		svc := svc // capture loop var
-		c, err := s.factory(svc)
-		if err != nil {
-			s.logger.Error("creating checker", "service", svc.Name, "error", err)
+		s.startLocked(svc)
+	}
+}
+
+// Update replaces the service list. Goroutines are started for new services,
+// stopped for removed or paused ones and restarted for changed ones;
+// unchanged services keep running undisturbed. Before Start, it only records
+// the list. It returns once stopped goroutines have exited, so a service is
+// never checked by two at once.
+func (s *Scheduler) Update(services []config.Service) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	s.mu.Lock()
+	s.services = services
+	if s.ctx == nil {
+		s.mu.Unlock()
+		return
+	}
+
+	want := make(map[string]config.Service, len(services))
+	for _, svc := range services {
+		want[svc.Name] = svc
+	}
+	var stopped []*runner
+	for name, r := range s.runners {
+		svc, ok := want[name]
+		if ok && reflect.DeepEqual(svc, r.svc) {
+			continue
+		}
+		r.cancel()
+		stopped = append(stopped, r)
+		delete(s.runners, name)
+		switch {
+		case !ok:
+			s.state.Forget(name)
+			s.logger.Info("service removed", "service", name)
+		case svc.Paused:
+			s.logger.Info("service paused", "service", name)
+		}
+	}
+	s.mu.Unlock()
+
+	// A stopped runner may be queued behind a manual check, which can take
+	// as long as the check's timeout; holding mu meanwhile would stall
+	// everything else that needs it, including that check's callbacks.
+	for _, r := range stopped {
+		<-r.done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, svc := range services {
+		if _, ok := s.runners[svc.Name]; ok {
 			continue
 		}
-		s.wg.Add(1)
-		go s.runService(ctx, svc, c)
+		if s.startLocked(svc) {
+			s.logger.Info("service scheduled", "service", svc.Name)
+		}
+	}
+}
+
+// Services returns the current service list.
+func (s *Scheduler) Services() []config.Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.services
+}
+
+// startLocked spawns the goroutine for svc. s.mu must be held.
+func (s *Scheduler) startLocked(svc config.Service) bool {
+	if svc.Paused {
+		return false
+	}
+	c, err := s.factory(svc)
+	if err != nil {
+		s.logger.Error("creating checker", "service", svc.Name, "error", err)
+		return false
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	r := &runner{svc: svc, checker: c, cancel: cancel, done: make(chan struct{})}
+	s.runners[svc.Name] = r
+	s.wg.Add(1)
+	go func() {
+		defer close(r.done)
+		s.runService(ctx, r)
+	}()
+	return true
+}
+
+// CheckNow runs the named service's check immediately, outside its regular
+// schedule. The result is stored and passed to the OnResult callback like any
+// other, and never overlaps with a scheduled check of the same service.
+func (s *Scheduler) CheckNow(ctx context.Context, name string) (checker.CheckResult, error) {
+	s.mu.Lock()
+	r, ok := s.runners[name]
+	if !ok {
+		// Not running (paused, or before Start): check with a one-off checker.
+		for _, svc := range s.services {
+			if svc.Name != name {
+				continue
+			}
+			c, err := s.factory(svc)
+			if err != nil {
+				s.mu.Unlock()
+				return checker.CheckResult{}, fmt.Errorf("creating checker: %w", err)
+			}
+			r, ok = &runner{svc: svc, checker: c}, true
+			break
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		return checker.CheckResult{}, ErrUnknownService
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result, ok := s.runCheck(ctx, r.svc, r.checker)
+	if !ok {
+		return result, ctx.Err()
+	}
+	return result, nil
+}
+
+// Stats returns the current state of the scheduler.
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	running := len(s.runners)
+	s.mu.Unlock()
+	return Stats{
+		Running:  running,
+		InFlight: int(s.inFlight.Load()),
+		Missed:   s.missed.Load(),
 	}
 }
 
@@ -68,13 +268,25 @@ func (s *Scheduler) Wait() {
 	s.wg.Wait()
 }
 
-func (s *Scheduler) runService(ctx context.Context, svc config.Service, c checker.Checker) {
+func (s *Scheduler) runService(ctx context.Context, r *runner) {
 	defer s.wg.Done()
 
+	interval := r.svc.Interval.Duration
+	check := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		start := time.Now()
+		s.runCheck(ctx, r.svc, r.checker)
+		// The ticker drops the ticks that passed during a slow check.
+		if took := time.Since(start); took > interval {
+			s.missed.Add(uint64(took / interval))
+		}
+	}
+
 	// Run immediately.
-	s.runCheck(ctx, svc, c)
+	check()
 
-	ticker := time.NewTicker(svc.Interval.Duration)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -82,37 +294,45 @@ func (s *Scheduler) runService(ctx context.Context, svc config.Service, c checke
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			s.runCheck(ctx, svc, c)
+			check()
 		}
 	}
 }
 
-func (s *Scheduler) runCheck(ctx context.Context, svc config.Service, c checker.Checker) {
-	// Fetch previous status before running the check.
-	prev, err := s.store.LatestCheck(ctx, svc.Name)
-	if err != nil {
-		s.logger.Warn("fetching previous check", "service", svc.Name, "error", err)
-	}
-
+// runCheck runs, stores and reports a single check. It returns false if ctx
+// was canceled mid-check, in which case the result is discarded.
+func (s *Scheduler) runCheck(ctx context.Context, svc config.Service, c checker.Checker) (checker.CheckResult, bool) {
+	s.inFlight.Add(1)
 	result := c.Check(ctx)
+	s.inFlight.Add(-1)
+	if ctx.Err() != nil {
+		// Stopped mid-check (shutdown or reload); the result is meaningless.
+		return result, false
+	}
+	if s.maint != nil && s.maint.Active(svc, result.CheckedAt) {
+		result.Maintenance = true
+	}
 
 	s.logger.Info("check result",
 		"service", svc.Name,
 		"status", result.Status,
 		"response_time", result.ResponseTime,
 		"error", result.Error,
+		"maintenance", result.Maintenance,
 	)
 
+	// Record before storing, so a failed insert doesn't skew transitions.
+	prev, hadPrev := s.state.Record(result)
 	if err := s.store.InsertCheck(ctx, result); err != nil {
 		s.logger.Error("storing check result", "service", svc.Name, "error", err)
 	}
 
 	if s.onResult != nil {
 		var prevStatus *checker.Status
-		if prev != nil {
-			st := checker.Status(prev.Status)
-			prevStatus = &st
+		if hadPrev {
+			prevStatus = &prev.Status
 		}
 		s.onResult(result, prevStatus)
 	}
+	return result, true
 }
@@ -2,6 +2,7 @@ package scheduler_test
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -26,7 +27,7 @@ func (m *mockChecker) Check(ctx context.Context) checker.CheckResult {
 type mockStore struct {
 	mu     sync.Mutex
 	checks []checker.CheckResult
-	latest map[string]*storage.Check
+	runs   []storage.ServiceRun
 	err    error
 }
 
@@ -40,13 +41,8 @@ func (m *mockStore) InsertCheck(_ context.Context, r checker.CheckResult) error
 	return nil
 }
 
-func (m *mockStore) LatestCheck(_ context.Context, service string) (*storage.Check, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if m.latest != nil {
-		return m.latest[service], nil
-	}
-	return nil, nil
+func (m *mockStore) CurrentRuns(context.Context) ([]storage.ServiceRun, error) {
+	return m.runs, nil
 }
 
 func makeServices(interval time.Duration) []config.Service {
@@ -235,3 +231,271 @@ func TestScheduler_MultipleServices(t *testing.T) {
 		t.Errorf("expected at least 2 checks (one per service), got %d", n)
 	}
 }
+
+type alwaysMaintenance struct{}
+
+func (alwaysMaintenance) Active(config.Service, time.Time) bool { return true }
+
+func TestScheduler_FlagsMaintenance(t *testing.T) {
+	store := &mockStore{}
+	mc := &mockChecker{
+		result: checker.CheckResult{ServiceName: "api", Status: checker.StatusDown},
+	}
+
+	results := make(chan checker.CheckResult, 1)
+	sched := scheduler.New(makeServices(time.Hour), store, makeFactory(mc), nil)
+	sched.SetMaintenance(alwaysMaintenance{})
+	sched.SetOnResult(func(r checker.CheckResult, prev *checker.Status) {
+		select {
+		case results <- r:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sched.Start(ctx)
+	defer func() {
+		cancel()
+		sched.Wait()
+	}()
+
+	select {
+	case r := <-results:
+		if !r.Maintenance {
+			t.Error("expected result to be flagged as maintenance")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no check result within 2s")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.checks) == 0 || !store.checks[0].Maintenance {
+		t.Error("expected stored result to be flagged as maintenance")
+	}
+}
+
+func TestScheduler_Update(t *testing.T) {
+	store := &mockStore{}
+	var mu sync.Mutex
+	created := make(map[string]int)
+	factory := func(svc config.Service) (checker.Checker, error) {
+		mu.Lock()
+		created[svc.Name]++
+		mu.Unlock()
+		return &mockChecker{result: checker.CheckResult{ServiceName: svc.Name, Status: checker.StatusUp}}, nil
+	}
+	svc := func(name, target string) config.Service {
+		return config.Service{Name: name, Type: "tcp", Target: target, Interval: config.Duration{Duration: time.Hour}}
+	}
+
+	sched := scheduler.New([]config.Service{svc("keep", "a:1"), svc("change", "b:1"), svc("remove", "c:1")}, store, factory, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	sched.Start(ctx)
+
+	sched.Update([]config.Service{svc("keep", "a:1"), svc("change", "b:2"), svc("add", "d:1")})
+
+	cancel()
+	sched.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int{"keep": 1, "change": 2, "remove": 1, "add": 1}
+	for name, n := range want {
+		if created[name] != n {
+			t.Errorf("expected %d checker(s) created for %q, got %d", n, name, created[name])
+		}
+	}
+
+	var names []string
+	for _, s := range sched.Services() {
+		names = append(names, s.Name)
+	}
+	if len(names) != 3 || names[2] != "add" {
+		t.Errorf("expected updated service list, got %v", names)
+	}
+}
+
+func TestScheduler_UpdateBeforeStart(t *testing.T) {
+	store := &mockStore{}
+	mc := &mockChecker{result: checker.CheckResult{ServiceName: "other", Status: checker.StatusUp}}
+	sched := scheduler.New(makeServices(time.Hour), store, makeFactory(mc), nil)
+
+	sched.Update([]config.Service{{Name: "other", Interval: config.Duration{Duration: time.Hour}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sched.Start(ctx)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.Lock()
+		n := len(store.checks)
+		store.mu.Unlock()
+		if n >= 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	sched.Wait()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.checks) != 1 || store.checks[0].ServiceName != "other" {
+		t.Errorf("expected only the updated service to run, got %+v", store.checks)
+	}
+}
+
+func TestScheduler_SkipsPausedServices(t *testing.T) {
+	store := &mockStore{}
+	mc := &mockChecker{result: checker.CheckResult{ServiceName: "api", Status: checker.StatusUp}}
+	services := makeServices(time.Hour)
+	services[0].Paused = true
+	sched := scheduler.New(services, store, makeFactory(mc), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sched.Start(ctx)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	sched.Wait()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.checks) != 0 {
+		t.Errorf("expected no checks for paused service, got %d", len(store.checks))
+	}
+}
+
+func TestScheduler_CheckNow(t *testing.T) {
+	store := &mockStore{}
+	mc := &mockChecker{result: checker.CheckResult{ServiceName: "api", Status: checker.StatusDown, Error: "refused"}}
+	sched := scheduler.New(makeServices(time.Hour), store, makeFactory(mc), nil)
+	var notified atomic.Int32
+	sched.SetOnResult(func(checker.CheckResult, *checker.Status) { notified.Add(1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		sched.Wait()
+	}()
+	sched.Start(ctx)
+	time.Sleep(50 * time.Millisecond) // let the initial check finish
+
+	result, err := sched.CheckNow(context.Background(), "api")
+	if err != nil {
+		t.Fatalf("CheckNow: %v", err)
+	}
+	if result.Status != checker.StatusDown || result.Error != "refused" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	store.mu.Lock()
+	stored := len(store.checks)
+	store.mu.Unlock()
+	if stored != 2 {
+		t.Errorf("expected initial and manual checks to be stored, got %d", stored)
+	}
+	if n := notified.Load(); n != 2 {
+		t.Errorf("expected OnResult for both checks, got %d", n)
+	}
+
+	if _, err := sched.CheckNow(context.Background(), "ghost"); !errors.Is(err, scheduler.ErrUnknownService) {
+		t.Errorf("expected ErrUnknownService, got %v", err)
+	}
+}
+
+func TestScheduler_CheckNowPaused(t *testing.T) {
+	store := &mockStore{}
+	mc := &mockChecker{result: checker.CheckResult{ServiceName: "api", Status: checker.StatusUp}}
+	services := makeServices(time.Hour)
+	services[0].Paused = true
+	sched := scheduler.New(services, store, makeFactory(mc), nil)
+
+	if _, err := sched.CheckNow(context.Background(), "api"); err != nil {
+		t.Fatalf("CheckNow: %v", err)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.checks) != 1 {
+		t.Errorf("expected manual check of paused service to be stored, got %d", len(store.checks))
+	}
+}
+
+func TestScheduler_LoadStateSeedsPreviousStatus(t *testing.T) {
+	store := &mockStore{runs: []storage.ServiceRun{{
+		Latest: storage.Check{Service: "api", Status: "down", CheckedAt: time.Now().Add(-time.Minute)},
+		Since:  time.Now().Add(-time.Hour),
+		Count:  5,
+	}}}
+	mc := &mockChecker{result: checker.CheckResult{ServiceName: "api", Status: checker.StatusUp, CheckedAt: time.Now()}}
+	sched := scheduler.New(makeServices(time.Hour), store, makeFactory(mc), nil)
+	if err := sched.LoadState(context.Background()); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if st, ok := sched.State().Get("api"); !ok || st.Consecutive != 5 {
+		t.Errorf("expected seeded state, got %+v (ok=%v)", st, ok)
+	}
+
+	var prev *checker.Status
+	sched.SetOnResult(func(_ checker.CheckResult, p *checker.Status) { prev = p })
+	if _, err := sched.CheckNow(context.Background(), "api"); err != nil {
+		t.Fatal(err)
+	}
+	if prev == nil || *prev != checker.StatusDown {
+		t.Errorf("expected previous status down from storage, got %v", prev)
+	}
+}
+
+func TestScheduler_StoreErrorKeepsTransitions(t *testing.T) {
+	store := &mockStore{err: errors.New("disk full")}
+	mc := &mockChecker{result: checker.CheckResult{ServiceName: "api", Status: checker.StatusUp}}
+	sched := scheduler.New(makeServices(time.Hour), store, makeFactory(mc), nil)
+
+	var prevs []*checker.Status
+	sched.SetOnResult(func(_ checker.CheckResult, p *checker.Status) { prevs = append(prevs, p) })
+	sched.CheckNow(context.Background(), "api")
+	mc.result.Status = checker.StatusDown
+	sched.CheckNow(context.Background(), "api")
+
+	if len(prevs) != 2 || prevs[0] != nil || prevs[1] == nil || *prevs[1] != checker.StatusUp {
+		t.Errorf("expected up→down transition despite failed inserts, got %v", prevs)
+	}
+	if st, _ := sched.State().Get("api"); st.Status != checker.StatusDown || st.Consecutive != 1 {
+		t.Errorf("unexpected state: %+v", st)
+	}
+}
+
+// slowChecker blocks until release is closed, then reports up.
+type slowChecker struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowChecker) Check(ctx context.Context) checker.CheckResult {
+	s.started <- struct{}{}
+	<-s.release
+	return checker.CheckResult{ServiceName: "api", Status: checker.StatusUp, CheckedAt: time.Now()}
+}
+
+func TestScheduler_Stats(t *testing.T) {
+	sc := &slowChecker{started: make(chan struct{}, 10), release: make(chan struct{})}
+	sched := scheduler.New(makeServices(10*time.Millisecond), &mockStore{}, makeFactory(sc), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sched.Start(ctx)
+	<-sc.started
+
+	if st := sched.Stats(); st.Running != 1 || st.InFlight != 1 {
+		t.Errorf("expected one running service with a check in flight, got %+v", st)
+	}
+
+	// The first check outlasts several intervals, so ticks are missed.
+	time.Sleep(50 * time.Millisecond)
+	close(sc.release)
+	<-sc.started
+	if st := sched.Stats(); st.Missed < 1 {
+		t.Errorf("expected missed checks, got %+v", st)
+	}
+	cancel()
+	sched.Wait()
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// AlertLog lists alert deliveries from the outbox.
+type AlertLog interface {
+	ListAlerts(ctx context.Context, status string, limit int) ([]storage.AlertDelivery, error)
+}
+
+// SetAlertLog enables GET /api/alerts.
+func (s *Server) SetAlertLog(l AlertLog) {
+	s.alerts = l
+}
+
+func (s *Server) handleListAlerts(w http.ResponseWriter, r *http.Request) {
+	const maxLimit = 1000
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", storage.AlertPending, storage.AlertSent, storage.AlertFailed:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid status parameter: must be pending, sent or failed")
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+
+	if s.alerts == nil {
+		writeJSON(w, http.StatusOK, []storage.AlertDelivery{})
+		return
+	}
+	alerts, err := s.alerts.ListAlerts(r.Context(), status, limit)
+	if err != nil {
+		s.logger.Error("ListAlerts", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	writeJSON(w, http.StatusOK, alerts)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/scheduler"
+)
+
+// manualCheckInterval is the minimum time between two manual checks of the
+// same service.
+const manualCheckInterval = 10 * time.Second
+
+// CheckRunner runs a service's check on demand.
+type CheckRunner interface {
+	CheckNow(ctx context.Context, name string) (checker.CheckResult, error)
+}
+
+// checkLimiter allows one manual check per service per interval.
+type checkLimiter struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// allow reports whether a check of service may run now and, if not, how long
+// until it may.
+func (l *checkLimiter) allow(service string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.last == nil {
+		l.last = make(map[string]time.Time)
+	}
+	if wait := l.last[service].Add(manualCheckInterval).Sub(now); wait > 0 {
+		return false, wait
+	}
+	l.last[service] = now
+	return true, 0
+}
+
+type checkResponse struct {
+	Service     string    `json:"service"`
+	Status      string    `json:"status"`
+	ResponseMs  int64     `json:"response_ms"`
+	Error       string    `json:"error"`
+	CheckedAt   time.Time `json:"checked_at"`
+	Maintenance bool      `json:"maintenance"`
+}
+
+func (s *Server) handleCheckService(w http.ResponseWriter, r *http.Request) {
+	if s.checks == nil {
+		writeError(w, http.StatusNotImplemented, "manual checks are not enabled")
+		return
+	}
+	name := chi.URLParam(r, "name")
+	if _, ok := s.serviceIndex()[name]; !ok {
+		writeError(w, http.StatusNotFound, "service not found")
+		return
+	}
+
+	if ok, wait := s.limiter.allow(name, time.Now()); !ok {
+		secs := int(wait.Round(time.Second) / time.Second)
+		if secs < 1 {
+			secs = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		writeError(w, http.StatusTooManyRequests, "service was checked too recently; retry later")
+		return
+	}
+
+	result, err := s.checks.CheckNow(r.Context(), name)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrUnknownService) {
+			writeError(w, http.StatusNotFound, "service not found")
+			return
+		}
+		s.logger.Error("running manual check", "service", name, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	s.logger.Info("manual check", "service", name, "status", result.Status)
+	writeJSON(w, http.StatusOK, checkResponse{
+		Service:     result.ServiceName,
+		Status:      string(result.Status),
+		ResponseMs:  result.ResponseTime.Milliseconds(),
+		Error:       result.Error,
+		CheckedAt:   result.CheckedAt.UTC(),
+		Maintenance: result.Maintenance,
+	})
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hazz-dev/servprobe/internal/export"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// Exporter streams stored checks.
+type Exporter interface {
+	ExportChecks(ctx context.Context, q storage.ExportQuery, fn func(storage.Check) error) error
+}
+
+// SetExporter enables GET /api/export.
+func (s *Server) SetExporter(e Exporter) {
+	s.exporter = e
+}
+
+// handleExport streams the checks of one or every service as CSV or JSON
+// Lines, oldest first.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	format := export.JSONL
+	if v := r.URL.Query().Get("format"); v != "" {
+		f, err := export.ParseFormat(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid format parameter: use csv or jsonl")
+			return
+		}
+		format = f
+	}
+	// Services that were removed can still be exported, so the name isn't
+	// checked against the service list.
+	q := storage.ExportQuery{Service: r.URL.Query().Get("service")}
+	var err error
+	if q.From, err = timeParam(r, "from"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if q.To, err = timeParam(r, "to"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		writeError(w, http.StatusBadRequest, "to must be after from")
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="servprobe-export.%s"`, format))
+	out := &trackingWriter{w: w}
+	ew := export.NewWriter(out, format)
+	if s.exporter != nil {
+		err = s.exporter.ExportChecks(r.Context(), q, ew.Write)
+	}
+	if err == nil {
+		err = ew.Flush()
+	}
+	if err != nil {
+		s.logger.Error("ExportChecks", "service", q.Service, "error", err)
+		// Once streaming has started the status is sent, and a cut-off
+		// body is all that's left to signal the failure.
+		if !out.wrote {
+			w.Header().Del("Content-Disposition")
+			writeError(w, http.StatusInternalServerError, "internal error")
+		}
+	}
+}
+
+// trackingWriter records whether anything was written to w.
+type trackingWriter struct {
+	w     io.Writer
+	wrote bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.wrote = true
+	return t.w.Write(p)
+}
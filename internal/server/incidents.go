@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// IncidentLog lists incidents derived from check results.
+type IncidentLog interface {
+	ListIncidents(ctx context.Context, q storage.IncidentQuery) ([]storage.Incident, error)
+}
+
+// SetIncidentLog enables GET /api/incidents and
+// GET /api/services/{name}/incidents.
+func (s *Server) SetIncidentLog(l IncidentLog) {
+	s.incidents = l
+}
+
+type incidentResponse struct {
+	storage.Incident
+	Ongoing         bool    `json:"ongoing"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+func (s *Server) handleListIncidents(w http.ResponseWriter, r *http.Request) {
+	q, err := incidentQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q.Service = r.URL.Query().Get("service")
+	s.writeIncidents(w, r, q)
+}
+
+func (s *Server) handleServiceIncidents(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if _, ok := s.serviceIndex()[name]; !ok {
+		writeError(w, http.StatusNotFound, "service not found")
+		return
+	}
+	q, err := incidentQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q.Service = name
+	s.writeIncidents(w, r, q)
+}
+
+func (s *Server) writeIncidents(w http.ResponseWriter, r *http.Request, q storage.IncidentQuery) {
+	out := []incidentResponse{}
+	if s.incidents == nil {
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+	incidents, err := s.incidents.ListIncidents(r.Context(), q)
+	if err != nil {
+		s.logger.Error("ListIncidents", "service", q.Service, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	now := time.Now()
+	for _, i := range incidents {
+		out = append(out, incidentResponse{
+			Incident:        i,
+			Ongoing:         i.ResolvedAt == nil,
+			DurationSeconds: i.Duration(now).Seconds(),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// incidentQuery parses the from, to and limit parameters.
+func incidentQuery(r *http.Request) (storage.IncidentQuery, error) {
+	const maxLimit = 1000
+
+	q := storage.IncidentQuery{Limit: 100}
+	var err error
+	if q.From, err = timeParam(r, "from"); err != nil {
+		return q, err
+	}
+	if q.To, err = timeParam(r, "to"); err != nil {
+		return q, err
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		return q, fmt.Errorf("to must be after from")
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("invalid limit parameter")
+		}
+		q.Limit = min(n, maxLimit)
+	}
+	return q, nil
+}
+
+// timeParam parses the named query parameter as an RFC 3339 time or a
+// YYYY-MM-DD date (midnight UTC). A missing parameter is the zero time.
+func timeParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s parameter: use RFC 3339 or YYYY-MM-DD", name)
+}
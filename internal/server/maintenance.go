@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/maintenance"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// MaintenanceManager lists and manages maintenance windows.
+type MaintenanceManager interface {
+	Active(svc config.Service, t time.Time) bool
+	List(now time.Time) []maintenance.Window
+	Create(ctx context.Context, w storage.MaintenanceWindow) (storage.MaintenanceWindow, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+func (s *Server) handleListMaintenance(w http.ResponseWriter, r *http.Request) {
+	windows := []maintenance.Window{}
+	if s.maint != nil {
+		windows = s.maint.List(time.Now())
+	}
+	writeJSON(w, http.StatusOK, windows)
+}
+
+type createMaintenanceRequest struct {
+	Name     string     `json:"name"`
+	Services []string   `json:"services"`
+	Tags     []string   `json:"tags"`
+	Start    *time.Time `json:"start"`
+	End      *time.Time `json:"end"`
+	Duration string     `json:"duration"`
+}
+
+func (s *Server) handleCreateMaintenance(w http.ResponseWriter, r *http.Request) {
+	if s.maint == nil {
+		writeError(w, http.StatusNotImplemented, "maintenance windows are not enabled")
+		return
+	}
+
+	var req createMaintenanceRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	idx := s.serviceIndex()
+	for _, name := range req.Services {
+		if _, ok := idx[name]; !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown service %q", name))
+			return
+		}
+	}
+
+	mw := storage.MaintenanceWindow{
+		Name:     req.Name,
+		Services: req.Services,
+		Tags:     req.Tags,
+		StartsAt: time.Now(),
+	}
+	if req.Start != nil {
+		mw.StartsAt = *req.Start
+	}
+	switch {
+	case req.End != nil && req.Duration != "":
+		writeError(w, http.StatusBadRequest, "only one of end or duration may be set")
+		return
+	case req.End != nil:
+		mw.EndsAt = *req.End
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid duration")
+			return
+		}
+		mw.EndsAt = mw.StartsAt.Add(d)
+	default:
+		writeError(w, http.StatusBadRequest, "end or duration is required")
+		return
+	}
+	if !mw.EndsAt.After(mw.StartsAt) {
+		writeError(w, http.StatusBadRequest, "end must be after start")
+		return
+	}
+
+	created, err := s.maint.Create(r.Context(), mw)
+	if err != nil {
+		s.logger.Error("creating maintenance window", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	s.logger.Info("maintenance window created", "id", created.ID, "name", created.Name,
+		"start", created.StartsAt, "end", created.EndsAt)
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) handleDeleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	if s.maint == nil {
+		writeError(w, http.StatusNotImplemented, "maintenance windows are not enabled")
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := s.maint.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "maintenance window not found")
+			return
+		}
+		s.logger.Error("deleting maintenance window", "id", id, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
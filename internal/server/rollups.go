@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// RollupSource serves aggregated check history.
+type RollupSource interface {
+	Rollups(ctx context.Context, service string, res storage.Resolution, from, to time.Time) ([]storage.Rollup, error)
+}
+
+// SetRollups enables GET /api/services/{name}/rollups.
+func (s *Server) SetRollups(r RollupSource) {
+	s.rollups = r
+}
+
+func (s *Server) handleServiceRollups(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if _, ok := s.serviceIndex()[name]; !ok {
+		writeError(w, http.StatusNotFound, "service not found")
+		return
+	}
+
+	res := storage.Resolution(r.URL.Query().Get("resolution"))
+	lookback := 24 * time.Hour
+	switch res {
+	case "", storage.Hourly:
+		res = storage.Hourly
+	case storage.Daily:
+		lookback = 30 * 24 * time.Hour
+	default:
+		writeError(w, http.StatusBadRequest, "invalid resolution parameter: use hour or day")
+		return
+	}
+	from, to, ok := rangeParams(w, r, lookback)
+	if !ok {
+		return
+	}
+
+	out := []storage.Rollup{}
+	if s.rollups != nil {
+		rollups, err := s.rollups.Rollups(r.Context(), name, res, from, to)
+		if err != nil {
+			s.logger.Error("Rollups", "service", name, "error", err)
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		out = append(out, rollups...)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// rangeParams parses the from and to parameters. to defaults to now and from
+// to lookback before to. On a bad parameter it writes the error response and
+// returns false.
+func rangeParams(w http.ResponseWriter, r *http.Request, lookback time.Duration) (from, to time.Time, ok bool) {
+	var err error
+	if to, err = timeParam(r, "to"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return from, to, false
+	}
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from, err = timeParam(r, "from"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return from, to, false
+	}
+	if from.IsZero() {
+		from = to.Add(-lookback)
+	}
+	if !to.After(from) {
+		writeError(w, http.StatusBadRequest, "to must be after from")
+		return from, to, false
+	}
+	return from, to, true
+}
@@ -2,33 +2,58 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/hazz-dev/servprobe/internal/catalog"
+	"github.com/hazz-dev/servprobe/internal/checker"
 	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/state"
 	"github.com/hazz-dev/servprobe/internal/storage"
+	"github.com/hazz-dev/servprobe/internal/writer"
 )
 
 // ServerStore defines the storage queries the server needs.
 type ServerStore interface {
-	AllLatest(ctx context.Context) ([]storage.Check, error)
-	LatestCheck(ctx context.Context, service string) (*storage.Check, error)
-	ServiceHistory(ctx context.Context, service string, limit, offset int) ([]storage.Check, int, error)
-	UptimePercent(ctx context.Context, service string, last int) (float64, error)
+	ServiceHistory(ctx context.Context, q storage.HistoryQuery) (storage.HistoryPage, error)
+	UptimeBetween(ctx context.Context, service string, from, to time.Time) (storage.Uptime, error)
+	UptimesBetween(ctx context.Context, from, to time.Time) (map[string]storage.Uptime, error)
+}
+
+// StateSource reports the current state of a service.
+type StateSource interface {
+	Get(name string) (state.ServiceState, bool)
 }
 
 // Server holds the chi router and its dependencies.
 type Server struct {
-	store    ServerStore
-	services []config.Service
-	router   chi.Router
-	logger   *slog.Logger
+	store     ServerStore
+	state     StateSource
+	services  atomic.Pointer[[]config.Service]
+	maint     MaintenanceManager
+	manager   ServiceManager
+	checks    CheckRunner
+	alerts    AlertLog
+	incidents IncidentLog
+	rollups   RollupSource
+	stats     StatsSource
+	exporter  Exporter
+	writes    WriteStats
+	limiter   checkLimiter
+	apiToken  string
+	router    chi.Router
+	logger    *slog.Logger
 }
 
 // New creates a new Server and registers all routes.
@@ -37,15 +62,47 @@ func New(store ServerStore, services []config.Service, logger *slog.Logger) *Ser
 		logger = slog.Default()
 	}
 	s := &Server{
-		store:    store,
-		services: services,
-		router:   chi.NewRouter(),
-		logger:   logger,
+		store:  store,
+		router: chi.NewRouter(),
+		logger: logger,
 	}
+	s.SetServices(services)
 	s.registerRoutes()
 	return s
 }
 
+// SetServices atomically replaces the service list served by the API.
+func (s *Server) SetServices(services []config.Service) {
+	s.services.Store(&services)
+}
+
+// SetState sets where current service status is read from, typically the
+// scheduler's state tracker. Without it every service reports "unknown".
+func (s *Server) SetState(st StateSource) {
+	s.state = st
+}
+
+// SetServiceManager enables the write endpoints under /api/services.
+func (s *Server) SetServiceManager(m ServiceManager) {
+	s.manager = m
+}
+
+// SetCheckRunner enables POST /api/services/{name}/check.
+func (s *Server) SetCheckRunner(c CheckRunner) {
+	s.checks = c
+}
+
+// SetMaintenance sets the maintenance schedule exposed under /api/maintenance.
+func (s *Server) SetMaintenance(m MaintenanceManager) {
+	s.maint = m
+}
+
+// SetAPIToken sets the bearer token required by write endpoints. With no
+// token set, write endpoints are disabled.
+func (s *Server) SetAPIToken(token string) {
+	s.apiToken = token
+}
+
 // Router returns the chi router (for mounting or testing).
 func (s *Server) Router() chi.Router {
 	return s.router
@@ -60,6 +117,28 @@ func (s *Server) registerRoutes() {
 	r.Get("/api/services", s.handleListServices)
 	r.Get("/api/services/{name}", s.handleGetService)
 	r.Get("/api/services/{name}/history", s.handleGetServiceHistory)
+	r.Get("/api/services/{name}/incidents", s.handleServiceIncidents)
+	r.Get("/api/services/{name}/rollups", s.handleServiceRollups)
+	r.Get("/api/services/{name}/stats", s.handleServiceStats)
+	r.Get("/api/services/{name}/uptime", s.handleServiceUptime)
+	r.Get("/api/maintenance", s.handleListMaintenance)
+	r.Get("/api/alerts", s.handleListAlerts)
+	r.Get("/api/incidents", s.handleListIncidents)
+	r.Get("/api/export", s.handleExport)
+	// Manual checks are rate limited rather than authenticated, so the
+	// dashboard can trigger them.
+	r.Post("/api/services/{name}/check", s.handleCheckService)
+
+	r.Group(func(r chi.Router) {
+		r.Use(s.requireToken)
+		r.Post("/api/services", s.handleCreateService)
+		r.Put("/api/services/{name}", s.handleUpdateService)
+		r.Delete("/api/services/{name}", s.handleDeleteService)
+		r.Post("/api/services/{name}/pause", s.handlePauseService)
+		r.Post("/api/services/{name}/resume", s.handleResumeService)
+		r.Post("/api/maintenance", s.handleCreateMaintenance)
+		r.Delete("/api/maintenance/{id}", s.handleDeleteMaintenance)
+	})
 }
 
 // --- Response helpers ---
@@ -81,12 +160,30 @@ func writeError(w http.ResponseWriter, status int, msg string) {
 	json.NewEncoder(w).Encode(envelope{Error: msg})
 }
 
+// maxBodyBytes caps the size of JSON request bodies.
+const maxBodyBytes = 1 << 20
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
 // --- Service helpers ---
 
+// serviceList returns the current service list.
+func (s *Server) serviceList() []config.Service {
+	return *s.services.Load()
+}
+
 // serviceIndex returns a map from service name → config.Service.
 func (s *Server) serviceIndex() map[string]config.Service {
-	idx := make(map[string]config.Service, len(s.services))
-	for _, svc := range s.services {
+	services := s.serviceList()
+	idx := make(map[string]config.Service, len(services))
+	for _, svc := range services {
 		idx[svc.Name] = svc
 	}
 	return idx
@@ -94,53 +191,120 @@ func (s *Server) serviceIndex() map[string]config.Service {
 
 // --- Handlers ---
 
+// WriteStats reports on the pipeline that stores check results.
+type WriteStats interface {
+	Stats() writer.Stats
+}
+
+// SetWriteStats adds the write pipeline's state to GET /api/health.
+func (s *Server) SetWriteStats(ws WriteStats) {
+	s.writes = ws
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{"status": "ok"}
+	if s.writes != nil {
+		resp["writes"] = s.writes.Stats()
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	json.NewEncoder(w).Encode(resp)
 }
 
+// Statuses reported instead of the last check's status: statusPaused while
+// a service is paused and statusFlapping while it is flapping.
+const (
+	statusPaused   = "paused"
+	statusFlapping = "flapping"
+)
+
 type serviceDetail struct {
 	Name        string     `json:"name"`
 	Type        string     `json:"type"`
 	Target      string     `json:"target"`
 	Interval    string     `json:"interval"`
+	Tags        []string   `json:"tags"`
+	Source      string     `json:"source"`
+	Paused      bool       `json:"paused"`
+	PausedSince *time.Time `json:"paused_since,omitempty"`
 	Status      string     `json:"status"`
-	ResponseMs  int64      `json:"response_ms"`
-	UptimePct   float64    `json:"uptime_percent"`
-	LastChecked *time.Time `json:"last_checked"`
+	StatusSince *time.Time `json:"status_since"`
+	Consecutive int        `json:"consecutive_checks"`
+	// StateChange is the percent state change used for flap detection.
+	StateChange   float64    `json:"state_change_percent"`
+	FlappingSince *time.Time `json:"flapping_since,omitempty"`
+	ResponseMs    int64      `json:"response_ms"`
+	UptimePct     float64    `json:"uptime_percent"`
+	// Uptime is keyed by window name; windows without data are null.
+	Uptime      map[string]*float64 `json:"uptime"`
+	SLA         *slaStatus          `json:"sla,omitempty"`
+	LastChecked *time.Time          `json:"last_checked"`
+	Maintenance bool                `json:"maintenance"`
 }
 
-func (s *Server) handleListServices(w http.ResponseWriter, r *http.Request) {
-	latestChecks, err := s.store.AllLatest(r.Context())
-	if err != nil {
-		s.logger.Error("AllLatest", "error", err)
-		writeError(w, http.StatusInternalServerError, "internal error")
-		return
+func (s *Server) newServiceDetail(svc config.Service) serviceDetail {
+	tags := svc.Tags
+	if tags == nil {
+		tags = []string{}
 	}
-
-	byService := make(map[string]storage.Check, len(latestChecks))
-	for _, c := range latestChecks {
-		byService[c.Service] = c
+	source := catalog.SourceConfig
+	var pausedSince *time.Time
+	if s.manager != nil {
+		source = s.manager.Source(svc.Name)
+		if t, ok := s.manager.PausedSince(svc.Name); ok && svc.Paused {
+			pausedSince = &t
+		}
 	}
+	return serviceDetail{
+		Name:        svc.Name,
+		Type:        svc.Type,
+		Target:      svc.Target,
+		Interval:    svc.Interval.Duration.String(),
+		Tags:        tags,
+		Source:      source,
+		Paused:      svc.Paused,
+		PausedSince: pausedSince,
+		Status:      "unknown",
+		Maintenance: s.maint != nil && s.maint.Active(svc, time.Now()),
+	}
+}
 
-	details := make([]serviceDetail, 0, len(s.services))
-	for _, svc := range s.services {
-		d := serviceDetail{
-			Name:     svc.Name,
-			Type:     svc.Type,
-			Target:   svc.Target,
-			Interval: svc.Interval.Duration.String(),
-			Status:   "unknown",
-		}
-		if c, ok := byService[svc.Name]; ok {
-			d.Status = c.Status
-			d.ResponseMs = c.ResponseMs
-			t := c.CheckedAt
-			d.LastChecked = &t
-			pct, _ := s.store.UptimePercent(r.Context(), svc.Name, 100)
-			d.UptimePct = pct
+// withState fills in d's current status from the state tracker and returns
+// whether the service has been checked.
+func (s *Server) withState(d *serviceDetail) bool {
+	checked := false
+	if s.state != nil {
+		if st, ok := s.state.Get(d.Name); ok {
+			checked = true
+			d.Status = string(st.Status)
+			since := st.Since
+			d.StatusSince = &since
+			d.Consecutive = st.Consecutive
+			d.StateChange = st.StateChange
+			if st.Flapping {
+				d.Status = statusFlapping
+				flapping := st.FlappingSince
+				d.FlappingSince = &flapping
+			}
+			d.ResponseMs = st.LastResult.ResponseTime.Milliseconds()
+			last := st.LastResult.CheckedAt
+			d.LastChecked = &last
 		}
+	}
+	if d.Paused {
+		d.Status = statusPaused
+	}
+	return checked
+}
+
+func (s *Server) handleListServices(w http.ResponseWriter, r *http.Request) {
+	services := s.serviceList()
+	details := make([]serviceDetail, 0, len(services))
+	uptimes := s.windowUptimes(r.Context(), "", time.Now())
+	for _, svc := range services {
+		d := s.newServiceDetail(svc)
+		s.withState(&d)
+		s.withUptime(&d, svc, uptimes)
 		details = append(details, d)
 	}
 
@@ -162,46 +326,27 @@ func (s *Server) handleGetService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	latest, err := s.store.LatestCheck(r.Context(), name)
-	if err != nil {
-		s.logger.Error("LatestCheck", "service", name, "error", err)
-		writeError(w, http.StatusInternalServerError, "internal error")
-		return
-	}
-
-	history, _, err := s.store.ServiceHistory(r.Context(), name, 10, 0)
+	history, err := s.store.ServiceHistory(r.Context(), storage.HistoryQuery{Service: name, Limit: 10})
 	if err != nil {
 		s.logger.Error("ServiceHistory", "service", name, "error", err)
 		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 
-	pct, _ := s.store.UptimePercent(r.Context(), name, 100)
-
-	d := serviceDetail{
-		Name:      svc.Name,
-		Type:      svc.Type,
-		Target:    svc.Target,
-		Interval:  svc.Interval.Duration.String(),
-		Status:    "unknown",
-		UptimePct: pct,
-	}
-	if latest != nil {
-		d.Status = latest.Status
-		d.ResponseMs = latest.ResponseMs
-		t := latest.CheckedAt
-		d.LastChecked = &t
-	}
+	d := s.newServiceDetail(svc)
+	s.withState(&d)
+	s.withUptime(&d, svc, s.windowUptimes(r.Context(), name, time.Now()))
 
 	writeJSON(w, http.StatusOK, serviceDetailResponse{
 		serviceDetail: d,
-		RecentChecks:  history,
+		RecentChecks:  history.Checks,
 	})
 }
 
 type historyResponse struct {
-	Checks []storage.Check `json:"checks"`
-	Total  int             `json:"total"`
+	Checks     []storage.Check `json:"checks"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"next_cursor,omitempty"`
 }
 
 func (s *Server) handleGetServiceHistory(w http.ResponseWriter, r *http.Request) {
@@ -213,46 +358,88 @@ func (s *Server) handleGetServiceHistory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	const maxLimit = 1000
+	q, err := historyQuery(r, name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := s.store.ServiceHistory(r.Context(), q)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, "invalid cursor parameter")
+		return
+	}
+	if err != nil {
+		s.logger.Error("ServiceHistory", "service", name, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
 
-	limit := 50
-	offset := 0
+	writeJSON(w, http.StatusOK, historyResponse{
+		Checks:     page.Checks,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// historyQuery parses the limit, offset, from, to, status and cursor
+// parameters.
+func historyQuery(r *http.Request, service string) (storage.HistoryQuery, error) {
+	const maxLimit = 1000
 
+	q := storage.HistoryQuery{Service: service, Limit: 50}
 	if v := r.URL.Query().Get("limit"); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil || n < 0 {
-			writeError(w, http.StatusBadRequest, "invalid limit parameter")
-			return
-		}
-		if n > maxLimit {
-			n = maxLimit
+			return q, fmt.Errorf("invalid limit parameter")
 		}
-		limit = n
+		q.Limit = min(n, maxLimit)
 	}
 	if v := r.URL.Query().Get("offset"); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil || n < 0 {
-			writeError(w, http.StatusBadRequest, "invalid offset parameter")
-			return
+			return q, fmt.Errorf("invalid offset parameter")
 		}
-		offset = n
+		q.Offset = n
 	}
-
-	checks, total, err := s.store.ServiceHistory(r.Context(), name, limit, offset)
-	if err != nil {
-		s.logger.Error("ServiceHistory", "service", name, "error", err)
-		writeError(w, http.StatusInternalServerError, "internal error")
-		return
+	var err error
+	if q.From, err = timeParam(r, "from"); err != nil {
+		return q, err
 	}
-
-	writeJSON(w, http.StatusOK, historyResponse{
-		Checks: checks,
-		Total:  total,
-	})
+	if q.To, err = timeParam(r, "to"); err != nil {
+		return q, err
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		return q, fmt.Errorf("to must be after from")
+	}
+	switch q.Status = r.URL.Query().Get("status"); checker.Status(q.Status) {
+	case "", checker.StatusUp, checker.StatusDown:
+	default:
+		return q, fmt.Errorf("invalid status parameter: use up or down")
+	}
+	q.Cursor = r.URL.Query().Get("cursor")
+	return q, nil
 }
 
 // --- Middleware ---
 
+// requireToken rejects requests without the configured bearer token.
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.apiToken == "" {
+			writeError(w, http.StatusForbidden, "write API disabled: set server.api_token to enable it")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.apiToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 type statusWriter struct {
 	http.ResponseWriter
 	status int
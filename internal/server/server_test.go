@@ -2,58 +2,97 @@ package server_test
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/hazz-dev/servprobe/internal/catalog"
+	"github.com/hazz-dev/servprobe/internal/checker"
 	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/maintenance"
 	"github.com/hazz-dev/servprobe/internal/server"
+	"github.com/hazz-dev/servprobe/internal/state"
 	"github.com/hazz-dev/servprobe/internal/storage"
+	"github.com/hazz-dev/servprobe/internal/writer"
 )
 
 // mockStore implements server.ServerStore for testing.
 type mockStore struct {
-	checks    []storage.Check
-	latest    map[string]*storage.Check
 	history   map[string][]storage.Check
 	totalHist map[string]int
 	uptime    map[string]float64
 	err       error
+	// lastQuery is the last history query, nextCursor its page's cursor.
+	lastQuery  storage.HistoryQuery
+	nextCursor string
+	// uptimesCalls counts UptimesBetween calls.
+	uptimesCalls int
 }
 
-func (m *mockStore) AllLatest(_ context.Context) ([]storage.Check, error) {
+func (m *mockStore) ServiceHistory(_ context.Context, q storage.HistoryQuery) (storage.HistoryPage, error) {
+	m.lastQuery = q
 	if m.err != nil {
-		return nil, m.err
+		return storage.HistoryPage{}, m.err
 	}
-	return m.checks, nil
+	return storage.HistoryPage{
+		Checks:     m.history[q.Service],
+		Total:      m.totalHist[q.Service],
+		NextCursor: m.nextCursor,
+	}, nil
 }
 
-func (m *mockStore) LatestCheck(_ context.Context, service string) (*storage.Check, error) {
+// UptimeBetween reports m.uptime[service] percent of every range as up.
+func (m *mockStore) UptimeBetween(_ context.Context, service string, from, to time.Time) (storage.Uptime, error) {
 	if m.err != nil {
-		return nil, m.err
+		return storage.Uptime{}, m.err
 	}
-	if m.latest != nil {
-		return m.latest[service], nil
+	pct, ok := m.uptime[service]
+	if !ok {
+		return storage.Uptime{}, nil
 	}
-	return nil, nil
+	known := to.Sub(from)
+	return storage.Uptime{Known: known, Up: time.Duration(float64(known) * pct / 100)}, nil
 }
 
-func (m *mockStore) ServiceHistory(_ context.Context, service string, limit, offset int) ([]storage.Check, int, error) {
-	if m.err != nil {
-		return nil, 0, m.err
+// UptimesBetween reports UptimeBetween of every service in m.uptime.
+func (m *mockStore) UptimesBetween(ctx context.Context, from, to time.Time) (map[string]storage.Uptime, error) {
+	m.uptimesCalls++
+	out := make(map[string]storage.Uptime, len(m.uptime))
+	for name := range m.uptime {
+		u, err := m.UptimeBetween(ctx, name, from, to)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = u
 	}
-	checks := m.history[service]
-	total := m.totalHist[service]
-	return checks, total, nil
+	return out, nil
 }
 
-func (m *mockStore) UptimePercent(_ context.Context, service string, last int) (float64, error) {
-	if m.err != nil {
-		return 0, m.err
-	}
-	return m.uptime[service], nil
+// mockState implements server.StateSource for testing.
+type mockState map[string]state.ServiceState
+
+func (m mockState) Get(name string) (state.ServiceState, bool) {
+	st, ok := m[name]
+	return st, ok
+}
+
+func makeState(service string, status checker.Status) mockState {
+	now := time.Now().UTC()
+	return mockState{service: {
+		Status:      status,
+		Since:       now.Add(-time.Hour),
+		Consecutive: 3,
+		LastResult: checker.CheckResult{
+			ServiceName:  service,
+			Status:       status,
+			ResponseTime: 42 * time.Millisecond,
+			CheckedAt:    now,
+		},
+	}}
 }
 
 func makeServices() []config.Service {
@@ -108,8 +147,27 @@ func TestHealth(t *testing.T) {
 	}
 }
 
+type mockWriteStats struct{ stats writer.Stats }
+
+func (m mockWriteStats) Stats() writer.Stats { return m.stats }
+
+func TestHealth_WriteStats(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetWriteStats(mockWriteStats{writer.Stats{Queued: 3, Written: 120, Dropped: 2, LagSeconds: 0.5}})
+	w := doRequest(t, s.Router(), "GET", "/api/health")
+
+	var resp struct {
+		Status string       `json:"status"`
+		Writes writer.Stats `json:"writes"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Status != "ok" || resp.Writes.Queued != 3 || resp.Writes.Written != 120 || resp.Writes.Dropped != 2 || resp.Writes.LagSeconds != 0.5 {
+		t.Errorf("unexpected health response: %+v", resp)
+	}
+}
+
 func TestListServices_Empty(t *testing.T) {
-	store := &mockStore{checks: []storage.Check{}}
+	store := &mockStore{}
 	s := server.New(store, makeServices(), nil)
 	w := doRequest(t, s.Router(), "GET", "/api/services")
 
@@ -129,11 +187,10 @@ func TestListServices_Empty(t *testing.T) {
 
 func TestListServices_WithStatus(t *testing.T) {
 	store := &mockStore{
-		checks: []storage.Check{makeCheck("api", "up")},
-		latest: map[string]*storage.Check{"api": func() *storage.Check { c := makeCheck("api", "up"); return &c }()},
 		uptime: map[string]float64{"api": 100.0},
 	}
 	s := server.New(store, makeServices(), nil)
+	s.SetState(makeState("api", checker.StatusUp))
 	w := doRequest(t, s.Router(), "GET", "/api/services")
 
 	if w.Code != http.StatusOK {
@@ -151,17 +208,24 @@ func TestListServices_WithStatus(t *testing.T) {
 	if resp.Data[0]["name"] != "api" {
 		t.Errorf("expected name 'api', got %v", resp.Data[0]["name"])
 	}
+	got := resp.Data[0]
+	if got["status"] != "up" || got["response_ms"] != float64(42) || got["consecutive_checks"] != float64(3) || got["status_since"] == nil {
+		t.Errorf("expected status from state tracker, got %v", got)
+	}
+	if got["uptime_percent"] != float64(100) {
+		t.Errorf("expected uptime 100, got %v", got["uptime_percent"])
+	}
 }
 
 func TestGetService_Found(t *testing.T) {
 	c := makeCheck("api", "up")
 	store := &mockStore{
-		latest:    map[string]*storage.Check{"api": &c},
 		history:   map[string][]storage.Check{"api": {c}},
 		totalHist: map[string]int{"api": 1},
 		uptime:    map[string]float64{"api": 99.5},
 	}
 	s := server.New(store, makeServices(), nil)
+	s.SetState(makeState("api", checker.StatusUp))
 	w := doRequest(t, s.Router(), "GET", "/api/services/api")
 
 	if w.Code != http.StatusOK {
@@ -176,6 +240,9 @@ func TestGetService_Found(t *testing.T) {
 	if resp.Data["name"] != "api" {
 		t.Errorf("expected name 'api', got %v", resp.Data["name"])
 	}
+	if resp.Data["status"] != "up" {
+		t.Errorf("expected status 'up', got %v", resp.Data["status"])
+	}
 }
 
 func TestGetService_NotFound(t *testing.T) {
@@ -244,3 +311,880 @@ func TestGetServiceHistory_InvalidOffset(t *testing.T) {
 		t.Errorf("expected 400 for bad offset, got %d", w.Code)
 	}
 }
+
+func TestGetServiceHistory_FiltersAndCursor(t *testing.T) {
+	store := &mockStore{
+		history:    map[string][]storage.Check{"api": {makeCheck("api", "down")}},
+		totalHist:  map[string]int{"api": 7},
+		nextCursor: "next",
+	}
+	s := server.New(store, makeServices(), nil)
+	w := doRequest(t, s.Router(), "GET", "/api/services/api/history?from=2026-01-01&to=2026-01-02T12:00:00Z&status=down&cursor=abc&limit=1")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Total      int    `json:"total"`
+			NextCursor string `json:"next_cursor"`
+		} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Data.Total != 7 || resp.Data.NextCursor != "next" {
+		t.Errorf("unexpected page: %+v", resp.Data)
+	}
+
+	want := storage.HistoryQuery{
+		Service: "api",
+		From:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC),
+		Status:  "down",
+		Limit:   1,
+		Cursor:  "abc",
+	}
+	if q := store.lastQuery; !q.From.Equal(want.From) || !q.To.Equal(want.To) || q.Status != want.Status || q.Limit != want.Limit || q.Cursor != want.Cursor {
+		t.Errorf("expected query %+v, got %+v", want, q)
+	}
+}
+
+func TestGetServiceHistory_InvalidFilters(t *testing.T) {
+	for _, query := range []string{"status=degraded", "from=yesterday", "from=2026-01-02&to=2026-01-01"} {
+		s := server.New(&mockStore{}, makeServices(), nil)
+		w := doRequest(t, s.Router(), "GET", "/api/services/api/history?"+query)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+
+	s := server.New(&mockStore{err: storage.ErrInvalidCursor}, makeServices(), nil)
+	w := doRequest(t, s.Router(), "GET", "/api/services/api/history?cursor=bogus")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad cursor, got %d", w.Code)
+	}
+}
+
+// mockMaintenance implements server.MaintenanceManager for testing.
+type mockMaintenance struct {
+	active  map[string]bool
+	created []storage.MaintenanceWindow
+}
+
+func (m *mockMaintenance) Active(svc config.Service, _ time.Time) bool {
+	return m.active[svc.Name]
+}
+
+func (m *mockMaintenance) List(time.Time) []maintenance.Window {
+	var out []maintenance.Window
+	for i, w := range m.created {
+		out = append(out, maintenance.Window{ID: int64(i + 1), Name: w.Name, Start: w.StartsAt, End: w.EndsAt, Source: "api"})
+	}
+	return out
+}
+
+func (m *mockMaintenance) Create(_ context.Context, w storage.MaintenanceWindow) (storage.MaintenanceWindow, error) {
+	m.created = append(m.created, w)
+	w.ID = int64(len(m.created))
+	return w, nil
+}
+
+func (m *mockMaintenance) Delete(_ context.Context, id int64) error {
+	if id < 1 || int(id) > len(m.created) {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func doAuthRequest(t *testing.T, router http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateMaintenance_RequiresToken(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetMaintenance(&mockMaintenance{})
+	body := `{"name":"deploy","duration":"1h"}`
+
+	// No token configured: write API disabled.
+	w := doAuthRequest(t, s.Router(), "POST", "/api/maintenance", "", body)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 with no token configured, got %d", w.Code)
+	}
+
+	s.SetAPIToken("secret")
+	w = doAuthRequest(t, s.Router(), "POST", "/api/maintenance", "wrong", body)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", w.Code)
+	}
+}
+
+func TestCreateMaintenance(t *testing.T) {
+	maint := &mockMaintenance{}
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetMaintenance(maint)
+	s.SetAPIToken("secret")
+
+	w := doAuthRequest(t, s.Router(), "POST", "/api/maintenance", "secret",
+		`{"name":"deploy","services":["api"],"duration":"30m"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d; body: %s", w.Code, w.Body.String())
+	}
+	if len(maint.created) != 1 {
+		t.Fatalf("expected 1 window created, got %d", len(maint.created))
+	}
+	got := maint.created[0]
+	if got.EndsAt.Sub(got.StartsAt) != 30*time.Minute {
+		t.Errorf("expected 30m window, got %v", got.EndsAt.Sub(got.StartsAt))
+	}
+
+	w = doRequest(t, s.Router(), "GET", "/api/maintenance")
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Data) != 1 || resp.Data[0]["name"] != "deploy" {
+		t.Errorf("expected listed window 'deploy', got %v", resp.Data)
+	}
+}
+
+func TestCreateMaintenance_Invalid(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetMaintenance(&mockMaintenance{})
+	s.SetAPIToken("secret")
+
+	bodies := []string{
+		`{"name":"no end"}`,
+		`{"services":["nonexistent"],"duration":"1h"}`,
+		`{"duration":"-1h"}`,
+		`{"duration":"1h","end":"2026-01-01T00:00:00Z"}`,
+		`{"bogus":true}`,
+	}
+	for _, body := range bodies {
+		w := doAuthRequest(t, s.Router(), "POST", "/api/maintenance", "secret", body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestDeleteMaintenance_NotFound(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetMaintenance(&mockMaintenance{})
+	s.SetAPIToken("secret")
+
+	w := doAuthRequest(t, s.Router(), "DELETE", "/api/maintenance/42", "secret", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestListServices_Maintenance(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetMaintenance(&mockMaintenance{active: map[string]bool{"api": true}})
+
+	w := doRequest(t, s.Router(), "GET", "/api/services")
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Data) != 1 || resp.Data[0]["maintenance"] != true {
+		t.Errorf("expected api to be reported in maintenance, got %v", resp.Data)
+	}
+}
+
+func TestSetServices(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetServices([]config.Service{{Name: "new", Type: "tcp", Target: "db:5432"}})
+
+	w := doRequest(t, s.Router(), "GET", "/api/services/api")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for removed service, got %d", w.Code)
+	}
+	w = doRequest(t, s.Router(), "GET", "/api/services")
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Data) != 1 || resp.Data[0]["name"] != "new" {
+		t.Errorf("expected only 'new' service, got %v", resp.Data)
+	}
+}
+
+// mockManager implements server.ServiceManager for testing.
+type mockManager struct {
+	config  map[string]bool
+	managed map[string]config.Service
+	paused  map[string]time.Time
+}
+
+func newMockManager() *mockManager {
+	return &mockManager{
+		config:  map[string]bool{"api": true},
+		managed: map[string]config.Service{},
+		paused:  map[string]time.Time{},
+	}
+}
+
+func (m *mockManager) Pause(_ context.Context, name string) error {
+	m.paused[name] = time.Now()
+	return nil
+}
+
+func (m *mockManager) Resume(_ context.Context, name string) error {
+	delete(m.paused, name)
+	return nil
+}
+
+func (m *mockManager) PausedSince(name string) (time.Time, bool) {
+	t, ok := m.paused[name]
+	return t, ok
+}
+
+func (m *mockManager) Add(_ context.Context, svc config.Service) error {
+	if m.config[svc.Name] || m.managed[svc.Name].Name != "" {
+		return catalog.ErrExists
+	}
+	m.managed[svc.Name] = svc
+	return nil
+}
+
+func (m *mockManager) Update(_ context.Context, svc config.Service) error {
+	if m.config[svc.Name] {
+		return catalog.ErrReadOnly
+	}
+	if _, ok := m.managed[svc.Name]; !ok {
+		return catalog.ErrNotFound
+	}
+	m.managed[svc.Name] = svc
+	return nil
+}
+
+func (m *mockManager) Remove(_ context.Context, name string) error {
+	if m.config[name] {
+		return catalog.ErrReadOnly
+	}
+	if _, ok := m.managed[name]; !ok {
+		return catalog.ErrNotFound
+	}
+	delete(m.managed, name)
+	return nil
+}
+
+func (m *mockManager) Source(name string) string {
+	if m.config[name] {
+		return catalog.SourceConfig
+	}
+	if _, ok := m.managed[name]; ok {
+		return catalog.SourceAPI
+	}
+	return ""
+}
+
+func TestCreateService_RequiresToken(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetServiceManager(newMockManager())
+	body := `{"name":"db","type":"tcp","target":"db:5432"}`
+
+	w := doAuthRequest(t, s.Router(), "POST", "/api/services", "", body)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 with no token configured, got %d", w.Code)
+	}
+
+	s.SetAPIToken("secret")
+	w = doAuthRequest(t, s.Router(), "POST", "/api/services", "", body)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", w.Code)
+	}
+}
+
+func TestCreateUpdateDeleteService(t *testing.T) {
+	mgr := newMockManager()
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetServiceManager(mgr)
+	s.SetAPIToken("secret")
+
+	w := doAuthRequest(t, s.Router(), "POST", "/api/services", "secret",
+		`{"name":"db","type":"tcp","target":"db:5432","interval":"1m","tags":["prod"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Data["source"] != "api" || resp.Data["interval"] != "1m0s" {
+		t.Errorf("unexpected created service: %v", resp.Data)
+	}
+	if got := mgr.managed["db"]; got.Interval.Duration != time.Minute || !got.HasTag("prod") {
+		t.Errorf("unexpected stored service: %+v", got)
+	}
+
+	w = doAuthRequest(t, s.Router(), "PUT", "/api/services/db", "secret",
+		`{"type":"tcp","target":"db:5433"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body: %s", w.Code, w.Body.String())
+	}
+	if got := mgr.managed["db"]; got.Target != "db:5433" || got.Interval.Duration != 30*time.Second {
+		t.Errorf("expected replaced service with default interval, got %+v", got)
+	}
+
+	w = doAuthRequest(t, s.Router(), "DELETE", "/api/services/db", "secret", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if _, ok := mgr.managed["db"]; ok {
+		t.Error("expected service to be removed")
+	}
+}
+
+func TestManageService_Errors(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetServiceManager(newMockManager())
+	s.SetAPIToken("secret")
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"POST", "/api/services", `{"name":"api","type":"tcp","target":"x:1"}`, http.StatusConflict},
+		{"POST", "/api/services", `{"name":"db","type":"smtp","target":"x:1"}`, http.StatusBadRequest},
+		{"POST", "/api/services", `{"name":"db","type":"tcp"}`, http.StatusBadRequest},
+		{"POST", "/api/services", `{"name":"db","type":"tcp","target":"x:1","bogus":1}`, http.StatusBadRequest},
+		{"PUT", "/api/services/api", `{"type":"tcp","target":"x:1"}`, http.StatusConflict},
+		{"PUT", "/api/services/ghost", `{"type":"tcp","target":"x:1"}`, http.StatusNotFound},
+		{"PUT", "/api/services/ghost", `{"name":"other","type":"tcp","target":"x:1"}`, http.StatusBadRequest},
+		{"DELETE", "/api/services/api", "", http.StatusConflict},
+		{"DELETE", "/api/services/ghost", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := doAuthRequest(t, s.Router(), tt.method, tt.path, "secret", tt.body)
+		if w.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d", tt.method, tt.path, tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+// mockRunner implements server.CheckRunner for testing.
+type mockRunner struct {
+	calls int
+}
+
+func (m *mockRunner) CheckNow(_ context.Context, name string) (checker.CheckResult, error) {
+	m.calls++
+	return checker.CheckResult{
+		ServiceName:  name,
+		Status:       checker.StatusUp,
+		ResponseTime: 42 * time.Millisecond,
+		CheckedAt:    time.Now(),
+	}, nil
+}
+
+func TestCheckService(t *testing.T) {
+	runner := &mockRunner{}
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetCheckRunner(runner)
+
+	w := doAuthRequest(t, s.Router(), "POST", "/api/services/api/check", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Data["service"] != "api" || resp.Data["status"] != "up" || resp.Data["response_ms"] != float64(42) {
+		t.Errorf("unexpected check result: %v", resp.Data)
+	}
+
+	// A second check right away is rate limited.
+	w = doAuthRequest(t, s.Router(), "POST", "/api/services/api/check", "", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+	if runner.calls != 1 {
+		t.Errorf("expected 1 check to run, got %d", runner.calls)
+	}
+}
+
+func TestCheckService_NotFound(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetCheckRunner(&mockRunner{})
+
+	w := doAuthRequest(t, s.Router(), "POST", "/api/services/ghost/check", "", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestPauseResumeService(t *testing.T) {
+	mgr := newMockManager()
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetServiceManager(mgr)
+	s.SetAPIToken("secret")
+
+	w := doAuthRequest(t, s.Router(), "POST", "/api/services/api/pause", "", "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", w.Code)
+	}
+
+	w = doAuthRequest(t, s.Router(), "POST", "/api/services/api/pause", "secret", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Data["status"] != "paused" || resp.Data["paused"] != true || resp.Data["paused_since"] == nil {
+		t.Errorf("expected paused service, got %v", resp.Data)
+	}
+	if _, ok := mgr.paused["api"]; !ok {
+		t.Error("expected manager to pause api")
+	}
+
+	w = doAuthRequest(t, s.Router(), "POST", "/api/services/api/resume", "secret", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Data["status"] == "paused" || resp.Data["paused"] != false {
+		t.Errorf("expected resumed service, got %v", resp.Data)
+	}
+
+	w = doAuthRequest(t, s.Router(), "POST", "/api/services/ghost/pause", "secret", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown service, got %d", w.Code)
+	}
+}
+
+func TestListServices_Paused(t *testing.T) {
+	services := makeServices()
+	services[0].Paused = true
+	s := server.New(&mockStore{}, services, nil)
+	s.SetState(makeState("api", checker.StatusUp))
+
+	w := doRequest(t, s.Router(), "GET", "/api/services")
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Data) != 1 || resp.Data[0]["status"] != "paused" {
+		t.Errorf("expected api to be reported paused, got %v", resp.Data)
+	}
+}
+
+func TestGetService_Flapping(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	st := makeState("api", checker.StatusDown)
+	api := st["api"]
+	api.Flapping, api.FlappingSince, api.StateChange = true, time.Now().Add(-10*time.Minute), 62.5
+	st["api"] = api
+	s.SetState(st)
+
+	w := doRequest(t, s.Router(), "GET", "/api/services/api")
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Data["status"] != "flapping" || resp.Data["flapping_since"] == nil || resp.Data["state_change_percent"] != 62.5 {
+		t.Errorf("expected api to be reported flapping, got %v", resp.Data)
+	}
+}
+
+type mockAlertLog struct {
+	status string
+	limit  int
+}
+
+func (m *mockAlertLog) ListAlerts(_ context.Context, status string, limit int) ([]storage.AlertDelivery, error) {
+	m.status, m.limit = status, limit
+	return []storage.AlertDelivery{{ID: 1, Service: "api", Channel: "ops", Status: status, Attempts: 3, LastError: "timeout"}}, nil
+}
+
+func TestListAlerts(t *testing.T) {
+	log := &mockAlertLog{}
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetAlertLog(log)
+
+	w := doRequest(t, s.Router(), "GET", "/api/alerts?status=pending&limit=5000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if log.status != "pending" || log.limit != 1000 {
+		t.Errorf("expected status pending and limit capped at 1000, got %q and %d", log.status, log.limit)
+	}
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Data) != 1 || resp.Data[0]["attempts"] != float64(3) || resp.Data[0]["last_error"] != "timeout" {
+		t.Errorf("unexpected response: %v", resp.Data)
+	}
+	if _, ok := resp.Data[0]["payload"]; ok {
+		t.Error("payload should not be exposed")
+	}
+}
+
+func TestListAlerts_InvalidStatus(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetAlertLog(&mockAlertLog{})
+	w := doRequest(t, s.Router(), "GET", "/api/alerts?status=lost")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestListAlerts_NoLog(t *testing.T) {
+	s := server.New(&mockStore{}, makeServices(), nil)
+	w := doRequest(t, s.Router(), "GET", "/api/alerts")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp struct {
+		Data []interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Data == nil || len(resp.Data) != 0 {
+		t.Errorf("expected empty list, got %v", resp.Data)
+	}
+}
+
+type mockIncidentLog struct {
+	query storage.IncidentQuery
+}
+
+func (m *mockIncidentLog) ListIncidents(_ context.Context, q storage.IncidentQuery) ([]storage.Incident, error) {
+	m.query = q
+	started := time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)
+	resolved := started.Add(42 * time.Minute)
+	return []storage.Incident{
+		{ID: 2, Service: "api", StartedAt: time.Now().Add(-time.Minute), FirstError: "503", CheckCount: 2},
+		{ID: 1, Service: "api", StartedAt: started, ResolvedAt: &resolved, FirstError: "timeout", CheckCount: 84},
+	}, nil
+}
+
+func TestServiceIncidents(t *testing.T) {
+	log := &mockIncidentLog{}
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetIncidentLog(log)
+
+	w := doRequest(t, s.Router(), "GET", "/api/services/api/incidents?from=2026-02-17&to=2026-02-18T00:00:00Z")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	want := storage.IncidentQuery{
+		Service: "api",
+		From:    time.Date(2026, 2, 17, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2026, 2, 18, 0, 0, 0, 0, time.UTC),
+		Limit:   100,
+	}
+	if q := log.query; q.Service != want.Service || !q.From.Equal(want.From) || !q.To.Equal(want.To) || q.Limit != want.Limit {
+		t.Errorf("expected query %+v, got %+v", want, q)
+	}
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Data) != 2 {
+		t.Fatalf("expected 2 incidents, got %v", resp.Data)
+	}
+	if resp.Data[0]["ongoing"] != true || resp.Data[0]["resolved_at"] != nil {
+		t.Errorf("expected the first incident to be ongoing, got %v", resp.Data[0])
+	}
+	if resp.Data[1]["ongoing"] != false || resp.Data[1]["duration_seconds"] != float64(42*60) || resp.Data[1]["first_error"] != "timeout" {
+		t.Errorf("unexpected resolved incident: %v", resp.Data[1])
+	}
+}
+
+func TestListIncidents_Params(t *testing.T) {
+	log := &mockIncidentLog{}
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetIncidentLog(log)
+
+	w := doRequest(t, s.Router(), "GET", "/api/incidents?service=db&limit=5000")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if log.query.Service != "db" || log.query.Limit != 1000 || !log.query.From.IsZero() {
+		t.Errorf("unexpected query: %+v", log.query)
+	}
+
+	for _, path := range []string{
+		"/api/incidents?from=yesterday",
+		"/api/incidents?from=2026-02-18&to=2026-02-17",
+		"/api/incidents?limit=-1",
+	} {
+		if w := doRequest(t, s.Router(), "GET", path); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+	if w := doRequest(t, s.Router(), "GET", "/api/services/nope/incidents"); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown service, got %d", w.Code)
+	}
+}
+
+// mockRollups implements server.RollupSource for testing.
+type mockRollups struct {
+	res      storage.Resolution
+	from, to time.Time
+}
+
+func (m *mockRollups) Rollups(_ context.Context, service string, res storage.Resolution, from, to time.Time) ([]storage.Rollup, error) {
+	m.res, m.from, m.to = res, from, to
+	return []storage.Rollup{{Service: service, Bucket: from, Checks: 120, Up: 118, MinMs: 12, AvgMs: 40.5, P95Ms: 90, MaxMs: 300}}, nil
+}
+
+func TestServiceRollups(t *testing.T) {
+	rollups := &mockRollups{}
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetRollups(rollups)
+
+	w := doRequest(t, s.Router(), "GET", "/api/services/api/rollups?resolution=day&from=2026-03-01&to=2026-03-08")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if rollups.res != storage.Daily || !rollups.from.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected query: %+v", rollups)
+	}
+	var resp struct {
+		Data []storage.Rollup `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Data) != 1 || resp.Data[0].P95Ms != 90 || resp.Data[0].Up != 118 {
+		t.Errorf("unexpected rollups: %+v", resp.Data)
+	}
+
+	// Hourly rollups of the last day by default.
+	if w := doRequest(t, s.Router(), "GET", "/api/services/api/rollups"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if rollups.res != storage.Hourly || rollups.to.Sub(rollups.from) != 24*time.Hour {
+		t.Errorf("unexpected default query: %+v", rollups)
+	}
+
+	for path, code := range map[string]int{
+		"/api/services/api/rollups?resolution=week":               http.StatusBadRequest,
+		"/api/services/api/rollups?from=2026-03-08&to=2026-03-01": http.StatusBadRequest,
+		"/api/services/nope/rollups":                              http.StatusNotFound,
+		"/api/services/api/uptime?to=soon":                        http.StatusBadRequest,
+		"/api/services/nope/uptime":                               http.StatusNotFound,
+	} {
+		if w := doRequest(t, s.Router(), "GET", path); w.Code != code {
+			t.Errorf("%s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+}
+
+type mockStats struct {
+	from, to time.Time
+	bucket   time.Duration
+}
+
+func (m *mockStats) ServiceStats(_ context.Context, service string, from, to time.Time, bucket time.Duration) ([]storage.LatencyStats, error) {
+	m.from, m.to, m.bucket = from, to, bucket
+	pct, p50, p99 := 99.0, int64(20), int64(120)
+	return []storage.LatencyStats{{Bucket: from, Checks: 60, Up: 59, MinMs: 10, AvgMs: 25, P50Ms: &p50, P95Ms: 80, P99Ms: &p99, MaxMs: 150, UptimePercent: &pct}}, nil
+}
+
+func TestServiceStats(t *testing.T) {
+	stats := &mockStats{}
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetStats(stats)
+
+	w := doRequest(t, s.Router(), "GET", "/api/services/api/stats?from=2026-03-01&to=2026-03-02&bucket=15m")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if stats.bucket != 15*time.Minute || !stats.to.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected query: %+v", stats)
+	}
+	var resp struct {
+		Data []storage.LatencyStats `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if len(resp.Data) != 1 || *resp.Data[0].P99Ms != 120 || *resp.Data[0].UptimePercent != 99 {
+		t.Errorf("unexpected stats: %+v", resp.Data)
+	}
+
+	// Hourly buckets over the last day by default, daily over longer ranges.
+	if w := doRequest(t, s.Router(), "GET", "/api/services/api/stats"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if stats.bucket != time.Hour || stats.to.Sub(stats.from) != 24*time.Hour {
+		t.Errorf("unexpected default query: %+v", stats)
+	}
+	if w := doRequest(t, s.Router(), "GET", "/api/services/api/stats?from=2026-01-01&to=2026-03-01"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if stats.bucket != 24*time.Hour {
+		t.Errorf("expected daily buckets for two months, got %s", stats.bucket)
+	}
+
+	for path, code := range map[string]int{
+		"/api/services/api/stats?bucket=soon":                             http.StatusBadRequest,
+		"/api/services/api/stats?bucket=500ms":                            http.StatusBadRequest,
+		"/api/services/api/stats?from=2026-01-01&to=2026-03-01&bucket=1m": http.StatusBadRequest,
+		"/api/services/nope/stats":                                        http.StatusNotFound,
+	} {
+		if w := doRequest(t, s.Router(), "GET", path); w.Code != code {
+			t.Errorf("%s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+}
+
+func TestServiceUptime(t *testing.T) {
+	store := &mockStore{uptime: map[string]float64{"api": 99.5}}
+	services := makeServices()
+	services[0].SLA = config.SLA{Target: 99.9, Window: "30d"}
+	s := server.New(store, services, nil)
+
+	w := doRequest(t, s.Router(), "GET", "/api/services/api/uptime?from=2026-01-01&to=2026-01-02")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+	if resp.Data["uptime_percent"] != 99.5 || resp.Data["from"] != "2026-01-01T00:00:00Z" || resp.Data["known_seconds"] != float64(86400) {
+		t.Errorf("unexpected uptime: %v", resp.Data)
+	}
+	sla, _ := resp.Data["sla"].(map[string]interface{})
+	if sla["met"] != false || sla["target"] != 99.9 {
+		t.Errorf("expected a missed SLA, got %v", resp.Data["sla"])
+	}
+
+	if w := doRequest(t, s.Router(), "GET", "/api/services/api/uptime?window=7d"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for a window, got %d", w.Code)
+	}
+	if w := doRequest(t, s.Router(), "GET", "/api/services/api/uptime?window=1y"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown window, got %d", w.Code)
+	}
+}
+
+func TestListServices_UptimeWindowsAndSLA(t *testing.T) {
+	store := &mockStore{uptime: map[string]float64{"api": 99.95}}
+	services := append(makeServices(), config.Service{Name: "db", Type: "tcp", Target: "db:5432"})
+	services[0].SLA = config.SLA{Target: 99.9, Window: "30d"}
+	s := server.New(store, services, nil)
+
+	w := doRequest(t, s.Router(), "GET", "/api/services")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data []struct {
+			Name   string              `json:"name"`
+			Uptime map[string]*float64 `json:"uptime"`
+			SLA    *struct {
+				Target                      float64  `json:"target"`
+				Window                      string   `json:"window"`
+				UptimePercent               *float64 `json:"uptime_percent"`
+				Met                         bool     `json:"met"`
+				ErrorBudgetSeconds          float64  `json:"error_budget_seconds"`
+				ErrorBudgetRemainingSeconds float64  `json:"error_budget_remaining_seconds"`
+				ErrorBudgetRemainingPercent float64  `json:"error_budget_remaining_percent"`
+			} `json:"sla"`
+		} `json:"data"`
+	}
+	decodeJSON(t, w, &resp)
+
+	api := resp.Data[0]
+	for _, window := range []string{"24h", "7d", "30d", "90d"} {
+		if p := api.Uptime[window]; p == nil || *p < 99.94 || *p > 99.96 {
+			t.Errorf("expected %s uptime of 99.95, got %v", window, p)
+		}
+	}
+	sla := api.SLA
+	if sla == nil || !sla.Met || sla.Window != "30d" {
+		t.Fatalf("expected a met 30d SLA, got %+v", sla)
+	}
+	// 30 days at 99.9% allow 43.2 minutes down; 99.95% used half of it.
+	if diff := sla.ErrorBudgetSeconds - 2592; diff < -1 || diff > 1 {
+		t.Errorf("expected an error budget of 2592s, got %v", sla.ErrorBudgetSeconds)
+	}
+	if diff := sla.ErrorBudgetRemainingPercent - 50; diff < -0.1 || diff > 0.1 {
+		t.Errorf("expected half the error budget left, got %v%%", sla.ErrorBudgetRemainingPercent)
+	}
+
+	db := resp.Data[1]
+	if db.SLA != nil || db.Uptime["24h"] != nil {
+		t.Errorf("expected no SLA and unknown uptime for db, got %+v", db)
+	}
+	// One query per window, however many services there are.
+	if store.uptimesCalls != 4 {
+		t.Errorf("expected 4 UptimesBetween calls, got %d", store.uptimesCalls)
+	}
+}
+
+type mockExporter struct {
+	checks []storage.Check
+	err    error
+	q      storage.ExportQuery
+}
+
+func (m *mockExporter) ExportChecks(_ context.Context, q storage.ExportQuery, fn func(storage.Check) error) error {
+	m.q = q
+	for _, c := range m.checks {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
+
+func TestExport(t *testing.T) {
+	exp := &mockExporter{checks: []storage.Check{makeCheck("api", "up"), makeCheck("api", "down")}}
+	s := server.New(&mockStore{}, makeServices(), nil)
+	s.SetExporter(exp)
+
+	w := doRequest(t, s.Router(), "GET", "/api/export?service=removed&from=2026-03-01&to=2026-03-02")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected JSON Lines by default, got %q", ct)
+	}
+	if lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"status":"down"`) {
+		t.Errorf("unexpected export:\n%s", w.Body)
+	}
+	if exp.q.Service != "removed" || !exp.q.To.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected query: %+v", exp.q)
+	}
+
+	w = doRequest(t, s.Router(), "GET", "/api/export?format=csv")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "servprobe-export.csv") {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	if lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "service,checked_at,status") {
+		t.Errorf("unexpected CSV export:\n%s", w.Body)
+	}
+
+	for path, code := range map[string]int{
+		"/api/export?format=parquet":                http.StatusBadRequest,
+		"/api/export?from=soon":                     http.StatusBadRequest,
+		"/api/export?from=2026-03-02&to=2026-03-01": http.StatusBadRequest,
+	} {
+		if w := doRequest(t, s.Router(), "GET", path); w.Code != code {
+			t.Errorf("%s: expected %d, got %d", path, code, w.Code)
+		}
+	}
+
+	// A failing query fails the request if nothing was sent yet.
+	s.SetExporter(&mockExporter{err: sql.ErrConnDone})
+	if w := doRequest(t, s.Router(), "GET", "/api/export"); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/hazz-dev/servprobe/internal/catalog"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// ServiceManager adds, edits and removes services at runtime.
+type ServiceManager interface {
+	Add(ctx context.Context, svc config.Service) error
+	Update(ctx context.Context, svc config.Service) error
+	Remove(ctx context.Context, name string) error
+	Pause(ctx context.Context, name string) error
+	Resume(ctx context.Context, name string) error
+	Source(name string) string
+	PausedSince(name string) (time.Time, bool)
+}
+
+func (s *Server) handleCreateService(w http.ResponseWriter, r *http.Request) {
+	if s.manager == nil {
+		writeError(w, http.StatusNotImplemented, "service management is not enabled")
+		return
+	}
+
+	var spec config.ServiceSpec
+	if err := decodeBody(w, r, &spec); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	svc, err := spec.Parse()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.manager.Add(r.Context(), svc); err != nil {
+		s.writeManagerError(w, svc.Name, err)
+		return
+	}
+	s.logger.Info("service added", "service", svc.Name)
+	writeJSON(w, http.StatusCreated, s.newServiceDetail(svc))
+}
+
+func (s *Server) handleUpdateService(w http.ResponseWriter, r *http.Request) {
+	if s.manager == nil {
+		writeError(w, http.StatusNotImplemented, "service management is not enabled")
+		return
+	}
+	name := chi.URLParam(r, "name")
+
+	var spec config.ServiceSpec
+	if err := decodeBody(w, r, &spec); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if spec.Name == "" {
+		spec.Name = name
+	}
+	if spec.Name != name {
+		writeError(w, http.StatusBadRequest, "renaming a service is not supported")
+		return
+	}
+	svc, err := spec.Parse()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.manager.Update(r.Context(), svc); err != nil {
+		s.writeManagerError(w, name, err)
+		return
+	}
+	s.logger.Info("service updated", "service", name)
+	writeJSON(w, http.StatusOK, s.newServiceDetail(svc))
+}
+
+func (s *Server) handleDeleteService(w http.ResponseWriter, r *http.Request) {
+	if s.manager == nil {
+		writeError(w, http.StatusNotImplemented, "service management is not enabled")
+		return
+	}
+	name := chi.URLParam(r, "name")
+
+	if err := s.manager.Remove(r.Context(), name); err != nil {
+		s.writeManagerError(w, name, err)
+		return
+	}
+	s.logger.Info("service removed", "service", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlePauseService(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, true)
+}
+
+func (s *Server) handleResumeService(w http.ResponseWriter, r *http.Request) {
+	s.setPaused(w, r, false)
+}
+
+func (s *Server) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if s.manager == nil {
+		writeError(w, http.StatusNotImplemented, "service management is not enabled")
+		return
+	}
+	name := chi.URLParam(r, "name")
+	svc, ok := s.serviceIndex()[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "service not found")
+		return
+	}
+
+	var err error
+	if paused {
+		err = s.manager.Pause(r.Context(), name)
+	} else {
+		err = s.manager.Resume(r.Context(), name)
+	}
+	if err != nil {
+		s.writeManagerError(w, name, err)
+		return
+	}
+	s.logger.Info("service pause changed", "service", name, "paused", paused)
+
+	svc.Paused = paused
+	d := s.newServiceDetail(svc)
+	s.withState(&d)
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (s *Server) writeManagerError(w http.ResponseWriter, name string, err error) {
+	switch {
+	case errors.Is(err, catalog.ErrNotFound):
+		writeError(w, http.StatusNotFound, "service not found")
+	case errors.Is(err, catalog.ErrExists), errors.Is(err, catalog.ErrReadOnly):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		s.logger.Error("managing service", "service", name, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+	}
+}
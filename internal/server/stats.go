@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// maxStatsBuckets bounds the buckets one stats request may ask for.
+const maxStatsBuckets = 1000
+
+// StatsSource computes bucketed latency and uptime stats.
+type StatsSource interface {
+	ServiceStats(ctx context.Context, service string, from, to time.Time, bucket time.Duration) ([]storage.LatencyStats, error)
+}
+
+// SetStats enables GET /api/services/{name}/stats.
+func (s *Server) SetStats(src StatsSource) {
+	s.stats = src
+}
+
+func (s *Server) handleServiceStats(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if _, ok := s.serviceIndex()[name]; !ok {
+		writeError(w, http.StatusNotFound, "service not found")
+		return
+	}
+
+	from, to, ok := rangeParams(w, r, 24*time.Hour)
+	if !ok {
+		return
+	}
+	// Hourly buckets up to a week, daily beyond.
+	bucket := time.Hour
+	if to.Sub(from) > 7*24*time.Hour {
+		bucket = 24 * time.Hour
+	}
+	if v := r.URL.Query().Get("bucket"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Second || d%time.Second != 0 {
+			writeError(w, http.StatusBadRequest, "invalid bucket parameter: use a whole number of seconds, e.g. 5m or 1h")
+			return
+		}
+		bucket = d
+	}
+	if to.Sub(from)/bucket >= maxStatsBuckets {
+		writeError(w, http.StatusBadRequest, "bucket too small for the range: at most 1000 buckets")
+		return
+	}
+
+	out := []storage.LatencyStats{}
+	if s.stats != nil {
+		stats, err := s.stats.ServiceStats(r.Context(), name, from, to, bucket)
+		if err != nil {
+			s.logger.Error("ServiceStats", "service", name, "error", err)
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		out = append(out, stats...)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
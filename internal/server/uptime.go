@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// slaStatus reports how a service is doing against its SLA over the SLA's
+// window. The error budget is the downtime the target allows within the
+// part of the window that counts towards uptime.
+type slaStatus struct {
+	config.SLA
+	UptimePercent               *float64 `json:"uptime_percent"`
+	Met                         bool     `json:"met"`
+	ErrorBudgetSeconds          float64  `json:"error_budget_seconds"`
+	ErrorBudgetRemainingSeconds float64  `json:"error_budget_remaining_seconds"`
+	ErrorBudgetRemainingPercent float64  `json:"error_budget_remaining_percent"`
+}
+
+func newSLAStatus(sla config.SLA, u storage.Uptime) *slaStatus {
+	st := &slaStatus{SLA: sla, Met: true, ErrorBudgetRemainingPercent: 100}
+	if u.Known <= 0 {
+		return st
+	}
+	pct := u.Percent()
+	budget := u.Known.Seconds() * (100 - sla.Target) / 100
+	remaining := budget - (u.Known - u.Up).Seconds()
+	st.UptimePercent = &pct
+	st.Met = pct >= sla.Target
+	st.ErrorBudgetSeconds = budget
+	st.ErrorBudgetRemainingSeconds = remaining
+	st.ErrorBudgetRemainingPercent = remaining / budget * 100
+	return st
+}
+
+// windowUptimes returns the uptime over each of config.UptimeWindows ending
+// at now, by window name and then service. An empty service gets every
+// service's uptime in one go. Windows that fail to load are left out.
+func (s *Server) windowUptimes(ctx context.Context, service string, now time.Time) map[string]map[string]storage.Uptime {
+	out := make(map[string]map[string]storage.Uptime, len(config.UptimeWindows))
+	for _, w := range config.UptimeWindows {
+		from := now.Add(-w.Duration)
+		if service == "" {
+			u, err := s.store.UptimesBetween(ctx, from, now)
+			if err != nil {
+				s.logger.Error("UptimesBetween", "window", w.Name, "error", err)
+				continue
+			}
+			out[w.Name] = u
+			continue
+		}
+		u, err := s.store.UptimeBetween(ctx, service, from, now)
+		if err != nil {
+			s.logger.Error("UptimeBetween", "service", service, "window", w.Name, "error", err)
+			continue
+		}
+		out[w.Name] = map[string]storage.Uptime{service: u}
+	}
+	return out
+}
+
+// withUptime fills in d's uptime over each window of uptimes, as returned by
+// windowUptimes, and its SLA status if svc has an SLA. uptime_percent is the
+// uptime of the last 24 hours.
+func (s *Server) withUptime(d *serviceDetail, svc config.Service, uptimes map[string]map[string]storage.Uptime) {
+	d.Uptime = make(map[string]*float64, len(config.UptimeWindows))
+	for _, w := range config.UptimeWindows {
+		byService, ok := uptimes[w.Name]
+		if !ok {
+			continue
+		}
+		u := byService[svc.Name]
+		if u.Known > 0 {
+			pct := u.Percent()
+			d.Uptime[w.Name] = &pct
+		} else {
+			d.Uptime[w.Name] = nil
+		}
+		if w.Name == "24h" {
+			d.UptimePct = u.Percent()
+		}
+		if svc.SLA.Target > 0 && w.Name == svc.SLA.Window {
+			d.SLA = newSLAStatus(svc.SLA, u)
+		}
+	}
+}
+
+type uptimeResponse struct {
+	Service       string     `json:"service"`
+	From          time.Time  `json:"from"`
+	To            time.Time  `json:"to"`
+	UptimePercent float64    `json:"uptime_percent"`
+	UpSeconds     float64    `json:"up_seconds"`
+	KnownSeconds  float64    `json:"known_seconds"`
+	SLA           *slaStatus `json:"sla,omitempty"`
+}
+
+func (s *Server) handleServiceUptime(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	svc, ok := s.serviceIndex()[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "service not found")
+		return
+	}
+
+	var from, to time.Time
+	if v := r.URL.Query().Get("window"); v != "" {
+		win, ok := config.LookupUptimeWindow(v)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "invalid window parameter: use 24h, 7d, 30d or 90d")
+			return
+		}
+		to = time.Now().UTC()
+		from = to.Add(-win.Duration)
+	} else if from, to, ok = rangeParams(w, r, 24*time.Hour); !ok {
+		return
+	}
+
+	u, err := s.store.UptimeBetween(r.Context(), name, from, to)
+	if err != nil {
+		s.logger.Error("UptimeBetween", "service", name, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	out := uptimeResponse{
+		Service:       name,
+		From:          from,
+		To:            to,
+		UptimePercent: u.Percent(),
+		UpSeconds:     u.Up.Seconds(),
+		KnownSeconds:  u.Known.Seconds(),
+	}
+	if svc.SLA.Target > 0 {
+		out.SLA = newSLAStatus(svc.SLA, u)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,160 @@
+// Package state tracks the current state of every monitored service in
+// memory: its status, how long it has had it, its last check result and
+// whether it is flapping. The scheduler records every result; the API and
+// the alerter read from it.
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// ServiceState is the current state of one service.
+type ServiceState struct {
+	Status checker.Status
+	// Since is when the service was first seen with Status, i.e. the start
+	// of the current run of identical results.
+	Since time.Time
+	// PreviousSince is when the service got the status it had before
+	// Status, if known.
+	PreviousSince time.Time
+	// Consecutive is the number of consecutive checks with Status.
+	Consecutive int
+	LastResult  checker.CheckResult
+	// StateChange is the weighted percentage of checks in the flap
+	// detection window whose status differed from the check before.
+	StateChange float64
+	// Flapping is set while the service changes status too often for its
+	// individual transitions to be meaningful; FlappingSince is when it
+	// started.
+	Flapping      bool
+	FlappingSince time.Time
+	// recent holds the statuses of the last checks, oldest first.
+	recent []checker.Status
+}
+
+// Tracker holds the state of every service. It is safe for concurrent use.
+type Tracker struct {
+	mu     sync.RWMutex
+	states map[string]ServiceState
+	flap   config.FlappingConfig
+}
+
+// New creates an empty Tracker.
+func New() *Tracker {
+	return &Tracker{states: make(map[string]ServiceState)}
+}
+
+// Seed replaces the tracked state with the runs loaded from storage, so
+// transitions are detected correctly across restarts.
+func (t *Tracker) Seed(runs []storage.ServiceRun) {
+	states := make(map[string]ServiceState, len(runs))
+	for _, run := range runs {
+		c := run.Latest
+		states[c.Service] = ServiceState{
+			Status:      checker.Status(c.Status),
+			Since:       run.Since,
+			Consecutive: run.Count,
+			LastResult: checker.CheckResult{
+				ServiceName:  c.Service,
+				Status:       checker.Status(c.Status),
+				ResponseTime: time.Duration(c.ResponseMs) * time.Millisecond,
+				Error:        c.Error,
+				CheckedAt:    c.CheckedAt,
+				Maintenance:  c.Maintenance,
+			},
+		}
+	}
+	t.mu.Lock()
+	t.states = states
+	t.mu.Unlock()
+}
+
+// SetFlapping sets how flapping is detected. Until it is called, or with
+// detection disabled, no service is reported as flapping.
+func (t *Tracker) SetFlapping(cfg config.FlappingConfig) {
+	t.mu.Lock()
+	t.flap = cfg
+	t.mu.Unlock()
+}
+
+// Record applies a check result and returns the state from before it. ok is
+// false if the service had no state yet.
+func (t *Tracker) Record(result checker.CheckResult) (prev ServiceState, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev, ok = t.states[result.ServiceName]
+	next := prev
+	if !ok || prev.Status != result.Status {
+		next.PreviousSince = prev.Since
+		next.Status = result.Status
+		next.Since = result.CheckedAt
+		next.Consecutive = 0
+	}
+	next.Consecutive++
+	next.LastResult = result
+	t.detectFlapping(&next, result)
+	t.states[result.ServiceName] = next
+	return prev, ok
+}
+
+// Get returns the state of the named service.
+func (t *Tracker) Get(name string) (ServiceState, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	st, ok := t.states[name]
+	return st, ok
+}
+
+// Forget drops the state of a service that is no longer monitored.
+func (t *Tracker) Forget(name string) {
+	t.mu.Lock()
+	delete(t.states, name)
+	t.mu.Unlock()
+}
+
+// detectFlapping adds result to st's recent statuses and updates whether
+// it is flapping. Separate thresholds to start and stop keep a service near
+// one of them from toggling in and out of flapping.
+func (t *Tracker) detectFlapping(st *ServiceState, result checker.CheckResult) {
+	window := t.flap.Window
+	if t.flap.Disabled || window < 3 {
+		st.recent, st.StateChange, st.Flapping, st.FlappingSince = nil, 0, false, time.Time{}
+		return
+	}
+	// Copy, since the previous state may still be read through Get.
+	keep := st.recent[max(0, len(st.recent)-(window-1)):]
+	recent := make([]checker.Status, 0, len(keep)+1)
+	st.recent = append(append(recent, keep...), result.Status)
+	st.StateChange = stateChange(st.recent, window)
+
+	switch {
+	case !st.Flapping && st.StateChange >= t.flap.HighThreshold:
+		st.Flapping = true
+		st.FlappingSince = result.CheckedAt
+	case st.Flapping && st.StateChange < t.flap.LowThreshold:
+		st.Flapping = false
+		st.FlappingSince = time.Time{}
+	}
+}
+
+// stateChange returns the percent state change of recent over a window of
+// checks, as Nagios computes it: each change between consecutive checks
+// counts, weighted from 0.8 for the oldest to 1.2 for the newest. Checks
+// missing from the start of the window count as unchanged.
+func stateChange(recent []checker.Status, window int) float64 {
+	slots := window - 1
+	offset := window - len(recent)
+	var total float64
+	for i := 1; i < len(recent); i++ {
+		if recent[i] != recent[i-1] {
+			pos := offset + i - 1
+			total += 0.8 + 0.4*float64(pos)/float64(slots-1)
+		}
+	}
+	return total / float64(slots) * 100
+}
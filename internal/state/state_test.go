@@ -0,0 +1,131 @@
+package state_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/state"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func result(status checker.Status, at time.Time) checker.CheckResult {
+	return checker.CheckResult{ServiceName: "api", Status: status, CheckedAt: at}
+}
+
+func TestTracker_Record(t *testing.T) {
+	tr := state.New()
+	t0 := time.Now()
+
+	if _, ok := tr.Record(result(checker.StatusUp, t0)); ok {
+		t.Error("expected no previous state on first record")
+	}
+	prev, ok := tr.Record(result(checker.StatusUp, t0.Add(time.Minute)))
+	if !ok || prev.Status != checker.StatusUp || prev.Consecutive != 1 {
+		t.Errorf("unexpected previous state: %+v", prev)
+	}
+	st, _ := tr.Get("api")
+	if st.Consecutive != 2 || !st.Since.Equal(t0) {
+		t.Errorf("expected 2 consecutive up since t0, got %+v", st)
+	}
+
+	tr.Record(result(checker.StatusDown, t0.Add(2*time.Minute)))
+	st, _ = tr.Get("api")
+	if st.Status != checker.StatusDown || st.Consecutive != 1 || !st.Since.Equal(t0.Add(2*time.Minute)) {
+		t.Errorf("expected a new down run, got %+v", st)
+	}
+	if !st.LastResult.CheckedAt.Equal(t0.Add(2 * time.Minute)) {
+		t.Errorf("expected last result to be kept, got %+v", st.LastResult)
+	}
+	if !st.PreviousSince.Equal(t0) {
+		t.Errorf("expected previous run to have started at t0, got %v", st.PreviousSince)
+	}
+
+	tr.Forget("api")
+	if _, ok := tr.Get("api"); ok {
+		t.Error("expected state to be forgotten")
+	}
+}
+
+func TestTracker_Seed(t *testing.T) {
+	tr := state.New()
+	since := time.Now().Add(-time.Hour)
+	tr.Seed([]storage.ServiceRun{{
+		Latest: storage.Check{Service: "api", Status: "down", ResponseMs: 120, Error: "timeout", CheckedAt: time.Now()},
+		Since:  since,
+		Count:  4,
+	}})
+
+	st, ok := tr.Get("api")
+	if !ok {
+		t.Fatal("expected seeded state")
+	}
+	if st.Status != checker.StatusDown || st.Consecutive != 4 || !st.Since.Equal(since) {
+		t.Errorf("unexpected seeded state: %+v", st)
+	}
+	if st.LastResult.ResponseTime != 120*time.Millisecond || st.LastResult.Error != "timeout" {
+		t.Errorf("unexpected seeded last result: %+v", st.LastResult)
+	}
+
+	// The next check continues the seeded run.
+	tr.Record(result(checker.StatusDown, time.Now()))
+	if st, _ := tr.Get("api"); st.Consecutive != 5 || !st.Since.Equal(since) {
+		t.Errorf("expected the seeded run to continue, got %+v", st)
+	}
+}
+
+func TestTracker_Flapping(t *testing.T) {
+	tr := state.New()
+	tr.SetFlapping(config.FlappingConfig{Window: 21, LowThreshold: 25, HighThreshold: 50})
+	t0 := time.Now()
+	at := func(i int) time.Time { return t0.Add(time.Duration(i) * time.Minute) }
+
+	// Alternate until the state change reaches the high threshold.
+	i := 0
+	for ; i < 21; i++ {
+		status := checker.StatusUp
+		if i%2 == 1 {
+			status = checker.StatusDown
+		}
+		tr.Record(result(status, at(i)))
+		if st, _ := tr.Get("api"); st.Flapping {
+			break
+		}
+	}
+	st, _ := tr.Get("api")
+	if !st.Flapping || st.StateChange < 50 || !st.FlappingSince.Equal(at(i)) {
+		t.Fatalf("expected flapping after alternating checks, got %+v", st)
+	}
+	if i < 9 {
+		t.Errorf("started flapping too early, after %d checks", i+1)
+	}
+
+	// Steady checks push the changes out of the window until the state
+	// change drops below the low threshold.
+	for j := 1; j <= 21; j++ {
+		tr.Record(result(checker.StatusUp, at(i+j)))
+		if st, _ = tr.Get("api"); !st.Flapping {
+			break
+		}
+	}
+	if st.Flapping || st.StateChange >= 25 || !st.FlappingSince.IsZero() {
+		t.Errorf("expected flapping to stop after steady checks, got %+v", st)
+	}
+}
+
+func TestTracker_FlappingDisabled(t *testing.T) {
+	tr := state.New()
+	tr.SetFlapping(config.FlappingConfig{Disabled: true, Window: 21, LowThreshold: 25, HighThreshold: 50})
+	t0 := time.Now()
+	for i := 0; i < 40; i++ {
+		status := checker.StatusUp
+		if i%2 == 1 {
+			status = checker.StatusDown
+		}
+		tr.Record(result(status, t0.Add(time.Duration(i)*time.Minute)))
+	}
+	if st, _ := tr.Get("api"); st.Flapping || st.StateChange != 0 {
+		t.Errorf("expected no flap detection when disabled, got %+v", st)
+	}
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Alert delivery states.
+const (
+	AlertPending = "pending"
+	AlertSent    = "sent"
+	AlertFailed  = "failed"
+)
+
+// AlertDelivery is one alert queued for one channel in the outbox.
+type AlertDelivery struct {
+	ID            int64      `json:"id"`
+	Service       string     `json:"service"`
+	Channel       string     `json:"channel"`
+	Payload       string     `json:"-"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at"`
+}
+
+const alertColumns = `id, service, channel, payload, status, attempts, last_error, created_at, next_attempt_at, sent_at`
+
+// EnqueueAlert adds a pending delivery, due immediately, and returns it with
+// its ID set.
+func (d *DB) EnqueueAlert(ctx context.Context, a AlertDelivery) (AlertDelivery, error) {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+	a.Status = AlertPending
+	a.NextAttemptAt = a.CreatedAt
+	err := d.db.QueryRowContext(ctx,
+		`INSERT INTO alert_outbox (service, channel, payload, status, created_at, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		a.Service, a.Channel, a.Payload, a.Status, formatTime(a.CreatedAt), formatTime(a.NextAttemptAt),
+	).Scan(&a.ID)
+	if err != nil {
+		return a, fmt.Errorf("enqueueing alert: %w", err)
+	}
+	return a, nil
+}
+
+// ClaimDueAlerts claims up to limit pending deliveries whose next attempt
+// is at or before now, oldest first, by moving their next attempt to the
+// end of the lease. Instances sharing a database therefore never claim the
+// same delivery at once, and one that stops mid-attempt leaves it due again
+// once the lease ends. The deliveries are returned as claimed.
+func (d *DB) ClaimDueAlerts(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]AlertDelivery, error) {
+	due := `SELECT id FROM alert_outbox WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`
+	if d.db.dialect == dialectPostgres {
+		due += ` FOR UPDATE SKIP LOCKED`
+	}
+	alerts, err := d.queryAlerts(ctx,
+		`UPDATE alert_outbox SET next_attempt_at = ? WHERE id IN (`+due+`) RETURNING `+alertColumns,
+		formatTime(now.Add(lease)), AlertPending, formatTime(now), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(alerts, func(a, b AlertDelivery) int { return cmp.Compare(a.ID, b.ID) })
+	return alerts, nil
+}
+
+// ListAlerts returns the most recent deliveries, newest first. An empty status
+// returns deliveries in every state.
+func (d *DB) ListAlerts(ctx context.Context, status string, limit int) ([]AlertDelivery, error) {
+	query := `SELECT ` + alertColumns + ` FROM alert_outbox`
+	var args []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	return d.queryAlerts(ctx, query, append(args, limit)...)
+}
+
+// MarkAlertSent records a successful delivery attempt.
+func (d *DB) MarkAlertSent(ctx context.Context, id int64, attempts int, at time.Time) error {
+	res, err := d.db.ExecContext(ctx,
+		`UPDATE alert_outbox SET status = ?, attempts = ?, last_error = '', sent_at = ? WHERE id = ?`,
+		AlertSent, attempts, formatTime(at), id,
+	)
+	if err != nil {
+		return fmt.Errorf("marking alert %d sent: %w", id, err)
+	}
+	return requireAffected(res, fmt.Sprintf("marking alert %d sent", id))
+}
+
+// MarkAlertFailed records a failed attempt. With a zero next the delivery is
+// given up on; otherwise it stays pending until next.
+func (d *DB) MarkAlertFailed(ctx context.Context, id int64, attempts int, lastErr string, next time.Time) error {
+	status, nextAt := AlertFailed, sql.NullString{}
+	if !next.IsZero() {
+		status = AlertPending
+		nextAt = sql.NullString{String: formatTime(next), Valid: true}
+	}
+	res, err := d.db.ExecContext(ctx,
+		`UPDATE alert_outbox SET status = ?, attempts = ?, last_error = ?, next_attempt_at = COALESCE(?, next_attempt_at) WHERE id = ?`,
+		status, attempts, lastErr, nextAt, id,
+	)
+	if err != nil {
+		return fmt.Errorf("marking alert %d failed: %w", id, err)
+	}
+	return requireAffected(res, fmt.Sprintf("marking alert %d failed", id))
+}
+
+func (d *DB) queryAlerts(ctx context.Context, query string, args ...interface{}) ([]AlertDelivery, error) {
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []AlertDelivery{}
+	for rows.Next() {
+		var (
+			a                 AlertDelivery
+			createdAt, nextAt string
+			sentAt            sql.NullString
+		)
+		if err := rows.Scan(&a.ID, &a.Service, &a.Channel, &a.Payload, &a.Status, &a.Attempts, &a.LastError, &createdAt, &nextAt, &sentAt); err != nil {
+			return nil, fmt.Errorf("scanning alert row: %w", err)
+		}
+		if a.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, fmt.Errorf("parsing created_at %q: %w", createdAt, err)
+		}
+		if a.NextAttemptAt, err = parseTime(nextAt); err != nil {
+			return nil, fmt.Errorf("parsing next_attempt_at %q: %w", nextAt, err)
+		}
+		if sentAt.Valid {
+			t, err := parseTime(sentAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("parsing sent_at %q: %w", sentAt.String, err)
+			}
+			a.SentAt = &t
+		}
+		alerts = append(alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating alert rows: %w", err)
+	}
+	return alerts, nil
+}
@@ -0,0 +1,143 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func TestAlerts_Lifecycle(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	a, err := db.EnqueueAlert(ctx, storage.AlertDelivery{Service: "api", Channel: "ops", Payload: `{"service":"api"}`, CreatedAt: now})
+	if err != nil {
+		t.Fatalf("EnqueueAlert: %v", err)
+	}
+	if a.ID == 0 || a.Status != storage.AlertPending {
+		t.Fatalf("unexpected delivery: %+v", a)
+	}
+
+	due, err := db.ClaimDueAlerts(ctx, now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimDueAlerts: %v", err)
+	}
+	if len(due) != 1 || due[0].Payload != `{"service":"api"}` || due[0].Channel != "ops" {
+		t.Fatalf("expected the new delivery to be due, got %+v", due)
+	}
+
+	// A failed attempt with a retry time stays pending but is not due yet.
+	retryAt := now.Add(time.Minute)
+	if err := db.MarkAlertFailed(ctx, a.ID, 1, "timeout", retryAt); err != nil {
+		t.Fatalf("MarkAlertFailed: %v", err)
+	}
+	if due, _ := db.ClaimDueAlerts(ctx, now, time.Minute, 10); len(due) != 0 {
+		t.Errorf("expected nothing due before the retry time, got %+v", due)
+	}
+	due, _ = db.ClaimDueAlerts(ctx, retryAt, time.Minute, 10)
+	if len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "timeout" || due[0].Status != storage.AlertPending {
+		t.Fatalf("expected delivery due at retry time, got %+v", due)
+	}
+
+	if err := db.MarkAlertSent(ctx, a.ID, 2, retryAt); err != nil {
+		t.Fatalf("MarkAlertSent: %v", err)
+	}
+	sent, err := db.ListAlerts(ctx, storage.AlertSent, 10)
+	if err != nil {
+		t.Fatalf("ListAlerts: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Attempts != 2 || sent[0].LastError != "" || sent[0].SentAt == nil || !sent[0].SentAt.Equal(retryAt) {
+		t.Errorf("unexpected sent delivery: %+v", sent)
+	}
+	if due, _ := db.ClaimDueAlerts(ctx, retryAt.Add(time.Hour), time.Minute, 10); len(due) != 0 {
+		t.Errorf("sent deliveries should not be due, got %+v", due)
+	}
+}
+
+func TestAlerts_GiveUp(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	a, _ := db.EnqueueAlert(ctx, storage.AlertDelivery{Service: "api", Channel: "ops", Payload: "{}", CreatedAt: now})
+	if err := db.MarkAlertFailed(ctx, a.ID, 10, "connection refused", time.Time{}); err != nil {
+		t.Fatalf("MarkAlertFailed: %v", err)
+	}
+	if due, _ := db.ClaimDueAlerts(ctx, now.Add(time.Hour), time.Minute, 10); len(due) != 0 {
+		t.Errorf("failed deliveries should not be due, got %+v", due)
+	}
+	failed, _ := db.ListAlerts(ctx, storage.AlertFailed, 10)
+	if len(failed) != 1 || failed[0].Attempts != 10 || failed[0].SentAt != nil {
+		t.Errorf("unexpected failed delivery: %+v", failed)
+	}
+
+	if err := db.MarkAlertSent(ctx, 999, 1, now); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for unknown id, got %v", err)
+	}
+}
+
+func TestAlerts_ListOrderAndLimit(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for i, svc := range []string{"a", "b", "c"} {
+		if _, err := db.EnqueueAlert(ctx, storage.AlertDelivery{Service: svc, Channel: "ops", Payload: "{}", CreatedAt: now.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := db.ListAlerts(ctx, "", 2)
+	if err != nil {
+		t.Fatalf("ListAlerts: %v", err)
+	}
+	if len(all) != 2 || all[0].Service != "c" || all[1].Service != "b" {
+		t.Errorf("expected newest two deliveries, got %+v", all)
+	}
+	due, _ := db.ClaimDueAlerts(ctx, now.Add(time.Minute), time.Minute, 10)
+	if len(due) != 3 || due[0].Service != "a" {
+		t.Errorf("expected oldest delivery first, got %+v", due)
+	}
+}
+
+func TestAlerts_ClaimLease(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for i := range 20 {
+		if _, err := db.EnqueueAlert(ctx, storage.AlertDelivery{Service: "api", Channel: "ops", Payload: "{}", CreatedAt: now.Add(time.Duration(i) * time.Millisecond)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A claimed delivery isn't due to anyone else, such as another instance
+	// sharing the database.
+	claimed := map[int64]bool{}
+	for range 3 {
+		due, err := db.ClaimDueAlerts(ctx, now.Add(time.Second), time.Minute, 10)
+		if err != nil {
+			t.Fatalf("ClaimDueAlerts: %v", err)
+		}
+		for _, a := range due {
+			if claimed[a.ID] {
+				t.Errorf("delivery %d claimed twice", a.ID)
+			}
+			claimed[a.ID] = true
+		}
+	}
+	if len(claimed) != 20 {
+		t.Errorf("expected all 20 deliveries claimed, got %d", len(claimed))
+	}
+
+	// Unmarked claims are due again once the lease ends.
+	if due, _ := db.ClaimDueAlerts(ctx, now.Add(30*time.Second), time.Minute, 50); len(due) != 0 {
+		t.Errorf("expected nothing due during the lease, got %d", len(due))
+	}
+	if due, _ := db.ClaimDueAlerts(ctx, now.Add(2*time.Minute), time.Minute, 50); len(due) != 20 {
+		t.Errorf("expected all 20 due after the lease, got %d", len(due))
+	}
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// dialect is the SQL flavour of a database, named after its storage driver.
+type dialect string
+
+const (
+	dialectSQLite   dialect = config.DriverSQLite
+	dialectPostgres dialect = config.DriverPostgres
+)
+
+// rebind rewrites the ? placeholders that queries are written with into the
+// dialect's own. Question marks inside string literals are left alone.
+func (dl dialect) rebind(query string) string {
+	if dl != dialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var (
+		b      strings.Builder
+		n      int
+		quoted bool
+	)
+	b.Grow(len(query) + 8)
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(query[i])
+	}
+	return b.String()
+}
+
+// unixSeconds returns an expression for the Unix time, in whole seconds, of
+// the timestamp column col.
+func (dl dialect) unixSeconds(col string) string {
+	if dl == dialectPostgres {
+		return `CAST(EXTRACT(EPOCH FROM CAST(SUBSTR(` + col + `, 1, 19) || 'Z' AS TIMESTAMPTZ)) AS BIGINT)`
+	}
+	return `CAST(strftime('%s', ` + col + `) AS INTEGER)`
+}
+
+// epochSeconds returns an expression for the Unix time, with its fraction
+// of a second, of the timestamp column col.
+func (dl dialect) epochSeconds(col string) string {
+	return dl.unixSeconds(col) + ` + CAST(SUBSTR(` + col + `, 20, LENGTH(` + col + `) - 20) AS DOUBLE PRECISION)`
+}
+
+// dbConn is a connection pool that takes queries written for SQLite, with ?
+// placeholders, and rebinds them for its dialect. The rest of the package
+// writes SQL that both dialects accept.
+type dbConn struct {
+	*sql.DB
+	dialect dialect
+}
+
+func (c *dbConn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.DB.ExecContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c *dbConn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.DB.QueryContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c *dbConn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.DB.QueryRowContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c *dbConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*dbTx, error) {
+	tx, err := c.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &dbTx{Tx: tx, dialect: c.dialect}, nil
+}
+
+// dbTx is a transaction on a dbConn.
+type dbTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (t *dbTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.dialect.rebind(query), args...)
+}
+
+func (t *dbTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, t.dialect.rebind(query), args...)
+}
+
+func (t *dbTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, t.dialect.rebind(query), args...)
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExportQuery selects the checks to export. An empty Service exports every
+// service; a zero From or To leaves that end open.
+type ExportQuery struct {
+	Service string
+	From    time.Time
+	To      time.Time
+}
+
+// ExportChecks calls fn with each check q selects, oldest first, without
+// loading them all at once. It stops at the first error fn returns.
+func (d *DB) ExportChecks(ctx context.Context, q ExportQuery, fn func(Check) error) error {
+	var (
+		where []string
+		args  []any
+	)
+	if q.Service != "" {
+		where = append(where, "service = ?")
+		args = append(args, q.Service)
+	}
+	if !q.From.IsZero() {
+		where = append(where, "checked_at >= ?")
+		args = append(args, formatTime(q.From))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "checked_at < ?")
+		args = append(args, formatTime(q.To))
+	}
+	query := `SELECT id, service, status, response_ms, error, checked_at, maintenance FROM checks`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY checked_at, id`
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("querying checks to export: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c, err := scanCheck(rows)
+		if err != nil {
+			return fmt.Errorf("scanning check row: %w", err)
+		}
+		if err := fn(*c); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating check rows: %w", err)
+	}
+	return nil
+}
+
+// ImportChecks stores checks from an export in a single transaction and
+// returns how many were new. A check is skipped if its service already has
+// one at the same time, so importing the same file twice, or overlapping
+// exports of one instance, stores each check once.
+//
+// Imported checks don't open or resolve incidents. Rollups of hours and days
+// that were already rolled up are recomputed to include them; later ones are
+// rolled up by the next compaction as usual.
+func (d *DB) ImportChecks(ctx context.Context, checks []Check) (int, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var inserted []time.Time
+	for _, c := range checks {
+		checkedAt := formatTime(c.CheckedAt)
+		var exists int
+		err := tx.QueryRowContext(ctx,
+			`SELECT 1 FROM checks WHERE service = ? AND checked_at = ?`, c.Service, checkedAt,
+		).Scan(&exists)
+		if err == nil {
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return 0, fmt.Errorf("looking up check of %q at %s: %w", c.Service, checkedAt, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO checks (service, status, response_ms, error, checked_at, maintenance) VALUES (?, ?, ?, ?, ?, ?)`,
+			c.Service, c.Status, c.ResponseMs, c.Error, checkedAt, c.Maintenance,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("inserting check of %q at %s: %w", c.Service, checkedAt, err)
+		}
+		inserted = append(inserted, c.CheckedAt.UTC())
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing %d imported checks: %w", len(inserted), err)
+	}
+
+	for _, res := range []Resolution{Hourly, Daily} {
+		if err := d.rerollup(ctx, res, inserted); err != nil {
+			return len(inserted), err
+		}
+	}
+	return len(inserted), nil
+}
+
+// rerollup recomputes the rollups at res of the buckets holding times, up to
+// the last bucket rolled up so far.
+func (d *DB) rerollup(ctx context.Context, res Resolution, times []time.Time) error {
+	if len(times) == 0 {
+		return nil
+	}
+	var last sql.NullString
+	if err := d.db.QueryRowContext(ctx, `SELECT MAX(bucket) FROM `+res.table()).Scan(&last); err != nil {
+		return fmt.Errorf("finding last rollup in %s: %w", res.table(), err)
+	}
+	if !last.Valid {
+		return nil
+	}
+	lastBucket, err := parseTime(last.String)
+	if err != nil {
+		return fmt.Errorf("parsing bucket %q: %w", last.String, err)
+	}
+
+	done := map[time.Time]bool{}
+	for _, t := range times {
+		bucket := t.Truncate(res.step())
+		if bucket.After(lastBucket) || done[bucket] {
+			continue
+		}
+		done[bucket] = true
+		if err := d.rollupBucket(ctx, res, bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// export returns the checks q selects.
+func export(t *testing.T, db *storage.DB, q storage.ExportQuery) []storage.Check {
+	t.Helper()
+	var checks []storage.Check
+	err := db.ExportChecks(context.Background(), q, func(c storage.Check) error {
+		checks = append(checks, c)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportChecks: %v", err)
+	}
+	return checks
+}
+
+func TestExportChecks(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 6 {
+		for _, svc := range []string{"api", "db"} {
+			r := makeResult(svc, checker.StatusUp, int64(10+i))
+			r.CheckedAt = t0.Add(time.Duration(5-i) * time.Minute)
+			if err := db.InsertCheck(ctx, r); err != nil {
+				t.Fatalf("InsertCheck: %v", err)
+			}
+		}
+	}
+
+	all := export(t, db, storage.ExportQuery{})
+	if len(all) != 12 {
+		t.Fatalf("expected 12 checks, got %d", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].CheckedAt.Before(all[i-1].CheckedAt) {
+			t.Fatalf("expected checks oldest first, got %v after %v", all[i].CheckedAt, all[i-1].CheckedAt)
+		}
+	}
+
+	some := export(t, db, storage.ExportQuery{Service: "api", From: t0.Add(time.Minute), To: t0.Add(4 * time.Minute)})
+	if len(some) != 3 || some[0].Service != "api" || !some[0].CheckedAt.Equal(t0.Add(time.Minute)) || some[0].ResponseMs != 14 {
+		t.Errorf("unexpected filtered export: %+v", some)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err := db.ExportChecks(ctx, storage.ExportQuery{}, func(storage.Check) error {
+		n++
+		return stop
+	})
+	if !errors.Is(err, stop) || n != 1 {
+		t.Errorf("expected export to stop at the first error, got %v after %d checks", err, n)
+	}
+}
+
+func TestImportChecks_Dedupes(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	existing := makeResult("api", checker.StatusUp, 10)
+	existing.CheckedAt = t0
+	if err := db.InsertCheck(ctx, existing); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+
+	checks := []storage.Check{
+		{Service: "api", Status: "up", ResponseMs: 99, CheckedAt: t0},
+		{Service: "api", Status: "down", Error: "timeout", CheckedAt: t0.Add(time.Minute)},
+		{Service: "db", Status: "up", ResponseMs: 3, CheckedAt: t0, Maintenance: true},
+		{Service: "db", Status: "up", ResponseMs: 3, CheckedAt: t0},
+	}
+	n, err := db.ImportChecks(ctx, checks)
+	if err != nil {
+		t.Fatalf("ImportChecks: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 new checks, got %d", n)
+	}
+	if n, err := db.ImportChecks(ctx, checks); err != nil || n != 0 {
+		t.Errorf("expected a second import to add nothing, got %d, %v", n, err)
+	}
+
+	got := export(t, db, storage.ExportQuery{})
+	if len(got) != 3 {
+		t.Fatalf("expected 3 checks, got %+v", got)
+	}
+	if got[0].Service != "api" || got[0].ResponseMs != 10 {
+		t.Errorf("expected the existing check to be kept, got %+v", got[0])
+	}
+	if db := got[1]; db.Service != "db" || !db.Maintenance {
+		t.Errorf("unexpected imported check: %+v", db)
+	}
+	if down := got[2]; down.Status != "down" || down.Error != "timeout" || !down.CheckedAt.Equal(t0.Add(time.Minute)) {
+		t.Errorf("unexpected imported check: %+v", down)
+	}
+
+	// Imports don't touch incidents.
+	incidents, err := db.ListIncidents(ctx, storage.IncidentQuery{Service: "api"})
+	if err != nil {
+		t.Fatalf("ListIncidents: %v", err)
+	}
+	if len(incidents) != 0 {
+		t.Errorf("expected no incidents, got %+v", incidents)
+	}
+}
+
+func TestImportChecks_OlderThanLatest(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// api has been down since 00:10.
+	for i := range 2 {
+		r := makeResult("api", checker.StatusDown, 10)
+		r.CheckedAt = t0.Add(time.Duration(10+i) * time.Minute)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	// Older history imported afterwards gets higher IDs.
+	var older []storage.Check
+	for i := range 5 {
+		older = append(older, storage.Check{Service: "api", Status: "up", ResponseMs: 5, CheckedAt: t0.Add(time.Duration(i) * time.Minute)})
+	}
+	if _, err := db.ImportChecks(ctx, older); err != nil {
+		t.Fatalf("ImportChecks: %v", err)
+	}
+
+	latest, err := db.AllLatest(ctx)
+	if err != nil {
+		t.Fatalf("AllLatest: %v", err)
+	}
+	if len(latest) != 1 || latest[0].Status != "down" || !latest[0].CheckedAt.Equal(t0.Add(11*time.Minute)) {
+		t.Errorf("expected the live check at 00:11 to be the latest, got %+v", latest)
+	}
+
+	runs, err := db.CurrentRuns(ctx)
+	if err != nil {
+		t.Fatalf("CurrentRuns: %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("expected one run, got %+v", runs)
+	}
+	run := runs[0]
+	if run.Latest.Status != "down" || !run.Since.Equal(t0.Add(10*time.Minute)) || run.Count != 2 {
+		t.Errorf("expected a run of 2 down checks since 00:10, got %+v", run)
+	}
+}
+
+func TestImportChecks_RecomputesRollups(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for m := 0; m < 180; m += 30 {
+		r := makeResult("api", checker.StatusUp, 10)
+		r.CheckedAt = t0.Add(time.Duration(m) * time.Minute)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	if err := db.Compact(ctx, t0.Add(3*time.Hour), config.RetentionConfig{}); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	_, err := db.ImportChecks(ctx, []storage.Check{
+		{Service: "api", Status: "up", ResponseMs: 50, CheckedAt: t0.Add(70 * time.Minute)},
+		{Service: "other", Status: "up", ResponseMs: 5, CheckedAt: t0.Add(80 * time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("ImportChecks: %v", err)
+	}
+
+	hourly, err := db.Rollups(ctx, "api", storage.Hourly, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Rollups: %v", err)
+	}
+	if len(hourly) != 3 {
+		t.Fatalf("expected 3 hourly rollups, got %+v", hourly)
+	}
+	if got := hourly[1]; got.Checks != 3 || got.MaxMs != 50 {
+		t.Errorf("expected the imported check in the 01:00 rollup, got %+v", got)
+	}
+	other, err := db.Rollups(ctx, "other", storage.Hourly, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Rollups: %v", err)
+	}
+	if len(other) != 1 || other[0].Checks != 1 {
+		t.Errorf("expected a rollup of the imported service, got %+v", other)
+	}
+}
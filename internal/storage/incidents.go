@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+)
+
+// Incident is an outage of one service: a run of down checks, from the
+// first down check to the first up check after it.
+type Incident struct {
+	ID        int64     `json:"id"`
+	Service   string    `json:"service"`
+	StartedAt time.Time `json:"started_at"`
+	// ResolvedAt is nil while the incident is ongoing.
+	ResolvedAt *time.Time `json:"resolved_at"`
+	FirstError string     `json:"first_error"`
+	// CheckCount is the number of down checks in the incident.
+	CheckCount int `json:"check_count"`
+}
+
+// Duration is how long the incident lasted, or has lasted so far at now if
+// it is ongoing.
+func (i Incident) Duration(now time.Time) time.Duration {
+	if i.ResolvedAt != nil {
+		return i.ResolvedAt.Sub(i.StartedAt)
+	}
+	return now.Sub(i.StartedAt)
+}
+
+// IncidentQuery selects incidents. Zero fields don't restrict the result.
+type IncidentQuery struct {
+	Service string
+	// From and To select incidents that overlap [From, To).
+	From, To time.Time
+	Limit    int
+}
+
+// recordIncident opens, extends or resolves r's service's incident. A down
+// check during maintenance extends an open incident but doesn't open one.
+func recordIncident(ctx context.Context, tx *dbTx, r checker.CheckResult) error {
+	if r.Status == checker.StatusUp {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE incidents SET resolved_at = ? WHERE service = ? AND resolved_at IS NULL`,
+			formatTime(r.CheckedAt), r.ServiceName,
+		)
+		if err != nil {
+			return fmt.Errorf("resolving incident of %q: %w", r.ServiceName, err)
+		}
+		return nil
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE incidents SET check_count = check_count + 1 WHERE service = ? AND resolved_at IS NULL`,
+		r.ServiceName,
+	)
+	if err != nil {
+		return fmt.Errorf("updating incident of %q: %w", r.ServiceName, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 || r.Maintenance {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO incidents (service, started_at, first_error, check_count) VALUES (?, ?, ?, 1)`,
+		r.ServiceName, formatTime(r.CheckedAt), r.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("opening incident of %q: %w", r.ServiceName, err)
+	}
+	return nil
+}
+
+// ListIncidents returns the incidents selected by q, most recent first.
+func (d *DB) ListIncidents(ctx context.Context, q IncidentQuery) ([]Incident, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if q.Service != "" {
+		where = append(where, "service = ?")
+		args = append(args, q.Service)
+	}
+	if !q.From.IsZero() {
+		where = append(where, "(resolved_at IS NULL OR resolved_at > ?)")
+		args = append(args, formatTime(q.From))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "started_at < ?")
+		args = append(args, formatTime(q.To))
+	}
+	query := `SELECT id, service, started_at, resolved_at, first_error, check_count FROM incidents`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []Incident
+	for rows.Next() {
+		var (
+			i          Incident
+			startedAt  string
+			resolvedAt sql.NullString
+		)
+		if err := rows.Scan(&i.ID, &i.Service, &startedAt, &resolvedAt, &i.FirstError, &i.CheckCount); err != nil {
+			return nil, fmt.Errorf("scanning incident row: %w", err)
+		}
+		if i.StartedAt, err = parseTime(startedAt); err != nil {
+			return nil, fmt.Errorf("parsing started_at %q: %w", startedAt, err)
+		}
+		if resolvedAt.Valid {
+			t, err := parseTime(resolvedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("parsing resolved_at %q: %w", resolvedAt.String, err)
+			}
+			i.ResolvedAt = &t
+		}
+		incidents = append(incidents, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating incident rows: %w", err)
+	}
+	return incidents, nil
+}
@@ -0,0 +1,75 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func TestIncidents_DerivedFromChecks(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)
+
+	insert := func(service string, status checker.Status, minute int, errMsg string, maintenance bool) {
+		t.Helper()
+		r := makeResult(service, status, 10)
+		r.CheckedAt = t0.Add(time.Duration(minute) * time.Minute)
+		r.Error = errMsg
+		r.Maintenance = maintenance
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+
+	insert("payments", checker.StatusUp, 0, "", false)
+	insert("payments", checker.StatusDown, 1, "connection refused", false)
+	insert("payments", checker.StatusDown, 2, "timeout", false)
+	insert("payments", checker.StatusDown, 3, "timeout", true)
+	insert("payments", checker.StatusUp, 4, "", false)
+	// Down only during maintenance: no incident.
+	insert("payments", checker.StatusDown, 10, "planned", true)
+	insert("payments", checker.StatusUp, 11, "", false)
+	// Ongoing incident of another service.
+	insert("search", checker.StatusDown, 20, "503", false)
+
+	all, err := db.ListIncidents(ctx, storage.IncidentQuery{})
+	if err != nil {
+		t.Fatalf("ListIncidents: %v", err)
+	}
+	if len(all) != 2 || all[0].Service != "search" || all[1].Service != "payments" {
+		t.Fatalf("expected search then payments incidents, got %+v", all)
+	}
+
+	p := all[1]
+	if !p.StartedAt.Equal(t0.Add(time.Minute)) || p.ResolvedAt == nil || !p.ResolvedAt.Equal(t0.Add(4*time.Minute)) {
+		t.Errorf("unexpected payments incident times: %+v", p)
+	}
+	if p.FirstError != "connection refused" || p.CheckCount != 3 || p.Duration(time.Now()) != 3*time.Minute {
+		t.Errorf("unexpected payments incident: %+v", p)
+	}
+	if s := all[0]; s.ResolvedAt != nil || s.CheckCount != 1 {
+		t.Errorf("expected ongoing search incident, got %+v", s)
+	}
+
+	// Filtering by service and by a window that overlaps the incident.
+	got, _ := db.ListIncidents(ctx, storage.IncidentQuery{Service: "payments", From: t0.Add(2 * time.Minute), To: t0.Add(3 * time.Minute)})
+	if len(got) != 1 || got[0].ID != p.ID {
+		t.Errorf("expected the overlapping payments incident, got %+v", got)
+	}
+	got, _ = db.ListIncidents(ctx, storage.IncidentQuery{Service: "payments", From: t0.Add(5 * time.Minute)})
+	if len(got) != 0 {
+		t.Errorf("expected no payments incident after it resolved, got %+v", got)
+	}
+	got, _ = db.ListIncidents(ctx, storage.IncidentQuery{From: t0.Add(time.Hour)})
+	if len(got) != 1 || got[0].Service != "search" {
+		t.Errorf("expected the ongoing incident to overlap any later window, got %+v", got)
+	}
+	got, _ = db.ListIncidents(ctx, storage.IncidentQuery{Limit: 1})
+	if len(got) != 1 {
+		t.Errorf("expected limit to apply, got %d incidents", len(got))
+	}
+}
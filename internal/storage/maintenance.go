@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MaintenanceWindow is an ad-hoc maintenance window created at runtime.
+type MaintenanceWindow struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Services  []string  `json:"services"`
+	Tags      []string  `json:"tags"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InsertMaintenance persists a maintenance window and returns it with its ID set.
+func (d *DB) InsertMaintenance(ctx context.Context, w MaintenanceWindow) (MaintenanceWindow, error) {
+	if w.Services == nil {
+		w.Services = []string{}
+	}
+	if w.Tags == nil {
+		w.Tags = []string{}
+	}
+	services, err := json.Marshal(w.Services)
+	if err != nil {
+		return w, fmt.Errorf("encoding services: %w", err)
+	}
+	tags, err := json.Marshal(w.Tags)
+	if err != nil {
+		return w, fmt.Errorf("encoding tags: %w", err)
+	}
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+
+	err = d.db.QueryRowContext(ctx,
+		`INSERT INTO maintenance_windows (name, services, tags, starts_at, ends_at, created_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		w.Name, string(services), string(tags), formatTime(w.StartsAt), formatTime(w.EndsAt), formatTime(w.CreatedAt),
+	).Scan(&w.ID)
+	if err != nil {
+		return w, fmt.Errorf("inserting maintenance window: %w", err)
+	}
+	return w, nil
+}
+
+// ListMaintenance returns maintenance windows that end after since, ordered by start.
+func (d *DB) ListMaintenance(ctx context.Context, since time.Time) ([]MaintenanceWindow, error) {
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT id, name, services, tags, starts_at, ends_at, created_at FROM maintenance_windows WHERE ends_at > ? ORDER BY starts_at`,
+		formatTime(since),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []MaintenanceWindow
+	for rows.Next() {
+		w, err := scanMaintenance(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning maintenance row: %w", err)
+		}
+		windows = append(windows, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating maintenance rows: %w", err)
+	}
+	return windows, nil
+}
+
+// DeleteMaintenance removes a maintenance window. It returns sql.ErrNoRows if
+// no window has the given ID.
+func (d *DB) DeleteMaintenance(ctx context.Context, id int64) error {
+	res, err := d.db.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("deleting maintenance window %d: %w", id, err)
+	}
+	return requireAffected(res, fmt.Sprintf("deleting maintenance window %d", id))
+}
+
+func scanMaintenance(row scanner) (*MaintenanceWindow, error) {
+	var w MaintenanceWindow
+	var services, tags, startsAt, endsAt, createdAt string
+	if err := row.Scan(&w.ID, &w.Name, &services, &tags, &startsAt, &endsAt, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(services), &w.Services); err != nil {
+		return nil, fmt.Errorf("decoding services: %w", err)
+	}
+	if err := json.Unmarshal([]byte(tags), &w.Tags); err != nil {
+		return nil, fmt.Errorf("decoding tags: %w", err)
+	}
+	var err error
+	if w.StartsAt, err = parseTime(startsAt); err != nil {
+		return nil, fmt.Errorf("parsing starts_at %q: %w", startsAt, err)
+	}
+	if w.EndsAt, err = parseTime(endsAt); err != nil {
+		return nil, fmt.Errorf("parsing ends_at %q: %w", endsAt, err)
+	}
+	if w.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, fmt.Errorf("parsing created_at %q: %w", createdAt, err)
+	}
+	return &w, nil
+}
@@ -0,0 +1,64 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func TestMaintenance_InsertListDelete(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	w, err := db.InsertMaintenance(ctx, storage.MaintenanceWindow{
+		Name:     "deploy",
+		Services: []string{"api"},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("InsertMaintenance: %v", err)
+	}
+	if w.ID == 0 {
+		t.Error("expected ID to be set")
+	}
+
+	// A window that has already ended is not listed.
+	if _, err := db.InsertMaintenance(ctx, storage.MaintenanceWindow{
+		Name:     "old",
+		StartsAt: now.Add(-2 * time.Hour),
+		EndsAt:   now.Add(-time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	windows, err := db.ListMaintenance(ctx, now)
+	if err != nil {
+		t.Fatalf("ListMaintenance: %v", err)
+	}
+	if len(windows) != 1 {
+		t.Fatalf("expected 1 window, got %d", len(windows))
+	}
+	got := windows[0]
+	if got.Name != "deploy" || len(got.Services) != 1 || got.Services[0] != "api" {
+		t.Errorf("unexpected window: %+v", got)
+	}
+	if got.Tags == nil {
+		t.Error("expected empty tags, got nil")
+	}
+	if !got.EndsAt.Equal(w.EndsAt) {
+		t.Errorf("expected ends_at %v, got %v", w.EndsAt, got.EndsAt)
+	}
+
+	if err := db.DeleteMaintenance(ctx, w.ID); err != nil {
+		t.Fatalf("DeleteMaintenance: %v", err)
+	}
+	if err := db.DeleteMaintenance(ctx, w.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows deleting twice, got %v", err)
+	}
+}
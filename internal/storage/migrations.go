@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// Migration is a schema change known to this version of servprobe.
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// MigrationPlan is a database's schema version and the migrations it lacks.
+type MigrationPlan struct {
+	// Current is 0 for a new database or one created before versioning.
+	Current int
+	Pending []Migration
+}
+
+// migration is one step of the schema. Migrations are applied in version
+// order, each in a transaction together with its schema_version row, so a
+// failed migration leaves the database at the previous version.
+//
+// Each dialect has its own step. PostgreSQL support arrived with version 4,
+// so its baseline already has the changes of versions 2 to 4 and those steps
+// are nil for it; every later migration needs both, unless it fixes data
+// PostgreSQL databases never had.
+type migration struct {
+	Migration
+	sqlite, postgres func(ctx context.Context, tx *dbTx) error
+}
+
+// migrations must only ever be appended to.
+var migrations = []migration{
+	{Migration{1, "baseline schema"}, func(ctx context.Context, tx *dbTx) error {
+		if _, err := tx.ExecContext(ctx, schema); err != nil {
+			return err
+		}
+		for _, c := range columns {
+			if err := addColumnIfMissing(ctx, tx, c.table, c.name, c.def); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, execMigration(postgresSchema)},
+	{Migration{2, "drop index on checks.service covered by (service, checked_at)"}, execMigration(
+		`DROP INDEX IF EXISTS idx_checks_service`,
+	), nil},
+	// Rollups made before uptime was weighted by duration assume their
+	// checks were spread evenly over the bucket.
+	{Migration{3, "add uptime durations to rollups"}, execMigration(`
+		ALTER TABLE check_rollups_hourly ADD COLUMN up_seconds REAL NOT NULL DEFAULT 0;
+		ALTER TABLE check_rollups_hourly ADD COLUMN known_seconds REAL NOT NULL DEFAULT 0;
+		UPDATE check_rollups_hourly SET known_seconds = 3600, up_seconds = 3600.0 * up / checks;
+		ALTER TABLE check_rollups_daily ADD COLUMN up_seconds REAL NOT NULL DEFAULT 0;
+		ALTER TABLE check_rollups_daily ADD COLUMN known_seconds REAL NOT NULL DEFAULT 0;
+		UPDATE check_rollups_daily SET known_seconds = 86400, up_seconds = 86400.0 * up / checks;
+	`), nil},
+	{Migration{4, "add SLA targets to services"}, execMigration(`
+		ALTER TABLE services ADD COLUMN sla_target REAL NOT NULL DEFAULT 0;
+		ALTER TABLE services ADD COLUMN sla_window TEXT NOT NULL DEFAULT '';
+	`), nil},
+	// The first releases stored check times as RFC 3339 with trailing zeros
+	// of the fraction trimmed, which doesn't sort with the fixed-width
+	// format. PostgreSQL databases only ever had the fixed-width one.
+	{Migration{5, "rewrite check times in fixed-width format"}, fixedWidthCheckTimes, nil},
+}
+
+// fixedWidthCheckTimes rewrites the checked_at values of checks that aren't
+// in timeFormat, a batch at a time.
+func fixedWidthCheckTimes(ctx context.Context, tx *dbTx) error {
+	const batch = 1000
+	var lastID int64
+	for {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id, checked_at FROM checks WHERE id > ? AND checked_at NOT LIKE '____-__-__T__:__:__._________Z' ORDER BY id LIMIT ?`,
+			lastID, batch,
+		)
+		if err != nil {
+			return err
+		}
+		type row struct {
+			id        int64
+			checkedAt string
+		}
+		var old []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.id, &r.checkedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			old = append(old, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, r := range old {
+			t, err := parseTime(r.checkedAt)
+			if err != nil {
+				return fmt.Errorf("parsing checked_at %q of check %d: %w", r.checkedAt, r.id, err)
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE checks SET checked_at = ? WHERE id = ?`, formatTime(t), r.id); err != nil {
+				return err
+			}
+			lastID = r.id
+		}
+		if len(old) < batch {
+			return nil
+		}
+	}
+}
+
+func execMigration(stmts string) func(ctx context.Context, tx *dbTx) error {
+	return func(ctx context.Context, tx *dbTx) error {
+		_, err := tx.ExecContext(ctx, stmts)
+		return err
+	}
+}
+
+// Migrate opens the database cfg selects and applies pending migrations, or
+// with dryRun only reports them. The returned plan is the state before
+// migrating.
+func Migrate(ctx context.Context, cfg config.StorageConfig, dryRun bool) (MigrationPlan, error) {
+	db, err := openConn(cfg)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	defer db.Close()
+	return migrate(ctx, db, dryRun)
+}
+
+func migrate(ctx context.Context, db *dbConn, dryRun bool) (MigrationPlan, error) {
+	current, err := schemaVersion(ctx, db)
+	if err != nil {
+		return MigrationPlan{}, err
+	}
+	latest := migrations[len(migrations)-1].Version
+	if current > latest {
+		return MigrationPlan{}, fmt.Errorf("database schema version %d is newer than this servprobe supports (%d)", current, latest)
+	}
+
+	plan := MigrationPlan{Current: current}
+	for _, m := range migrations {
+		if m.Version > current {
+			plan.Pending = append(plan.Pending, m.Migration)
+		}
+	}
+	if dryRun {
+		return plan, nil
+	}
+	for _, m := range migrations[current:] {
+		if err := applyMigration(ctx, db, m); err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+// migrationLockKey is the PostgreSQL advisory lock that serializes
+// migrations, spelling "srvp".
+const migrationLockKey = 0x73727670
+
+// applyMigration applies m unless another instance sharing the database
+// applied it first. On PostgreSQL an advisory lock makes instances take
+// turns; on SQLite only one transaction writes at a time, so a migration
+// racing another fails rather than being applied twice.
+func applyMigration(ctx context.Context, db *dbConn, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning migration %d: %w", m.Version, err)
+	}
+	defer tx.Rollback()
+
+	if db.dialect == dialectPostgres {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(?)`, migrationLockKey); err != nil {
+			return fmt.Errorf("locking schema for migration %d: %w", m.Version, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_version (
+		    version    INTEGER PRIMARY KEY,
+		    name       TEXT    NOT NULL,
+		    applied_at TEXT    NOT NULL
+		)`)
+	if err != nil {
+		return fmt.Errorf("creating schema_version table: %w", err)
+	}
+	var current int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&current); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if current >= m.Version {
+		return nil
+	}
+	up := m.sqlite
+	if db.dialect == dialectPostgres {
+		up = m.postgres
+	}
+	if up != nil {
+		if err := up(ctx, tx); err != nil {
+			return fmt.Errorf("applying migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, formatTime(time.Now()),
+	)
+	if err != nil {
+		return fmt.Errorf("recording migration %d: %w", m.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// schemaVersion returns the latest migration applied to db, without creating
+// the schema_version table.
+func schemaVersion(ctx context.Context, db *dbConn) (int, error) {
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`
+	if db.dialect == dialectPostgres {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_version'`
+	}
+	var tables int
+	err := db.QueryRowContext(ctx, query).Scan(&tables)
+	if err != nil {
+		return 0, fmt.Errorf("looking up schema_version table: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+	var version int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, nil
+}
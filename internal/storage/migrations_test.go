@@ -0,0 +1,189 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+// fixtureDB creates a database file from SQL statements and returns its path.
+func fixtureDB(t *testing.T, stmts string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "servprobe.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("opening fixture: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(stmts); err != nil {
+		t.Fatalf("loading fixture: %v", err)
+	}
+	return path
+}
+
+func indexExists(t *testing.T, path, name string) bool {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	defer db.Close()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?`, name).Scan(&n); err != nil {
+		t.Fatalf("looking up index: %v", err)
+	}
+	return n > 0
+}
+
+func TestMigrate_UpgradesUnversionedDatabase(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/unversioned.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fixtureDB(t, string(fixture))
+	ctx := context.Background()
+
+	plan, err := storage.Migrate(ctx, config.StorageConfig{Path: path}, true)
+	if err != nil {
+		t.Fatalf("Migrate dry run: %v", err)
+	}
+	if plan.Current != 0 || len(plan.Pending) < 2 || plan.Pending[0].Version != 1 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if !indexExists(t, path, "idx_checks_service") {
+		t.Fatal("dry run changed the database")
+	}
+
+	db, err := storage.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	history, err := db.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Limit: 10})
+	if err != nil {
+		t.Fatalf("ServiceHistory: %v", err)
+	}
+	if history.Total != 3 || history.Checks[1].Error != "timeout" {
+		t.Errorf("expected the fixture's checks to survive, got %d: %+v", history.Total, history.Checks)
+	}
+	incidents, err := db.ListIncidents(ctx, storage.IncidentQuery{Service: "api"})
+	if err != nil || len(incidents) != 1 || incidents[0].ResolvedAt == nil {
+		t.Errorf("expected the fixture's incident to survive, got %+v (%v)", incidents, err)
+	}
+	if err := db.InsertCheck(ctx, makeResult("api", checker.StatusUp, 41)); err != nil {
+		t.Errorf("InsertCheck after upgrade: %v", err)
+	}
+	db.Close()
+
+	if indexExists(t, path, "idx_checks_service") {
+		t.Error("expected migration 2 to drop idx_checks_service")
+	}
+	plan, err = storage.Migrate(ctx, config.StorageConfig{Path: path}, false)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if plan.Current < 2 || len(plan.Pending) != 0 {
+		t.Errorf("expected an up-to-date database, got %+v", plan)
+	}
+}
+
+func TestMigrate_AddsColumnsToOldestSchema(t *testing.T) {
+	// The first release had no maintenance column.
+	path := fixtureDB(t, `
+		CREATE TABLE checks (
+		    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		    service     TEXT    NOT NULL,
+		    status      TEXT    NOT NULL CHECK(status IN ('up', 'down')),
+		    response_ms INTEGER NOT NULL,
+		    error       TEXT    NOT NULL DEFAULT '',
+		    checked_at  TEXT    NOT NULL
+		);
+		INSERT INTO checks (service, status, response_ms, checked_at)
+		VALUES ('api', 'up', 42, '2026-03-01T10:00:00.000000000Z');
+	`)
+
+	db, err := storage.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	latest, err := db.LatestCheck(context.Background(), "api")
+	if err != nil || latest == nil || latest.Maintenance {
+		t.Errorf("expected the old check without maintenance, got %+v (%v)", latest, err)
+	}
+}
+
+func TestMigrate_RejectsNewerSchema(t *testing.T) {
+	path := fixtureDB(t, `
+		CREATE TABLE schema_version (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TEXT NOT NULL);
+		INSERT INTO schema_version VALUES (999, 'from the future', '2030-01-01T00:00:00Z');
+	`)
+	_, err := storage.Open(path)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected an error about a newer schema, got %v", err)
+	}
+}
+
+func TestMigrate_RewritesCheckTimes(t *testing.T) {
+	// The first release trimmed trailing zeros of the fraction, so "10:00:01Z"
+	// sorted after the later "10:00:01.250000000Z".
+	path := fixtureDB(t, `
+		CREATE TABLE checks (
+		    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		    service     TEXT    NOT NULL,
+		    status      TEXT    NOT NULL CHECK(status IN ('up', 'down')),
+		    response_ms INTEGER NOT NULL,
+		    error       TEXT    NOT NULL DEFAULT '',
+		    checked_at  TEXT    NOT NULL
+		);
+		INSERT INTO checks (service, status, response_ms, checked_at) VALUES
+		    ('api', 'up', 1, '2026-03-01T10:00:01Z'),
+		    ('api', 'up', 2, '2026-03-01T10:00:00.5Z'),
+		    ('api', 'up', 3, '2026-03-01T10:00:02.123456789Z');
+	`)
+
+	db, err := storage.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	ctx := context.Background()
+	if err := db.InsertCheck(ctx, checker.CheckResult{
+		ServiceName: "api", Status: checker.StatusUp, ResponseTime: 4 * time.Millisecond,
+		CheckedAt: time.Date(2026, 3, 1, 10, 0, 1, 250_000_000, time.UTC),
+	}); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+	page, err := db.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Limit: 10})
+	if err != nil {
+		t.Fatalf("ServiceHistory: %v", err)
+	}
+	var order []int64
+	for _, c := range page.Checks {
+		order = append(order, c.ResponseMs)
+	}
+	if want := []int64{3, 4, 1, 2}; !slices.Equal(order, want) {
+		t.Errorf("expected checks newest first %v, got %v", want, order)
+	}
+	db.Close()
+
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	var checkedAt string
+	if err := raw.QueryRow(`SELECT checked_at FROM checks WHERE response_ms = 1`).Scan(&checkedAt); err != nil {
+		t.Fatal(err)
+	}
+	if checkedAt != "2026-03-01T10:00:01.000000000Z" {
+		t.Errorf("expected a fixed-width time, got %q", checkedAt)
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AlertOutage is the reminder and escalation state of a service that is
+// down, kept so restarts don't reset the escalation clock.
+type AlertOutage struct {
+	Service        string
+	StartedAt      time.Time
+	Error          string
+	LastNotifiedAt time.Time
+	// EscalationStep is the number of escalation steps already taken.
+	EscalationStep int
+	// Escalated lists the channels notified by those steps.
+	Escalated []string
+}
+
+// SaveOutage inserts or replaces the outage state of o.Service.
+func (d *DB) SaveOutage(ctx context.Context, o AlertOutage) error {
+	if o.Escalated == nil {
+		o.Escalated = []string{}
+	}
+	escalated, err := json.Marshal(o.Escalated)
+	if err != nil {
+		return fmt.Errorf("encoding escalated channels: %w", err)
+	}
+	_, err = d.db.ExecContext(ctx, `
+		INSERT INTO alert_outages (service, started_at, error, last_notified_at, escalation_step, escalated)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(service) DO UPDATE SET
+			started_at = excluded.started_at,
+			error = excluded.error,
+			last_notified_at = excluded.last_notified_at,
+			escalation_step = excluded.escalation_step,
+			escalated = excluded.escalated`,
+		o.Service, formatTime(o.StartedAt), o.Error, formatTime(o.LastNotifiedAt), o.EscalationStep, string(escalated),
+	)
+	if err != nil {
+		return fmt.Errorf("saving outage of %q: %w", o.Service, err)
+	}
+	return nil
+}
+
+// DeleteOutage removes the outage state of service. Deleting a service with
+// no outage does nothing.
+func (d *DB) DeleteOutage(ctx context.Context, service string) error {
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM alert_outages WHERE service = ?`, service); err != nil {
+		return fmt.Errorf("deleting outage of %q: %w", service, err)
+	}
+	return nil
+}
+
+// ListOutages returns every stored outage.
+func (d *DB) ListOutages(ctx context.Context) ([]AlertOutage, error) {
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT service, started_at, error, last_notified_at, escalation_step, escalated FROM alert_outages ORDER BY service`)
+	if err != nil {
+		return nil, fmt.Errorf("querying outages: %w", err)
+	}
+	defer rows.Close()
+
+	var outages []AlertOutage
+	for rows.Next() {
+		var (
+			o                   AlertOutage
+			startedAt, notified string
+			escalated           string
+		)
+		if err := rows.Scan(&o.Service, &startedAt, &o.Error, &notified, &o.EscalationStep, &escalated); err != nil {
+			return nil, fmt.Errorf("scanning outage row: %w", err)
+		}
+		if o.StartedAt, err = parseTime(startedAt); err != nil {
+			return nil, fmt.Errorf("parsing started_at %q: %w", startedAt, err)
+		}
+		if o.LastNotifiedAt, err = parseTime(notified); err != nil {
+			return nil, fmt.Errorf("parsing last_notified_at %q: %w", notified, err)
+		}
+		if err := json.Unmarshal([]byte(escalated), &o.Escalated); err != nil {
+			return nil, fmt.Errorf("decoding escalated channels: %w", err)
+		}
+		outages = append(outages, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating outage rows: %w", err)
+	}
+	return outages, nil
+}
@@ -0,0 +1,52 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func TestOutages_SaveListDelete(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-time.Hour)
+
+	o := storage.AlertOutage{Service: "api", StartedAt: start, Error: "refused", LastNotifiedAt: start}
+	if err := db.SaveOutage(ctx, o); err != nil {
+		t.Fatalf("SaveOutage: %v", err)
+	}
+
+	// Saving again replaces the stored state.
+	o.EscalationStep = 1
+	o.Escalated = []string{"oncall"}
+	o.LastNotifiedAt = start.Add(30 * time.Minute)
+	if err := db.SaveOutage(ctx, o); err != nil {
+		t.Fatalf("SaveOutage (update): %v", err)
+	}
+
+	outages, err := db.ListOutages(ctx)
+	if err != nil {
+		t.Fatalf("ListOutages: %v", err)
+	}
+	if len(outages) != 1 {
+		t.Fatalf("expected 1 outage, got %+v", outages)
+	}
+	got := outages[0]
+	if got.Service != "api" || got.Error != "refused" || !got.StartedAt.Equal(start) ||
+		!got.LastNotifiedAt.Equal(o.LastNotifiedAt) || got.EscalationStep != 1 ||
+		len(got.Escalated) != 1 || got.Escalated[0] != "oncall" {
+		t.Errorf("unexpected outage: %+v", got)
+	}
+
+	if err := db.DeleteOutage(ctx, "api"); err != nil {
+		t.Fatalf("DeleteOutage: %v", err)
+	}
+	if err := db.DeleteOutage(ctx, "api"); err != nil {
+		t.Errorf("deleting a missing outage should not fail: %v", err)
+	}
+	if outages, _ := db.ListOutages(ctx); len(outages) != 0 {
+		t.Errorf("expected no outages after delete, got %+v", outages)
+	}
+}
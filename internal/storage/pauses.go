@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PauseService records that service was paused at at. Pausing a service that
+// is already paused does nothing.
+func (d *DB) PauseService(ctx context.Context, service string, at time.Time) error {
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO pauses (service, started_at)
+		SELECT ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM pauses WHERE service = ? AND ended_at IS NULL)`,
+		service, formatTime(at), service,
+	)
+	if err != nil {
+		return fmt.Errorf("pausing %q: %w", service, err)
+	}
+	return nil
+}
+
+// ResumeService ends the current pause of service at at. It returns
+// sql.ErrNoRows if the service is not paused.
+func (d *DB) ResumeService(ctx context.Context, service string, at time.Time) error {
+	res, err := d.db.ExecContext(ctx,
+		`UPDATE pauses SET ended_at = ? WHERE service = ? AND ended_at IS NULL`,
+		formatTime(at), service,
+	)
+	if err != nil {
+		return fmt.Errorf("resuming %q: %w", service, err)
+	}
+	return requireAffected(res, fmt.Sprintf("resuming %q", service))
+}
+
+// ListPaused returns the services that are currently paused, with the time
+// each was paused.
+func (d *DB) ListPaused(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT service, started_at FROM pauses WHERE ended_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("querying paused services: %w", err)
+	}
+	defer rows.Close()
+
+	paused := make(map[string]time.Time)
+	for rows.Next() {
+		var service, startedAt string
+		if err := rows.Scan(&service, &startedAt); err != nil {
+			return nil, fmt.Errorf("scanning pause row: %w", err)
+		}
+		t, err := parseTime(startedAt)
+		if err != nil {
+			return nil, fmt.Errorf("parsing started_at %q: %w", startedAt, err)
+		}
+		paused[service] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating pause rows: %w", err)
+	}
+	return paused, nil
+}
@@ -0,0 +1,41 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPauses_PauseResume(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	if err := db.PauseService(ctx, "api", now); err != nil {
+		t.Fatalf("PauseService: %v", err)
+	}
+	// Pausing again keeps the original start.
+	if err := db.PauseService(ctx, "api", now.Add(time.Minute)); err != nil {
+		t.Fatalf("PauseService: %v", err)
+	}
+	paused, err := db.ListPaused(ctx)
+	if err != nil {
+		t.Fatalf("ListPaused: %v", err)
+	}
+	if got, ok := paused["api"]; !ok || !got.Equal(now) {
+		t.Errorf("expected api paused since %v, got %v (ok=%v)", now, got, ok)
+	}
+
+	if err := db.ResumeService(ctx, "api", now.Add(time.Hour)); err != nil {
+		t.Fatalf("ResumeService: %v", err)
+	}
+	paused, _ = db.ListPaused(ctx)
+	if len(paused) != 0 {
+		t.Errorf("expected no paused services, got %v", paused)
+	}
+	if err := db.ResumeService(ctx, "api", now); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows resuming a running service, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresSchema is the PostgreSQL schema of version 1. It matches the SQLite
+// schema after version 4, with timestamps kept as the same fixed-width text
+// so that queries compare them the same way on both.
+const postgresSchema = `
+CREATE TABLE checks (
+    id          BIGINT  GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    service     TEXT    NOT NULL,
+    status      TEXT    NOT NULL CHECK(status IN ('up', 'down')),
+    response_ms BIGINT  NOT NULL,
+    error       TEXT    NOT NULL DEFAULT '',
+    checked_at  TEXT    NOT NULL,
+    maintenance BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_checks_checked_at ON checks(checked_at DESC);
+CREATE INDEX idx_checks_service_checked ON checks(service, checked_at DESC);
+
+CREATE TABLE maintenance_windows (
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name       TEXT   NOT NULL DEFAULT '',
+    services   TEXT   NOT NULL DEFAULT '[]',
+    tags       TEXT   NOT NULL DEFAULT '[]',
+    starts_at  TEXT   NOT NULL,
+    ends_at    TEXT   NOT NULL,
+    created_at TEXT   NOT NULL
+);
+
+CREATE INDEX idx_maintenance_ends_at ON maintenance_windows(ends_at);
+
+CREATE TABLE services (
+    name            TEXT             PRIMARY KEY,
+    type            TEXT             NOT NULL,
+    target          TEXT             NOT NULL,
+    interval        TEXT             NOT NULL,
+    timeout         TEXT             NOT NULL,
+    expected_status INTEGER          NOT NULL DEFAULT 0,
+    headers         TEXT             NOT NULL DEFAULT '{}',
+    tags            TEXT             NOT NULL DEFAULT '[]',
+    created_at      TEXT             NOT NULL,
+    updated_at      TEXT             NOT NULL,
+    sla_target      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    sla_window      TEXT             NOT NULL DEFAULT ''
+);
+
+CREATE TABLE pauses (
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    service    TEXT   NOT NULL,
+    started_at TEXT   NOT NULL,
+    ended_at   TEXT
+);
+
+CREATE INDEX idx_pauses_service ON pauses(service, started_at);
+
+CREATE TABLE alert_outbox (
+    id              BIGINT  GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    service         TEXT    NOT NULL,
+    channel         TEXT    NOT NULL,
+    payload         TEXT    NOT NULL,
+    status          TEXT    NOT NULL CHECK(status IN ('pending', 'sent', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT    NOT NULL DEFAULT '',
+    created_at      TEXT    NOT NULL,
+    next_attempt_at TEXT    NOT NULL,
+    sent_at         TEXT
+);
+
+CREATE INDEX idx_alert_outbox_due ON alert_outbox(status, next_attempt_at);
+
+CREATE TABLE alert_outages (
+    service          TEXT    PRIMARY KEY,
+    started_at       TEXT    NOT NULL,
+    error            TEXT    NOT NULL DEFAULT '',
+    last_notified_at TEXT    NOT NULL,
+    escalation_step  INTEGER NOT NULL DEFAULT 0,
+    escalated        TEXT    NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE incidents (
+    id          BIGINT  GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    service     TEXT    NOT NULL,
+    started_at  TEXT    NOT NULL,
+    resolved_at TEXT,
+    first_error TEXT    NOT NULL DEFAULT '',
+    check_count INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_incidents_started_at ON incidents(started_at DESC);
+CREATE INDEX idx_incidents_service ON incidents(service, started_at DESC);
+CREATE UNIQUE INDEX idx_incidents_open ON incidents(service) WHERE resolved_at IS NULL;
+
+CREATE TABLE check_rollups_hourly (
+    service       TEXT             NOT NULL,
+    bucket        TEXT             NOT NULL,
+    checks        INTEGER          NOT NULL,
+    up            INTEGER          NOT NULL,
+    min_ms        BIGINT           NOT NULL,
+    avg_ms        DOUBLE PRECISION NOT NULL,
+    p95_ms        BIGINT           NOT NULL,
+    max_ms        BIGINT           NOT NULL,
+    up_seconds    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    known_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (service, bucket)
+);
+
+CREATE INDEX idx_check_rollups_hourly_bucket ON check_rollups_hourly(bucket);
+
+CREATE TABLE check_rollups_daily (
+    service       TEXT             NOT NULL,
+    bucket        TEXT             NOT NULL,
+    checks        INTEGER          NOT NULL,
+    up            INTEGER          NOT NULL,
+    min_ms        BIGINT           NOT NULL,
+    avg_ms        DOUBLE PRECISION NOT NULL,
+    p95_ms        BIGINT           NOT NULL,
+    max_ms        BIGINT           NOT NULL,
+    up_seconds    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    known_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (service, bucket)
+);
+
+CREATE INDEX idx_check_rollups_daily_bucket ON check_rollups_daily(bucket);
+`
+
+// OpenPostgres connects to the PostgreSQL database at dsn and applies pending
+// migrations. Tables are created in the connection's current schema.
+func OpenPostgres(dsn string) (*DB, error) {
+	db, err := openPostgres(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrate(context.Background(), db, false); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{db: db}, nil
+}
+
+// openPostgres opens a connection pool and checks that the server is
+// reachable. Errors leave out the DSN, which may hold a password.
+func openPostgres(dsn string) (*dbConn, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening postgres: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to postgres: %w", err)
+	}
+	return &dbConn{DB: db, dialect: dialectPostgres}, nil
+}
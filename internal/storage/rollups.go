@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// Resolution is the bucket size of a rollup.
+type Resolution string
+
+const (
+	Hourly Resolution = "hour"
+	Daily  Resolution = "day"
+)
+
+func (r Resolution) table() string {
+	if r == Daily {
+		return "check_rollups_daily"
+	}
+	return "check_rollups_hourly"
+}
+
+func (r Resolution) step() time.Duration {
+	if r == Daily {
+		return 24 * time.Hour
+	}
+	return time.Hour
+}
+
+// Rollup aggregates one service's checks over an hour or a day. Like uptime,
+// it leaves out checks taken during maintenance or while the service was
+// paused.
+type Rollup struct {
+	Service string `json:"service"`
+	// Bucket is the start of the hour or day, in UTC.
+	Bucket time.Time `json:"bucket"`
+	Checks int       `json:"checks"`
+	Up     int       `json:"up"`
+	MinMs  int64     `json:"min_ms"`
+	AvgMs  float64   `json:"avg_ms"`
+	P95Ms  int64     `json:"p95_ms"`
+	MaxMs  int64     `json:"max_ms"`
+	// UpSeconds and KnownSeconds weigh uptime by duration; see Uptime.
+	UpSeconds    float64 `json:"up_seconds"`
+	KnownSeconds float64 `json:"known_seconds"`
+}
+
+// countedChecks selects the checks of c that count towards uptime.
+const countedChecks = `NOT c.maintenance
+	AND NOT EXISTS (
+		SELECT 1 FROM pauses p
+		WHERE p.service = c.service AND c.checked_at >= p.started_at
+		  AND (p.ended_at IS NULL OR c.checked_at < p.ended_at)
+	)`
+
+// Compact rolls up every completed hour and day that hasn't been rolled up
+// yet, then deletes checks and rollups older than policy allows. Days are
+// counted back from midnight UTC.
+func (d *DB) Compact(ctx context.Context, now time.Time, policy config.RetentionConfig) error {
+	now = now.UTC()
+	if err := d.rollup(ctx, Hourly, now, retentionCutoff(now, policy.HourlyDays)); err != nil {
+		return err
+	}
+	if err := d.rollup(ctx, Daily, now, retentionCutoff(now, policy.DailyDays)); err != nil {
+		return err
+	}
+
+	prune := []struct {
+		what, query string
+		days        int
+	}{
+		{"checks", `DELETE FROM checks WHERE checked_at < ?`, policy.RawDays},
+		{"hourly rollups", `DELETE FROM check_rollups_hourly WHERE bucket < ?`, policy.HourlyDays},
+		{"daily rollups", `DELETE FROM check_rollups_daily WHERE bucket < ?`, policy.DailyDays},
+	}
+	for _, p := range prune {
+		if p.days == 0 {
+			continue
+		}
+		if _, err := d.db.ExecContext(ctx, p.query, formatTime(retentionCutoff(now, p.days))); err != nil {
+			return fmt.Errorf("deleting old %s: %w", p.what, err)
+		}
+	}
+	return nil
+}
+
+// retentionCutoff is midnight UTC days days before now, or the zero time if
+// data is kept forever.
+func retentionCutoff(now time.Time, days int) time.Time {
+	if days == 0 {
+		return time.Time{}
+	}
+	return now.Truncate(24*time.Hour).AddDate(0, 0, -days)
+}
+
+// rollupSettle is how long after a bucket ends it is rolled up again on
+// every pass. Results reach the database a little after they are taken, as
+// they are written in batches, so the first rollup of a bucket can miss a
+// few.
+const rollupSettle = time.Hour
+
+// rollup aggregates the completed buckets of res after the last one rolled
+// up, and the last one again while it is within rollupSettle of its end.
+// Buckets before cutoff would be deleted again right away, so they are
+// skipped.
+func (d *DB) rollup(ctx context.Context, res Resolution, now, cutoff time.Time) error {
+	step := res.step()
+	end := now.Truncate(step)
+
+	var last sql.NullString
+	if err := d.db.QueryRowContext(ctx, `SELECT MAX(bucket) FROM `+res.table()).Scan(&last); err != nil {
+		return fmt.Errorf("finding last rollup in %s: %w", res.table(), err)
+	}
+	var from time.Time
+	if last.Valid {
+		t, err := parseTime(last.String)
+		if err != nil {
+			return fmt.Errorf("parsing bucket %q: %w", last.String, err)
+		}
+		from = t.Add(step)
+		if now.Before(from.Add(rollupSettle)) {
+			from = t
+		}
+	}
+	if from.Before(cutoff) {
+		from = cutoff
+	}
+
+	for {
+		// Skip over gaps without checks, e.g. while servprobe wasn't running.
+		next, ok, err := d.firstCheckSince(ctx, from)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		bucket := next.Truncate(step)
+		if !bucket.Before(end) {
+			return nil
+		}
+		if err := d.rollupBucket(ctx, res, bucket); err != nil {
+			return err
+		}
+		from = bucket.Add(step)
+	}
+}
+
+func (d *DB) firstCheckSince(ctx context.Context, from time.Time) (time.Time, bool, error) {
+	var first sql.NullString
+	err := d.db.QueryRowContext(ctx,
+		`SELECT MIN(checked_at) FROM checks WHERE checked_at >= ?`, formatTime(from),
+	).Scan(&first)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("finding checks to roll up: %w", err)
+	}
+	if !first.Valid {
+		return time.Time{}, false, nil
+	}
+	t, err := parseTime(first.String)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("parsing checked_at %q: %w", first.String, err)
+	}
+	return t.UTC(), true, nil
+}
+
+// rollupBucket aggregates the checks of every service in the bucket starting
+// at start. Percentiles need the sorted response times and uptime the time
+// between checks, so the aggregation happens here rather than in SQL.
+func (d *DB) rollupBucket(ctx context.Context, res Resolution, start time.Time) error {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT c.service, c.status, c.response_ms FROM checks c
+		WHERE c.checked_at >= ? AND c.checked_at < ? AND `+countedChecks+`
+		ORDER BY c.service, c.response_ms
+	`, formatTime(start), formatTime(start.Add(res.step())))
+	if err != nil {
+		return fmt.Errorf("querying checks from %s: %w", formatTime(start), err)
+	}
+	defer rows.Close()
+
+	type sample struct {
+		up int
+		ms []int64
+	}
+	var (
+		order   []string
+		samples = map[string]*sample{}
+	)
+	for rows.Next() {
+		var (
+			service, status string
+			ms              int64
+		)
+		if err := rows.Scan(&service, &status, &ms); err != nil {
+			return fmt.Errorf("scanning check row: %w", err)
+		}
+		s, ok := samples[service]
+		if !ok {
+			s = &sample{}
+			samples[service] = s
+			order = append(order, service)
+		}
+		if status == "up" {
+			s.up++
+		}
+		s.ms = append(s.ms, ms)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating check rows: %w", err)
+	}
+	rows.Close()
+
+	uptimes, err := d.uptimes(ctx, "", start, start.Add(res.step()))
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// A re-roll may find nothing left to count for a service, e.g. when the
+	// late results all fall in maintenance, so its old row must not stay.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+res.table()+` WHERE bucket = ?`, formatTime(start)); err != nil {
+		return fmt.Errorf("clearing %s bucket %s: %w", res.table(), formatTime(start), err)
+	}
+	for _, service := range order {
+		s := samples[service]
+		var sum int64
+		for _, ms := range s.ms {
+			sum += ms
+		}
+		n := len(s.ms)
+		p95 := s.ms[int(math.Ceil(0.95*float64(n)))-1]
+		u := uptimes[service]
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO `+res.table()+` (service, bucket, checks, up, min_ms, avg_ms, p95_ms, max_ms, up_seconds, known_seconds)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(service, bucket) DO UPDATE SET
+				checks = excluded.checks, up = excluded.up, min_ms = excluded.min_ms, avg_ms = excluded.avg_ms,
+				p95_ms = excluded.p95_ms, max_ms = excluded.max_ms,
+				up_seconds = excluded.up_seconds, known_seconds = excluded.known_seconds`,
+			service, formatTime(start), n, s.up, s.ms[0], float64(sum)/float64(n), p95, s.ms[n-1],
+			u.Up.Seconds(), u.Known.Seconds(),
+		)
+		if err != nil {
+			return fmt.Errorf("storing %s rollup of %q: %w", res, service, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing %s rollups: %w", res, err)
+	}
+	return nil
+}
+
+// Rollups returns the service's rollups at res whose buckets start in
+// [from, to), oldest first. A zero from or to leaves that end open.
+func (d *DB) Rollups(ctx context.Context, service string, res Resolution, from, to time.Time) ([]Rollup, error) {
+	query := `SELECT service, bucket, checks, up, min_ms, avg_ms, p95_ms, max_ms, up_seconds, known_seconds FROM ` + res.table() +
+		` WHERE service = ? AND bucket >= ?`
+	args := []any{service, formatTime(from)}
+	if !to.IsZero() {
+		query += ` AND bucket < ?`
+		args = append(args, formatTime(to))
+	}
+	rows, err := d.db.QueryContext(ctx, query+` ORDER BY bucket`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s rollups for %q: %w", res, service, err)
+	}
+	defer rows.Close()
+
+	var rollups []Rollup
+	for rows.Next() {
+		var (
+			r      Rollup
+			bucket string
+		)
+		if err := rows.Scan(&r.Service, &bucket, &r.Checks, &r.Up, &r.MinMs, &r.AvgMs, &r.P95Ms, &r.MaxMs, &r.UpSeconds, &r.KnownSeconds); err != nil {
+			return nil, fmt.Errorf("scanning rollup row: %w", err)
+		}
+		if r.Bucket, err = parseTime(bucket); err != nil {
+			return nil, fmt.Errorf("parsing bucket %q: %w", bucket, err)
+		}
+		rollups = append(rollups, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rollup rows: %w", err)
+	}
+	return rollups, nil
+}
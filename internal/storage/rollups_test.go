@@ -0,0 +1,187 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func TestCompact_RollsUpAndPrunes(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Two days of checks every 10 minutes, answering in 10..60ms within each
+	// hour and down from 05:00 to 06:00.
+	for m := 0; m < 48*60; m += 10 {
+		status := checker.StatusUp
+		if m/60%24 == 5 {
+			status = checker.StatusDown
+		}
+		r := makeResult("api", status, int64(m%60+10))
+		r.CheckedAt = t0.Add(time.Duration(m) * time.Minute)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	// Checks during maintenance don't count.
+	r := makeResult("api", checker.StatusDown, 5000)
+	r.CheckedAt = t0.Add(95 * time.Minute)
+	r.Maintenance = true
+	if err := db.InsertCheck(ctx, r); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+
+	now := t0.Add(49*time.Hour + 30*time.Minute)
+	policy := config.RetentionConfig{RawDays: 1}
+	if err := db.Compact(ctx, now, policy); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	// Compacting again changes nothing.
+	if err := db.Compact(ctx, now, policy); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	hourly, err := db.Rollups(ctx, "api", storage.Hourly, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Rollups: %v", err)
+	}
+	if len(hourly) != 48 {
+		t.Fatalf("expected 48 hourly rollups, got %d", len(hourly))
+	}
+	want := storage.Rollup{Service: "api", Bucket: t0.Add(time.Hour), Checks: 6, Up: 6, MinMs: 10, AvgMs: 35, P95Ms: 60, MaxMs: 60, UpSeconds: 3300, KnownSeconds: 3300}
+	if got := hourly[1]; got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got := hourly[5]; got.Checks != 6 || got.Up != 0 || got.UpSeconds != 0 || got.KnownSeconds != 3600 {
+		t.Errorf("expected a down hour, got %+v", got)
+	}
+
+	daily, err := db.Rollups(ctx, "api", storage.Daily, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Rollups: %v", err)
+	}
+	if len(daily) != 2 || daily[0].Checks != 144 || daily[0].Up != 138 || daily[0].MaxMs != 60 {
+		t.Fatalf("unexpected daily rollups: %+v", daily)
+	}
+
+	// Only the last day of raw checks is left.
+	history, err := db.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Limit: 1})
+	if err != nil {
+		t.Fatalf("ServiceHistory: %v", err)
+	}
+	if history.Total != 144 {
+		t.Errorf("expected 144 raw checks after pruning, got %d", history.Total)
+	}
+
+	// Uptime is weighted by duration: every check lasts 10 minutes, except
+	// that the one before the maintenance check lasts only 5.
+	assertUptime := func(from, to time.Time, wantUp, wantKnown time.Duration) {
+		t.Helper()
+		got, err := db.UptimeBetween(ctx, "api", from, to)
+		if err != nil {
+			t.Fatalf("UptimeBetween: %v", err)
+		}
+		if got.Up != wantUp || got.Known != wantKnown {
+			t.Errorf("expected %s up of %s known between %s and %s, got %s of %s", wantUp, wantKnown, from, to, got.Up, got.Known)
+		}
+	}
+	assertUptime(t0, t0.Add(48*time.Hour), 46*time.Hour-5*time.Minute, 48*time.Hour-5*time.Minute)
+	assertUptime(t0.Add(24*time.Hour), t0.Add(48*time.Hour), 23*time.Hour, 24*time.Hour)
+	assertUptime(t0.Add(5*time.Hour), t0.Add(6*time.Hour), 0, time.Hour)
+	// The first half hour's raw checks are gone, so it is unknown; the
+	// rest comes from hourly rollups and, on the second day, raw checks.
+	assertUptime(t0.Add(30*time.Minute), t0.Add(24*time.Hour+30*time.Minute), 22*time.Hour+25*time.Minute, 23*time.Hour+25*time.Minute)
+
+	// Once hourly rollups are pruned too, the daily ones answer instead.
+	policy.HourlyDays = 1
+	if err := db.Compact(ctx, now, policy); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	hourly, _ = db.Rollups(ctx, "api", storage.Hourly, time.Time{}, t0.Add(24*time.Hour))
+	if len(hourly) != 0 {
+		t.Errorf("expected first day's hourly rollups to be pruned, got %d", len(hourly))
+	}
+	assertUptime(t0, t0.Add(48*time.Hour), 46*time.Hour-5*time.Minute, 48*time.Hour-5*time.Minute)
+}
+
+func TestCompact_CountsLateResults(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	insert := func(at time.Duration) {
+		t.Helper()
+		r := makeResult("api", checker.StatusUp, 10)
+		r.CheckedAt = t0.Add(at)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	hourChecks := func() int {
+		t.Helper()
+		hourly, err := db.Rollups(ctx, "api", storage.Hourly, t0, t0.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Rollups: %v", err)
+		}
+		if len(hourly) != 1 {
+			t.Fatalf("expected one hourly rollup, got %+v", hourly)
+		}
+		return hourly[0].Checks
+	}
+
+	for m := 0; m < 50; m += 10 {
+		insert(time.Duration(m) * time.Minute)
+	}
+	if err := db.Compact(ctx, t0.Add(time.Hour+time.Minute), config.RetentionConfig{}); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if n := hourChecks(); n != 5 {
+		t.Fatalf("expected 5 checks rolled up, got %d", n)
+	}
+
+	// A result from 09:50 written after the first pass is counted by the
+	// next one.
+	insert(50 * time.Minute)
+	if err := db.Compact(ctx, t0.Add(time.Hour+15*time.Minute), config.RetentionConfig{}); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if n := hourChecks(); n != 6 {
+		t.Errorf("expected the late check to be rolled up, got %d checks", n)
+	}
+}
+
+func TestCompact_RerollDropsServicesWithoutCountedChecks(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for m := 0; m < 50; m += 10 {
+		r := makeResult("api", checker.StatusUp, 10)
+		r.CheckedAt = t0.Add(time.Duration(m) * time.Minute)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	if err := db.Compact(ctx, t0.Add(time.Hour+time.Minute), config.RetentionConfig{}); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	// A pause recorded after the first pass covers the whole hour, so the
+	// re-roll finds nothing to count for api.
+	if err := db.PauseService(ctx, "api", t0); err != nil {
+		t.Fatalf("PauseService: %v", err)
+	}
+	if err := db.Compact(ctx, t0.Add(time.Hour+15*time.Minute), config.RetentionConfig{}); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	hourly, err := db.Rollups(ctx, "api", storage.Hourly, t0, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Rollups: %v", err)
+	}
+	if len(hourly) != 0 {
+		t.Errorf("expected the stale rollup to be removed, got %+v", hourly)
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// ErrExists is returned when inserting a service whose name is taken.
+var ErrExists = errors.New("already exists")
+
+// InsertService persists a service managed through the API. It returns
+// ErrExists if a service with the same name is already stored.
+func (d *DB) InsertService(ctx context.Context, svc config.Service) error {
+	headers, tags, err := encodeServiceMeta(svc)
+	if err != nil {
+		return err
+	}
+	now := formatTime(time.Now())
+	res, err := d.db.ExecContext(ctx, `
+		INSERT INTO services (name, type, target, interval, timeout, expected_status, headers, tags, sla_target, sla_window, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO NOTHING`,
+		svc.Name, svc.Type, svc.Target,
+		svc.Interval.Duration.String(), svc.Timeout.Duration.String(),
+		svc.ExpectedStatus, headers, tags, svc.SLA.Target, svc.SLA.Window, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting service %q: %w", svc.Name, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("inserting service %q: %w", svc.Name, err)
+	} else if n == 0 {
+		return fmt.Errorf("service %q: %w", svc.Name, ErrExists)
+	}
+	return nil
+}
+
+// UpdateService replaces a stored service. It returns sql.ErrNoRows if the
+// service does not exist.
+func (d *DB) UpdateService(ctx context.Context, svc config.Service) error {
+	headers, tags, err := encodeServiceMeta(svc)
+	if err != nil {
+		return err
+	}
+	res, err := d.db.ExecContext(ctx, `
+		UPDATE services
+		SET type = ?, target = ?, interval = ?, timeout = ?, expected_status = ?, headers = ?, tags = ?,
+		    sla_target = ?, sla_window = ?, updated_at = ?
+		WHERE name = ?`,
+		svc.Type, svc.Target,
+		svc.Interval.Duration.String(), svc.Timeout.Duration.String(),
+		svc.ExpectedStatus, headers, tags, svc.SLA.Target, svc.SLA.Window, formatTime(time.Now()),
+		svc.Name,
+	)
+	if err != nil {
+		return fmt.Errorf("updating service %q: %w", svc.Name, err)
+	}
+	return requireAffected(res, fmt.Sprintf("updating service %q", svc.Name))
+}
+
+// DeleteService removes a stored service. Its check history is kept. It
+// returns sql.ErrNoRows if the service does not exist.
+func (d *DB) DeleteService(ctx context.Context, name string) error {
+	res, err := d.db.ExecContext(ctx, `DELETE FROM services WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("deleting service %q: %w", name, err)
+	}
+	return requireAffected(res, fmt.Sprintf("deleting service %q", name))
+}
+
+// ListServices returns all stored services ordered by creation time.
+func (d *DB) ListServices(ctx context.Context) ([]config.Service, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT name, type, target, interval, timeout, expected_status, headers, tags, sla_target, sla_window
+		FROM services
+		ORDER BY created_at, name`)
+	if err != nil {
+		return nil, fmt.Errorf("querying services: %w", err)
+	}
+	defer rows.Close()
+
+	var services []config.Service
+	for rows.Next() {
+		var spec config.ServiceSpec
+		var headers, tags string
+		err := rows.Scan(&spec.Name, &spec.Type, &spec.Target, &spec.Interval, &spec.Timeout,
+			&spec.ExpectedStatus, &headers, &tags, &spec.SLA.Target, &spec.SLA.Window)
+		if err != nil {
+			return nil, fmt.Errorf("scanning service row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(headers), &spec.Headers); err != nil {
+			return nil, fmt.Errorf("decoding headers of service %q: %w", spec.Name, err)
+		}
+		if err := json.Unmarshal([]byte(tags), &spec.Tags); err != nil {
+			return nil, fmt.Errorf("decoding tags of service %q: %w", spec.Name, err)
+		}
+		svc, err := spec.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("stored service: %w", err)
+		}
+		services = append(services, svc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating service rows: %w", err)
+	}
+	return services, nil
+}
+
+func encodeServiceMeta(svc config.Service) (headers, tags string, err error) {
+	h := svc.Headers
+	if h == nil {
+		h = map[string]string{}
+	}
+	t := svc.Tags
+	if t == nil {
+		t = []string{}
+	}
+	hb, err := json.Marshal(h)
+	if err != nil {
+		return "", "", fmt.Errorf("encoding headers: %w", err)
+	}
+	tb, err := json.Marshal(t)
+	if err != nil {
+		return "", "", fmt.Errorf("encoding tags: %w", err)
+	}
+	return string(hb), string(tb), nil
+}
+
+// requireAffected returns sql.ErrNoRows if res affected no rows.
+func requireAffected(res sql.Result, op string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/storage"
+)
+
+func makeService(name string) config.Service {
+	return config.Service{
+		Name:           name,
+		Type:           "http",
+		Target:         "https://example.com",
+		Interval:       config.Duration{Duration: time.Minute},
+		Timeout:        config.Duration{Duration: 5 * time.Second},
+		ExpectedStatus: 200,
+		Headers:        map[string]string{"X-Probe": "1"},
+		Tags:           []string{"prod"},
+	}
+}
+
+func TestServices_InsertListUpdateDelete(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	if err := db.InsertService(ctx, makeService("api")); err != nil {
+		t.Fatalf("InsertService: %v", err)
+	}
+	if err := db.InsertService(ctx, makeService("web")); err != nil {
+		t.Fatalf("InsertService: %v", err)
+	}
+
+	services, err := db.ListServices(ctx)
+	if err != nil {
+		t.Fatalf("ListServices: %v", err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+	got := services[0]
+	if got.Name != "api" || got.Interval.Duration != time.Minute || got.Headers["X-Probe"] != "1" || !got.HasTag("prod") {
+		t.Errorf("unexpected service: %+v", got)
+	}
+
+	updated := makeService("api")
+	updated.Target = "https://example.org"
+	updated.SLA = config.SLA{Target: 99.9, Window: "7d"}
+	if err := db.UpdateService(ctx, updated); err != nil {
+		t.Fatalf("UpdateService: %v", err)
+	}
+	services, _ = db.ListServices(ctx)
+	if services[0].Target != "https://example.org" || services[0].SLA != updated.SLA {
+		t.Errorf("expected update to be stored, got %+v", services[0])
+	}
+
+	if err := db.DeleteService(ctx, "api"); err != nil {
+		t.Fatalf("DeleteService: %v", err)
+	}
+	services, _ = db.ListServices(ctx)
+	if len(services) != 1 || services[0].Name != "web" {
+		t.Errorf("expected only web to remain, got %+v", services)
+	}
+}
+
+func TestServices_InsertDuplicate(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	if err := db.InsertService(ctx, makeService("api")); err != nil {
+		t.Fatal(err)
+	}
+	err := db.InsertService(ctx, makeService("api"))
+	if !errors.Is(err, storage.ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+}
+
+func TestServices_Missing(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	if err := db.UpdateService(ctx, makeService("ghost")); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateService: expected sql.ErrNoRows, got %v", err)
+	}
+	if err := db.DeleteService(ctx, "ghost"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteService: expected sql.ErrNoRows, got %v", err)
+	}
+}
@@ -3,13 +3,20 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hazz-dev/servprobe/internal/checker"
 	_ "modernc.org/sqlite"
 )
 
+// schema is the schema of version 1, the first versioned one. It creates
+// tables only if missing so that databases from before versioning can adopt
+// it; like every migration, it must not change once released.
 const schema = `
 CREATE TABLE IF NOT EXISTS checks (
     id          INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -17,31 +24,170 @@ CREATE TABLE IF NOT EXISTS checks (
     status      TEXT    NOT NULL CHECK(status IN ('up', 'down')),
     response_ms INTEGER NOT NULL,
     error       TEXT    NOT NULL DEFAULT '',
-    checked_at  TEXT    NOT NULL
+    checked_at  TEXT    NOT NULL,
+    maintenance INTEGER NOT NULL DEFAULT 0
 );
 
 CREATE INDEX IF NOT EXISTS idx_checks_service ON checks(service);
 CREATE INDEX IF NOT EXISTS idx_checks_checked_at ON checks(checked_at DESC);
 CREATE INDEX IF NOT EXISTS idx_checks_service_checked ON checks(service, checked_at DESC);
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT    NOT NULL DEFAULT '',
+    services   TEXT    NOT NULL DEFAULT '[]',
+    tags       TEXT    NOT NULL DEFAULT '[]',
+    starts_at  TEXT    NOT NULL,
+    ends_at    TEXT    NOT NULL,
+    created_at TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_ends_at ON maintenance_windows(ends_at);
+
+CREATE TABLE IF NOT EXISTS services (
+    name            TEXT    PRIMARY KEY,
+    type            TEXT    NOT NULL,
+    target          TEXT    NOT NULL,
+    interval        TEXT    NOT NULL,
+    timeout         TEXT    NOT NULL,
+    expected_status INTEGER NOT NULL DEFAULT 0,
+    headers         TEXT    NOT NULL DEFAULT '{}',
+    tags            TEXT    NOT NULL DEFAULT '[]',
+    created_at      TEXT    NOT NULL,
+    updated_at      TEXT    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pauses (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    service    TEXT    NOT NULL,
+    started_at TEXT    NOT NULL,
+    ended_at   TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_pauses_service ON pauses(service, started_at);
+
+CREATE TABLE IF NOT EXISTS alert_outbox (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    service         TEXT    NOT NULL,
+    channel         TEXT    NOT NULL,
+    payload         TEXT    NOT NULL,
+    status          TEXT    NOT NULL CHECK(status IN ('pending', 'sent', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT    NOT NULL DEFAULT '',
+    created_at      TEXT    NOT NULL,
+    next_attempt_at TEXT    NOT NULL,
+    sent_at         TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_outbox_due ON alert_outbox(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS alert_outages (
+    service          TEXT    PRIMARY KEY,
+    started_at       TEXT    NOT NULL,
+    error            TEXT    NOT NULL DEFAULT '',
+    last_notified_at TEXT    NOT NULL,
+    escalation_step  INTEGER NOT NULL DEFAULT 0,
+    escalated        TEXT    NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    service     TEXT    NOT NULL,
+    started_at  TEXT    NOT NULL,
+    resolved_at TEXT,
+    first_error TEXT    NOT NULL DEFAULT '',
+    check_count INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_started_at ON incidents(started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_incidents_service ON incidents(service, started_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_open ON incidents(service) WHERE resolved_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS check_rollups_hourly (
+    service TEXT    NOT NULL,
+    bucket  TEXT    NOT NULL,
+    checks  INTEGER NOT NULL,
+    up      INTEGER NOT NULL,
+    min_ms  INTEGER NOT NULL,
+    avg_ms  REAL    NOT NULL,
+    p95_ms  INTEGER NOT NULL,
+    max_ms  INTEGER NOT NULL,
+    PRIMARY KEY (service, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_check_rollups_hourly_bucket ON check_rollups_hourly(bucket);
+
+CREATE TABLE IF NOT EXISTS check_rollups_daily (
+    service TEXT    NOT NULL,
+    bucket  TEXT    NOT NULL,
+    checks  INTEGER NOT NULL,
+    up      INTEGER NOT NULL,
+    min_ms  INTEGER NOT NULL,
+    avg_ms  REAL    NOT NULL,
+    p95_ms  INTEGER NOT NULL,
+    max_ms  INTEGER NOT NULL,
+    PRIMARY KEY (service, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_check_rollups_daily_bucket ON check_rollups_daily(bucket);
 `
 
+// columns lists columns added before versioning. Version 1 adds them to
+// databases that lack them; new databases get them from schema.
+var columns = []struct {
+	table, name, def string
+}{
+	{"checks", "maintenance", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// timeFormat is a fixed-width RFC 3339 layout, so stored timestamps sort
+// lexicographically in chronological order.
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+func parseTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		// Fallback to RFC3339 without sub-second precision.
+		t, err = time.Parse(time.RFC3339, s)
+	}
+	return t, err
+}
+
 // Check is a stored check result.
 type Check struct {
-	ID         int64     `json:"id"`
-	Service    string    `json:"service"`
-	Status     string    `json:"status"`
-	ResponseMs int64     `json:"response_ms"`
-	Error      string    `json:"error"`
-	CheckedAt  time.Time `json:"checked_at"`
+	ID          int64     `json:"id"`
+	Service     string    `json:"service"`
+	Status      string    `json:"status"`
+	ResponseMs  int64     `json:"response_ms"`
+	Error       string    `json:"error"`
+	CheckedAt   time.Time `json:"checked_at"`
+	Maintenance bool      `json:"maintenance"`
 }
 
-// DB wraps a SQLite database.
+// DB wraps a SQLite or PostgreSQL database.
 type DB struct {
-	db *sql.DB
+	db *dbConn
 }
 
-// Open opens (or creates) the SQLite database at path and applies the schema.
+// Open opens (or creates) the SQLite database at path and applies pending
+// migrations.
 func Open(path string) (*DB, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrate(context.Background(), db, false); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{db: db}, nil
+}
+
+func openSQLite(path string) (*dbConn, error) {
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, fmt.Errorf("opening sqlite at %q: %w", path, err)
@@ -60,12 +206,37 @@ func Open(path string) (*DB, error) {
 		}
 	}
 
-	if _, err := db.Exec(schema); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("applying schema: %w", err)
+	return &dbConn{DB: db, dialect: dialectSQLite}, nil
+}
+
+func addColumnIfMissing(ctx context.Context, tx *dbTx, table, column, def string) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("inspecting table %q: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, typ        string
+			dflt             sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("inspecting table %q: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("inspecting table %q: %w", table, err)
 	}
+	rows.Close()
 
-	return &DB{db: db}, nil
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def)); err != nil {
+		return fmt.Errorf("adding column %s.%s: %w", table, column, err)
+	}
+	return nil
 }
 
 // Close closes the underlying database connection.
@@ -73,18 +244,45 @@ func (d *DB) Close() error {
 	return d.db.Close()
 }
 
-// InsertCheck persists a check result.
+// DBStats returns statistics of the connection pool.
+func (d *DB) DBStats() sql.DBStats {
+	return d.db.Stats()
+}
+
+// InsertCheck persists a check result and updates the service's incidents
+// to match.
 func (d *DB) InsertCheck(ctx context.Context, r checker.CheckResult) error {
-	_, err := d.db.ExecContext(ctx,
-		`INSERT INTO checks (service, status, response_ms, error, checked_at) VALUES (?, ?, ?, ?, ?)`,
-		r.ServiceName,
-		string(r.Status),
-		r.ResponseTime.Milliseconds(),
-		r.Error,
-		r.CheckedAt.UTC().Format(time.RFC3339Nano),
-	)
+	return d.InsertChecks(ctx, []checker.CheckResult{r})
+}
+
+// InsertChecks persists check results in order in a single transaction, so
+// either all of them are stored or none is.
+func (d *DB) InsertChecks(ctx context.Context, results []checker.CheckResult) error {
+	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("inserting check for %q: %w", r.ServiceName, err)
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, r := range results {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO checks (service, status, response_ms, error, checked_at, maintenance) VALUES (?, ?, ?, ?, ?, ?)`,
+			r.ServiceName,
+			string(r.Status),
+			r.ResponseTime.Milliseconds(),
+			r.Error,
+			formatTime(r.CheckedAt),
+			r.Maintenance,
+		)
+		if err != nil {
+			return fmt.Errorf("inserting check for %q: %w", r.ServiceName, err)
+		}
+		if err := recordIncident(ctx, tx, r); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing %d checks: %w", len(results), err)
 	}
 	return nil
 }
@@ -92,7 +290,7 @@ func (d *DB) InsertCheck(ctx context.Context, r checker.CheckResult) error {
 // LatestCheck returns the most recent check for the given service, or nil if none.
 func (d *DB) LatestCheck(ctx context.Context, service string) (*Check, error) {
 	row := d.db.QueryRowContext(ctx,
-		`SELECT id, service, status, response_ms, error, checked_at FROM checks WHERE service = ? ORDER BY checked_at DESC LIMIT 1`,
+		`SELECT id, service, status, response_ms, error, checked_at, maintenance FROM checks WHERE service = ? ORDER BY checked_at DESC LIMIT 1`,
 		service,
 	)
 	c, err := scanCheck(row)
@@ -105,40 +303,139 @@ func (d *DB) LatestCheck(ctx context.Context, service string) (*Check, error) {
 	return c, nil
 }
 
-// ServiceHistory returns paginated check history for a service plus the total count.
-func (d *DB) ServiceHistory(ctx context.Context, service string, limit, offset int) ([]Check, int, error) {
-	var total int
+// ErrInvalidCursor is returned for a history cursor that ServiceHistory
+// didn't hand out.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// HistoryQuery selects a page of a service's checks, newest first. Zero
+// fields don't restrict the result.
+type HistoryQuery struct {
+	Service string
+	// From and To select checks taken in [From, To).
+	From, To time.Time
+	// Status selects only up or only down checks.
+	Status string
+	Limit  int
+	// Cursor continues after the page it was returned with. Unlike Offset it
+	// is stable while new checks arrive; Offset is ignored when it is set.
+	Cursor string
+	Offset int
+}
+
+// HistoryPage is a page of check history.
+type HistoryPage struct {
+	Checks []Check
+	// Total is the number of checks that match the query's filters.
+	Total int
+	// NextCursor selects the next page; it is empty on the last one.
+	NextCursor string
+}
+
+// ServiceHistory returns a page of q.Service's check history plus the total
+// count. Pages are ordered by time and ID so that cursors are unique.
+func (d *DB) ServiceHistory(ctx context.Context, q HistoryQuery) (HistoryPage, error) {
+	where := []string{"service = ?"}
+	args := []any{q.Service}
+	if !q.From.IsZero() {
+		where = append(where, "checked_at >= ?")
+		args = append(args, formatTime(q.From))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "checked_at < ?")
+		args = append(args, formatTime(q.To))
+	}
+	if q.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, q.Status)
+	}
+
+	var page HistoryPage
 	err := d.db.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM checks WHERE service = ?`, service,
-	).Scan(&total)
+		`SELECT COUNT(*) FROM checks WHERE `+strings.Join(where, " AND "), args...,
+	).Scan(&page.Total)
 	if err != nil {
-		return nil, 0, fmt.Errorf("counting checks for %q: %w", service, err)
+		return HistoryPage{}, fmt.Errorf("counting checks for %q: %w", q.Service, err)
 	}
 
+	offset := q.Offset
+	if q.Cursor != "" {
+		at, id, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return HistoryPage{}, err
+		}
+		where = append(where, "(checked_at < ? OR (checked_at = ? AND id < ?))")
+		args = append(args, at, at, id)
+		offset = 0
+	}
+	// One extra row tells whether there is a next page.
+	limit := max(q.Limit, 0)
+	args = append(args, limit+1, offset)
 	rows, err := d.db.QueryContext(ctx,
-		`SELECT id, service, status, response_ms, error, checked_at FROM checks WHERE service = ? ORDER BY checked_at DESC LIMIT ? OFFSET ?`,
-		service, limit, offset,
+		`SELECT id, service, status, response_ms, error, checked_at, maintenance FROM checks WHERE `+
+			strings.Join(where, " AND ")+` ORDER BY checked_at DESC, id DESC LIMIT ? OFFSET ?`,
+		args...,
 	)
 	if err != nil {
-		return nil, 0, fmt.Errorf("querying history for %q: %w", service, err)
+		return HistoryPage{}, fmt.Errorf("querying history for %q: %w", q.Service, err)
 	}
 	defer rows.Close()
 
 	checks, err := scanChecks(rows)
 	if err != nil {
-		return nil, 0, err
+		return HistoryPage{}, err
+	}
+	if len(checks) > limit {
+		checks = checks[:limit]
+		if limit > 0 {
+			last := checks[limit-1]
+			page.NextCursor = encodeCursor(last.CheckedAt, last.ID)
+		}
 	}
-	return checks, total, nil
+	page.Checks = checks
+	return page, nil
 }
 
+// encodeCursor makes an opaque token of the position of a check.
+func encodeCursor(at time.Time, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(formatTime(at) + "," + strconv.FormatInt(id, 10)))
+}
+
+// decodeCursor returns the checked_at column value and ID a cursor points at.
+func decodeCursor(cursor string) (string, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	at, idStr, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return "", 0, ErrInvalidCursor
+	}
+	t, err := parseTime(at)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	return formatTime(t), id, nil
+}
+
+// latestCheckIDs selects the ID of each service's most recent check. IDs
+// don't follow time, as imported checks can be older than those already
+// stored, so they only break ties between checks taken at the same time.
+const latestCheckIDs = `
+	SELECT MAX(c.id) FROM checks c
+	JOIN (SELECT service, MAX(checked_at) AS checked_at FROM checks GROUP BY service) m
+		ON m.service = c.service AND m.checked_at = c.checked_at
+	GROUP BY c.service`
+
 // AllLatest returns the most recent check for each service.
 func (d *DB) AllLatest(ctx context.Context) ([]Check, error) {
 	rows, err := d.db.QueryContext(ctx, `
-		SELECT id, service, status, response_ms, error, checked_at
+		SELECT id, service, status, response_ms, error, checked_at, maintenance
 		FROM checks
-		WHERE id IN (
-			SELECT MAX(id) FROM checks GROUP BY service
-		)
+		WHERE id IN (`+latestCheckIDs+`)
 		ORDER BY service
 	`)
 	if err != nil {
@@ -148,23 +445,67 @@ func (d *DB) AllLatest(ctx context.Context) ([]Check, error) {
 	return scanChecks(rows)
 }
 
-// UptimePercent returns the percentage of "up" checks in the last N checks for a service.
-func (d *DB) UptimePercent(ctx context.Context, service string, last int) (float64, error) {
-	var total int
-	var upCount sql.NullInt64
-	err := d.db.QueryRowContext(ctx, `
-		SELECT COUNT(*), SUM(CASE WHEN status = 'up' THEN 1 ELSE 0 END)
-		FROM (
-			SELECT status FROM checks WHERE service = ? ORDER BY checked_at DESC LIMIT ?
+// ServiceRun is a service's latest check together with the run of identical
+// statuses it ends.
+type ServiceRun struct {
+	Latest Check
+	// Since is when the run started: the first check after the last one
+	// with a different status.
+	Since time.Time
+	// Count is the number of checks in the run.
+	Count int
+}
+
+// CurrentRuns returns the current run of every service that has checks.
+func (d *DB) CurrentRuns(ctx context.Context) ([]ServiceRun, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		WITH latest AS (
+			SELECT id, service, status, response_ms, error, checked_at, maintenance
+			FROM checks
+			WHERE id IN (`+latestCheckIDs+`)
+		),
+		breaks AS (
+			SELECT l.service, (
+				SELECT MAX(c.checked_at) FROM checks c
+				WHERE c.service = l.service AND c.status != l.status
+			) AS broken_at
+			FROM latest l
 		)
-	`, service, last).Scan(&total, &upCount)
+		SELECT l.id, l.service, l.status, l.response_ms, l.error, l.checked_at, l.maintenance,
+			MIN(c.checked_at), COUNT(*)
+		FROM latest l
+		JOIN breaks b ON b.service = l.service
+		JOIN checks c ON c.service = l.service AND c.checked_at > COALESCE(b.broken_at, '')
+		GROUP BY l.id, l.service, l.status, l.response_ms, l.error, l.checked_at, l.maintenance
+		ORDER BY l.service
+	`)
 	if err != nil {
-		return 0, fmt.Errorf("calculating uptime for %q: %w", service, err)
+		return nil, fmt.Errorf("querying current runs: %w", err)
 	}
-	if total == 0 {
-		return 0, nil
+	defer rows.Close()
+
+	var runs []ServiceRun
+	for rows.Next() {
+		var run ServiceRun
+		var checkedAt, since string
+		c := &run.Latest
+		err := rows.Scan(&c.ID, &c.Service, &c.Status, &c.ResponseMs, &c.Error, &checkedAt, &c.Maintenance,
+			&since, &run.Count)
+		if err != nil {
+			return nil, fmt.Errorf("scanning run row: %w", err)
+		}
+		if c.CheckedAt, err = parseTime(checkedAt); err != nil {
+			return nil, fmt.Errorf("parsing checked_at %q: %w", checkedAt, err)
+		}
+		if run.Since, err = parseTime(since); err != nil {
+			return nil, fmt.Errorf("parsing run start %q: %w", since, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating run rows: %w", err)
 	}
-	return float64(upCount.Int64) / float64(total) * 100, nil
+	return runs, nil
 }
 
 type scanner interface {
@@ -174,17 +515,13 @@ type scanner interface {
 func scanCheck(row scanner) (*Check, error) {
 	var c Check
 	var checkedAt string
-	err := row.Scan(&c.ID, &c.Service, &c.Status, &c.ResponseMs, &c.Error, &checkedAt)
+	err := row.Scan(&c.ID, &c.Service, &c.Status, &c.ResponseMs, &c.Error, &checkedAt, &c.Maintenance)
 	if err != nil {
 		return nil, err
 	}
-	t, err := time.Parse(time.RFC3339Nano, checkedAt)
+	t, err := parseTime(checkedAt)
 	if err != nil {
-		// Fallback to RFC3339 without sub-second precision.
-		t, err = time.Parse(time.RFC3339, checkedAt)
-		if err != nil {
-			return nil, fmt.Errorf("parsing checked_at %q: %w", checkedAt, err)
-		}
+		return nil, fmt.Errorf("parsing checked_at %q: %w", checkedAt, err)
 	}
 	c.CheckedAt = t
 	return &c, nil
@@ -2,6 +2,11 @@ package storage_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -9,8 +14,14 @@ import (
 	"github.com/hazz-dev/servprobe/internal/storage"
 )
 
+// openTestDB opens an empty in-memory SQLite database or, if
+// SERVPROBE_TEST_POSTGRES_DSN is set, an empty schema in that PostgreSQL
+// database, so that the same tests run against both.
 func openTestDB(t *testing.T) *storage.DB {
 	t.Helper()
+	if dsn := os.Getenv(postgresDSNEnv); dsn != "" {
+		return openPostgresTestDB(t, dsn)
+	}
 	db, err := storage.Open(":memory:")
 	if err != nil {
 		t.Fatalf("opening in-memory DB: %v", err)
@@ -65,6 +76,45 @@ func TestInsertCheck_And_LatestCheck(t *testing.T) {
 	}
 }
 
+func TestInsertChecks_WritesBatchAtomically(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-time.Minute)
+
+	var batch []checker.CheckResult
+	for i, status := range []checker.Status{checker.StatusUp, checker.StatusDown, checker.StatusDown} {
+		r := makeResult("api", status, 10)
+		r.CheckedAt = start.Add(time.Duration(i) * time.Second)
+		batch = append(batch, r)
+	}
+	if err := db.InsertChecks(ctx, batch); err != nil {
+		t.Fatalf("InsertChecks: %v", err)
+	}
+	history, err := db.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Limit: 10})
+	if err != nil {
+		t.Fatalf("ServiceHistory: %v", err)
+	}
+	if history.Total != 3 {
+		t.Errorf("expected 3 checks, got %d", history.Total)
+	}
+	incidents, err := db.ListIncidents(ctx, storage.IncidentQuery{Service: "api"})
+	if err != nil {
+		t.Fatalf("ListIncidents: %v", err)
+	}
+	if len(incidents) != 1 || incidents[0].CheckCount != 2 {
+		t.Errorf("expected one incident of 2 checks, got %+v", incidents)
+	}
+
+	// A result the database rejects fails the whole batch.
+	bad := makeResult("api", checker.Status("unknown"), 10)
+	if err := db.InsertChecks(ctx, []checker.CheckResult{makeResult("api", checker.StatusUp, 10), bad}); err == nil {
+		t.Fatal("expected an error for an invalid status")
+	}
+	if history, _ := db.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Limit: 10}); history.Total != 3 {
+		t.Errorf("expected the failed batch to store nothing, got %d checks", history.Total)
+	}
+}
+
 func TestLatestCheck_ReturnsNilWhenEmpty(t *testing.T) {
 	db := openTestDB(t)
 	got, err := db.LatestCheck(context.Background(), "nonexistent")
@@ -113,41 +163,123 @@ func TestServiceHistory_Pagination(t *testing.T) {
 		}
 	}
 
-	checks, total, err := db.ServiceHistory(ctx, "api", 5, 0)
+	page, err := db.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Limit: 5})
 	if err != nil {
 		t.Fatalf("ServiceHistory: %v", err)
 	}
-	if total != 10 {
-		t.Errorf("expected total 10, got %d", total)
+	if page.Total != 10 {
+		t.Errorf("expected total 10, got %d", page.Total)
 	}
-	if len(checks) != 5 {
-		t.Errorf("expected 5 results, got %d", len(checks))
+	if len(page.Checks) != 5 {
+		t.Errorf("expected 5 results, got %d", len(page.Checks))
 	}
 
 	// Second page
-	checks2, total2, err := db.ServiceHistory(ctx, "api", 5, 5)
+	page2, err := db.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Limit: 5, Offset: 5})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if total2 != 10 {
-		t.Errorf("expected total 10 on page 2, got %d", total2)
+	if page2.Total != 10 {
+		t.Errorf("expected total 10 on page 2, got %d", page2.Total)
+	}
+	if len(page2.Checks) != 5 {
+		t.Errorf("expected 5 results on page 2, got %d", len(page2.Checks))
 	}
-	if len(checks2) != 5 {
-		t.Errorf("expected 5 results on page 2, got %d", len(checks2))
+	if page2.NextCursor != "" {
+		t.Errorf("expected no cursor on the last page, got %q", page2.NextCursor)
+	}
+}
+
+func TestServiceHistory_Cursor(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	// Two checks share a timestamp, so pages must break ties by ID.
+	base := time.Now().Add(-time.Hour).UTC()
+	for i := 0; i < 7; i++ {
+		r := makeResult("api", checker.StatusUp, int64(i))
+		r.CheckedAt = base.Add(time.Duration(min(i, 5)) * time.Minute)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	q := storage.HistoryQuery{Service: "api", Limit: 3}
+	page, err := db.ServiceHistory(ctx, q)
+	if err != nil {
+		t.Fatalf("ServiceHistory: %v", err)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("expected a cursor for the next page")
+	}
+
+	// A check arriving between pages doesn't shift the next one.
+	if err := db.InsertCheck(ctx, makeResult("api", checker.StatusUp, 99)); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int64
+	for _, c := range page.Checks {
+		got = append(got, c.ResponseMs)
+	}
+	for page.NextCursor != "" {
+		q.Cursor = page.NextCursor
+		if page, err = db.ServiceHistory(ctx, q); err != nil {
+			t.Fatalf("ServiceHistory: %v", err)
+		}
+		for _, c := range page.Checks {
+			got = append(got, c.ResponseMs)
+		}
+	}
+	want := []int64{6, 5, 4, 3, 2, 1, 0}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected response times %v across pages, got %v", want, got)
+	}
+
+	if _, err := db.ServiceHistory(ctx, storage.HistoryQuery{Service: "api", Cursor: "bogus"}); !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestServiceHistory_Filters(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Minute)
+	for i, status := range []checker.Status{checker.StatusUp, checker.StatusDown, checker.StatusUp, checker.StatusDown, checker.StatusDown} {
+		r := makeResult("api", status, int64(i))
+		r.CheckedAt = base.Add(time.Duration(i) * time.Minute)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := db.ServiceHistory(ctx, storage.HistoryQuery{
+		Service: "api",
+		From:    base.Add(time.Minute),
+		To:      base.Add(4 * time.Minute),
+		Status:  "down",
+		Limit:   10,
+	})
+	if err != nil {
+		t.Fatalf("ServiceHistory: %v", err)
+	}
+	if page.Total != 2 || len(page.Checks) != 2 || page.Checks[0].ResponseMs != 3 || page.Checks[1].ResponseMs != 1 {
+		t.Errorf("expected the down checks at 3m and 1m, got %d: %+v", page.Total, page.Checks)
 	}
 }
 
 func TestServiceHistory_EmptyDB(t *testing.T) {
 	db := openTestDB(t)
-	checks, total, err := db.ServiceHistory(context.Background(), "api", 10, 0)
+	page, err := db.ServiceHistory(context.Background(), storage.HistoryQuery{Service: "api", Limit: 10})
 	if err != nil {
 		t.Fatalf("ServiceHistory: %v", err)
 	}
-	if total != 0 {
-		t.Errorf("expected total 0, got %d", total)
+	if page.Total != 0 {
+		t.Errorf("expected total 0, got %d", page.Total)
 	}
-	if len(checks) != 0 {
-		t.Errorf("expected 0 results, got %d", len(checks))
+	if len(page.Checks) != 0 {
+		t.Errorf("expected 0 results, got %d", len(page.Checks))
 	}
 }
 
@@ -202,55 +334,74 @@ func TestAllLatest_EmptyDB(t *testing.T) {
 	}
 }
 
-func TestUptimePercent_AllUp(t *testing.T) {
+func TestUptimeBetween_ExcludesMaintenance(t *testing.T) {
 	db := openTestDB(t)
 	ctx := context.Background()
+	start := time.Now().UTC().Add(-time.Hour)
 
-	for i := 0; i < 10; i++ {
-		if err := db.InsertCheck(ctx, makeResult("api", checker.StatusUp, 10)); err != nil {
+	for i := 0; i < 5; i++ {
+		r := makeResult("api", checker.StatusUp, 10)
+		r.CheckedAt = start.Add(time.Duration(2*i) * time.Minute)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+		r = makeResult("api", checker.StatusDown, 10)
+		r.CheckedAt = start.Add(time.Duration(2*i+1) * time.Minute)
+		r.Maintenance = true
+		if err := db.InsertCheck(ctx, r); err != nil {
 			t.Fatal(err)
 		}
 	}
 
-	pct, err := db.UptimePercent(ctx, "api", 10)
+	u, err := db.UptimeBetween(ctx, "api", start, start.Add(10*time.Minute))
 	if err != nil {
-		t.Fatalf("UptimePercent: %v", err)
+		t.Fatalf("UptimeBetween: %v", err)
 	}
-	if pct != 100.0 {
-		t.Errorf("expected 100%%, got %.2f", pct)
+	if u.Percent() != 100 || u.Known != 5*time.Minute {
+		t.Errorf("expected 5 minutes up with maintenance excluded, got %+v", u)
 	}
-}
-
-func TestUptimePercent_HalfUp(t *testing.T) {
-	db := openTestDB(t)
-	ctx := context.Background()
 
-	for i := 0; i < 5; i++ {
-		if err := db.InsertCheck(ctx, makeResult("api", checker.StatusUp, 10)); err != nil {
-			t.Fatal(err)
-		}
-		if err := db.InsertCheck(ctx, makeResult("api", checker.StatusDown, 10)); err != nil {
-			t.Fatal(err)
-		}
+	latest, err := db.LatestCheck(ctx, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !latest.Maintenance {
+		t.Error("expected latest check to be flagged as maintenance")
 	}
+}
 
-	pct, err := db.UptimePercent(ctx, "api", 10)
+func TestOpen_AddsColumnsToExistingDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	raw, err := sql.Open("sqlite", path)
 	if err != nil {
-		t.Fatalf("UptimePercent: %v", err)
+		t.Fatal(err)
 	}
-	if pct != 50.0 {
-		t.Errorf("expected 50%%, got %.2f", pct)
+	_, err = raw.Exec(`CREATE TABLE checks (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		service     TEXT    NOT NULL,
+		status      TEXT    NOT NULL CHECK(status IN ('up', 'down')),
+		response_ms INTEGER NOT NULL,
+		error       TEXT    NOT NULL DEFAULT '',
+		checked_at  TEXT    NOT NULL
+	);
+	INSERT INTO checks (service, status, response_ms, checked_at) VALUES ('api', 'up', 5, '2026-01-01T00:00:00Z');`)
+	raw.Close()
+	if err != nil {
+		t.Fatal(err)
 	}
-}
 
-func TestUptimePercent_EmptyDB(t *testing.T) {
-	db := openTestDB(t)
-	pct, err := db.UptimePercent(context.Background(), "api", 100)
+	db, err := storage.Open(path)
 	if err != nil {
-		t.Fatalf("UptimePercent: %v", err)
+		t.Fatalf("Open on old schema: %v", err)
 	}
-	if pct != 0.0 {
-		t.Errorf("expected 0%%, got %.2f", pct)
+	defer db.Close()
+
+	latest, err := db.LatestCheck(context.Background(), "api")
+	if err != nil {
+		t.Fatalf("LatestCheck: %v", err)
+	}
+	if latest == nil || latest.Maintenance {
+		t.Errorf("expected existing row with maintenance=false, got %+v", latest)
 	}
 }
 
@@ -263,3 +414,37 @@ func TestClose(t *testing.T) {
 		t.Errorf("Close: %v", err)
 	}
 }
+
+func TestCurrentRuns(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	start := time.Now().UTC().Add(-time.Hour)
+
+	insert := func(service string, status checker.Status, at time.Time) {
+		r := makeResult(service, status, 10)
+		r.CheckedAt = at
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("api", checker.StatusUp, start)
+	insert("api", checker.StatusDown, start.Add(time.Minute))
+	insert("api", checker.StatusDown, start.Add(2*time.Minute))
+	insert("db", checker.StatusUp, start)
+	insert("db", checker.StatusUp, start.Add(time.Minute))
+
+	runs, err := db.CurrentRuns(ctx)
+	if err != nil {
+		t.Fatalf("CurrentRuns: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	api, dbRun := runs[0], runs[1]
+	if api.Latest.Status != "down" || api.Count != 2 || !api.Since.Equal(start.Add(time.Minute)) {
+		t.Errorf("unexpected api run: %+v", api)
+	}
+	if dbRun.Latest.Status != "up" || dbRun.Count != 2 || !dbRun.Since.Equal(start) {
+		t.Errorf("unexpected db run: %+v", dbRun)
+	}
+}
@@ -0,0 +1,312 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LatencyStats summarises a service's checks in one bucket of time. Response
+// times are in milliseconds and percentiles use the nearest-rank method, like
+// the rollups.
+type LatencyStats struct {
+	// Bucket is the start of the bucket, in UTC.
+	Bucket time.Time `json:"bucket"`
+	Checks int       `json:"checks"`
+	Up     int       `json:"up"`
+	MinMs  int64     `json:"min_ms"`
+	AvgMs  float64   `json:"avg_ms"`
+	// P50Ms and P99Ms are nil for buckets summarised from rollups, which
+	// don't keep them. P95Ms is then the highest p95 of the rollups.
+	P50Ms *int64 `json:"p50_ms"`
+	P95Ms int64  `json:"p95_ms"`
+	P99Ms *int64 `json:"p99_ms"`
+	MaxMs int64  `json:"max_ms"`
+	// UptimePercent is weighted by duration, see Uptime. It is nil if none
+	// of the bucket is known.
+	UptimePercent *float64 `json:"uptime_percent"`
+}
+
+// ServiceStats returns stats of the service's checks in [from, to), in
+// buckets of size bucket aligned to the Unix epoch, oldest first. Buckets
+// without checks are left out. Like uptime, stats leave out checks taken
+// during maintenance or while the service was paused.
+//
+// Buckets are summarised from raw checks where those are still kept, and
+// before that from hourly or, once those are gone too, daily rollups; a
+// bucket smaller than the rollups is then as coarse as they are.
+func (d *DB) ServiceStats(ctx context.Context, service string, from, to time.Time, bucket time.Duration) ([]LatencyStats, error) {
+	if bucket < time.Second || bucket%time.Second != 0 {
+		return nil, fmt.Errorf("bucket %s: must be a whole number of seconds", bucket)
+	}
+	from, to = from.UTC(), to.UTC()
+
+	// Each source covers the range from its first bucket up to where the
+	// next, finer one starts.
+	rawFrom, ok, err := d.statsStart(ctx, `SELECT MIN(checked_at) FROM checks WHERE service = ?`, service, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		rawFrom = to
+	}
+	hourlyFrom, ok, err := d.statsStart(ctx, `SELECT MIN(bucket) FROM `+Hourly.table()+` WHERE service = ?`, service, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		hourlyFrom = rawFrom
+	}
+	hourlyFrom = earlier(hourlyFrom, rawFrom)
+
+	stats := []LatencyStats{}
+	for _, src := range []struct {
+		res      Resolution
+		from, to time.Time
+	}{
+		{Daily, from, earlier(hourlyFrom, to)},
+		{Hourly, later(from, hourlyFrom), earlier(rawFrom, to)},
+	} {
+		if !src.from.Before(src.to) {
+			continue
+		}
+		s, err := d.rollupStats(ctx, service, src.res, src.from, src.to, bucket)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s...)
+	}
+	if f := later(from, rawFrom); f.Before(to) {
+		s, err := d.rawStats(ctx, service, f, to, bucket)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s...)
+	}
+	return stats, nil
+}
+
+// statsStart returns the start of the bucket, of size bucket, of the
+// earliest time query selects for service. ok is false if query selects
+// nothing.
+func (d *DB) statsStart(ctx context.Context, query, service string, bucket time.Duration) (start time.Time, ok bool, err error) {
+	var first sql.NullString
+	if err := d.db.QueryRowContext(ctx, query, service).Scan(&first); err != nil {
+		return time.Time{}, false, fmt.Errorf("finding the start of stats for %q: %w", service, err)
+	}
+	if !first.Valid {
+		return time.Time{}, false, nil
+	}
+	t, err := parseTime(first.String)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("parsing %q: %w", first.String, err)
+	}
+	secs := int64(bucket / time.Second)
+	return time.Unix(t.Unix()/secs*secs, 0).UTC(), true, nil
+}
+
+// rawStats summarises the service's raw checks in [from, to).
+func (d *DB) rawStats(ctx context.Context, service string, from, to time.Time, bucket time.Duration) ([]LatencyStats, error) {
+	secs := int64(bucket / time.Second)
+
+	// Percentiles take the first response time, in order, whose rank is at
+	// least p percent of the bucket's checks.
+	rows, err := d.db.QueryContext(ctx, `
+		WITH bucketed AS (
+			SELECT `+d.db.dialect.unixSeconds("c.checked_at")+` / ? AS b, c.status, c.response_ms
+			FROM checks c
+			WHERE c.service = ? AND c.checked_at >= ? AND c.checked_at < ? AND `+countedChecks+`
+		),
+		ranked AS (
+			SELECT b, status, response_ms,
+				ROW_NUMBER() OVER (PARTITION BY b ORDER BY response_ms) AS rn,
+				COUNT(*) OVER (PARTITION BY b) AS n
+			FROM bucketed
+		)
+		SELECT b, COUNT(*), SUM(CASE WHEN status = 'up' THEN 1 ELSE 0 END),
+			MIN(response_ms), AVG(CAST(response_ms AS DOUBLE PRECISION)),
+			MIN(CASE WHEN rn * 100 >= n * 50 THEN response_ms END),
+			MIN(CASE WHEN rn * 100 >= n * 95 THEN response_ms END),
+			MIN(CASE WHEN rn * 100 >= n * 99 THEN response_ms END),
+			MAX(response_ms)
+		FROM ranked
+		GROUP BY b
+		ORDER BY b
+	`, secs, service, formatTime(from), formatTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("querying stats for %q: %w", service, err)
+	}
+	defer rows.Close()
+
+	stats := []LatencyStats{}
+	index := map[int64]int{}
+	for rows.Next() {
+		var (
+			s        LatencyStats
+			idx      int64
+			p50, p99 int64
+		)
+		if err := rows.Scan(&idx, &s.Checks, &s.Up, &s.MinMs, &s.AvgMs, &p50, &s.P95Ms, &p99, &s.MaxMs); err != nil {
+			return nil, fmt.Errorf("scanning stats row: %w", err)
+		}
+		s.Bucket = time.Unix(idx*secs, 0).UTC()
+		s.P50Ms, s.P99Ms = &p50, &p99
+		index[idx] = len(stats)
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating stats rows: %w", err)
+	}
+	rows.Close()
+
+	if len(stats) == 0 {
+		return stats, nil
+	}
+	uptimes, err := d.bucketUptimes(ctx, service, from, earlier(to, time.Now()), secs)
+	if err != nil {
+		return nil, err
+	}
+	for idx, u := range uptimes {
+		if i, ok := index[idx]; ok && u.Known > 0 {
+			pct := u.Percent()
+			stats[i].UptimePercent = &pct
+		}
+	}
+	return stats, nil
+}
+
+// bucketUptimes computes the service's uptime in [from, to) by bucket of
+// secs seconds, like uptimeOf but in the database: each counted check lasts
+// until the next check, the next pause or maxCheckSpan, whichever comes
+// first, and is split across the buckets it overlaps.
+func (d *DB) bucketUptimes(ctx context.Context, service string, from, to time.Time, secs int64) (map[int64]Uptime, error) {
+	out := map[int64]Uptime{}
+	if !from.Before(to) {
+		return out, nil
+	}
+	span := maxCheckSpan.Seconds()
+	lo := float64(from.UnixNano()) / 1e9
+	hi := float64(to.UnixNano()) / 1e9
+	// A check spans at most this many bucket boundaries.
+	steps := min((int64(span)+secs-1)/secs, to.Unix()/secs-from.Unix()/secs)
+
+	dl := d.db.dialect
+	rows, err := d.db.QueryContext(ctx, `
+		WITH RECURSIVE steps(k) AS (
+			SELECT 0 UNION ALL SELECT k + 1 FROM steps WHERE k < ?
+		),
+		timed AS (
+			SELECT c.service, c.checked_at, c.maintenance, c.status,
+				`+dl.unixSeconds("c.checked_at")+` AS sec,
+				`+dl.epochSeconds("c.checked_at")+` AS at
+			FROM checks c
+			WHERE c.service = ? AND c.checked_at >= ? AND c.checked_at < ?
+		),
+		ordered AS (
+			SELECT c.*, LEAD(c.at) OVER (ORDER BY c.checked_at) AS next_at
+			FROM timed c
+		),
+		counted AS (
+			SELECT c.status, c.sec, c.at,
+				CASE WHEN c.next_at IS NOT NULL AND c.next_at < c.at + ? THEN c.next_at ELSE c.at + ? END AS end_at,
+				(SELECT MIN(`+dl.epochSeconds("p.started_at")+`) FROM pauses p
+				 WHERE p.service = c.service AND p.started_at > c.checked_at) AS pause_at
+			FROM ordered c
+			WHERE `+countedChecks+`
+		),
+		spans AS (
+			SELECT status,
+				CASE WHEN at < ? THEN ? ELSE sec / ? END AS first_b,
+				CASE WHEN at < ? THEN ? ELSE at END AS s,
+				CASE WHEN pause_at IS NOT NULL AND pause_at < end_at AND pause_at < ? THEN pause_at
+				     WHEN end_at < ? THEN end_at ELSE ? END AS e
+			FROM counted
+		),
+		pieces AS (
+			SELECT sp.status, sp.first_b + st.k AS b,
+				CASE WHEN sp.e < (sp.first_b + st.k + 1) * ? THEN sp.e ELSE (sp.first_b + st.k + 1) * ? END
+				- CASE WHEN sp.s > (sp.first_b + st.k) * ? THEN sp.s ELSE (sp.first_b + st.k) * ? END AS d
+			FROM spans sp JOIN steps st ON (sp.first_b + st.k) * ? < sp.e
+			WHERE sp.e > sp.s
+		)
+		SELECT b, SUM(CASE WHEN status = 'up' THEN d ELSE 0 END), SUM(d)
+		FROM pieces
+		WHERE d > 0
+		GROUP BY b
+	`,
+		steps,
+		service, formatTime(from.Add(-maxCheckSpan)), formatTime(to),
+		span, span,
+		lo, from.Unix()/secs, secs,
+		lo, lo,
+		hi, hi, hi,
+		secs, secs, secs, secs,
+		secs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("calculating uptime by bucket for %q: %w", service, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			idx       int64
+			up, known float64
+		)
+		if err := rows.Scan(&idx, &up, &known); err != nil {
+			return nil, fmt.Errorf("scanning uptime row: %w", err)
+		}
+		out[idx] = Uptime{
+			Known: time.Duration(known * float64(time.Second)),
+			Up:    time.Duration(up * float64(time.Second)),
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating uptime rows: %w", err)
+	}
+	return out, nil
+}
+
+// rollupStats summarises the service's rollups at res in [from, to).
+func (d *DB) rollupStats(ctx context.Context, service string, res Resolution, from, to time.Time, bucket time.Duration) ([]LatencyStats, error) {
+	secs := int64(bucket / time.Second)
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT `+d.db.dialect.unixSeconds("bucket")+` / ? AS b, SUM(checks), SUM(up), MIN(min_ms),
+			SUM(avg_ms * checks) / SUM(checks), MAX(p95_ms), MAX(max_ms),
+			SUM(up_seconds), SUM(known_seconds)
+		FROM `+res.table()+`
+		WHERE service = ? AND bucket >= ? AND bucket < ?
+		GROUP BY b
+		ORDER BY b
+	`, secs, service, formatTime(from), formatTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("querying %s rollup stats for %q: %w", res, service, err)
+	}
+	defer rows.Close()
+
+	stats := []LatencyStats{}
+	for rows.Next() {
+		var (
+			s         LatencyStats
+			idx       int64
+			up, known float64
+		)
+		if err := rows.Scan(&idx, &s.Checks, &s.Up, &s.MinMs, &s.AvgMs, &s.P95Ms, &s.MaxMs, &up, &known); err != nil {
+			return nil, fmt.Errorf("scanning %s rollup stats row: %w", res, err)
+		}
+		s.Bucket = time.Unix(idx*secs, 0).UTC()
+		u := Uptime{
+			Known: time.Duration(known * float64(time.Second)),
+			Up:    time.Duration(up * float64(time.Second)),
+		}
+		if u.Known > 0 {
+			pct := u.Percent()
+			s.UptimePercent = &pct
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating %s rollup stats rows: %w", res, err)
+	}
+	return stats, nil
+}
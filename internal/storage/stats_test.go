@@ -0,0 +1,194 @@
+package storage_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+func TestServiceStats_BucketsAndPercentiles(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	insert := func(status checker.Status, ms int64, at time.Duration, maintenance bool) {
+		t.Helper()
+		r := makeResult("api", status, ms)
+		r.CheckedAt = t0.Add(at)
+		r.Maintenance = maintenance
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	// The first hour answers in 1..100ms, in shuffled order, every 36s.
+	for i := int64(0); i < 100; i++ {
+		insert(checker.StatusUp, (i*37)%100+1, time.Duration(i)*36*time.Second, false)
+	}
+	// A slow check during maintenance doesn't count.
+	insert(checker.StatusDown, 9000, 30*time.Minute+time.Second, true)
+	// The second hour is down for its first half.
+	for i := 0; i < 4; i++ {
+		status := checker.StatusUp
+		if i < 2 {
+			status = checker.StatusDown
+		}
+		insert(status, 200, time.Hour+time.Duration(i)*15*time.Minute, false)
+	}
+	// Another service doesn't count either.
+	other := makeResult("web", checker.StatusUp, 1)
+	other.CheckedAt = t0
+	if err := db.InsertCheck(ctx, other); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+
+	stats, err := db.ServiceStats(ctx, "api", t0, t0.Add(2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("ServiceStats: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 buckets, got %+v", stats)
+	}
+	first := stats[0]
+	if !first.Bucket.Equal(t0) || first.Checks != 100 || first.Up != 100 ||
+		first.MinMs != 1 || first.AvgMs != 50.5 || *first.P50Ms != 50 || first.P95Ms != 95 || *first.P99Ms != 99 || first.MaxMs != 100 {
+		t.Errorf("unexpected first bucket: %+v", first)
+	}
+	if first.UptimePercent == nil || *first.UptimePercent != 100 {
+		t.Errorf("expected 100%% uptime in the first bucket, got %v", first.UptimePercent)
+	}
+	second := stats[1]
+	if !second.Bucket.Equal(t0.Add(time.Hour)) || second.Checks != 4 || second.Up != 2 || *second.P99Ms != 200 {
+		t.Errorf("unexpected second bucket: %+v", second)
+	}
+	if second.UptimePercent == nil || *second.UptimePercent != 50 {
+		t.Errorf("expected 50%% uptime in the second bucket, got %v", second.UptimePercent)
+	}
+
+	// Buckets are aligned to the epoch, not to from.
+	stats, err = db.ServiceStats(ctx, "api", t0.Add(90*time.Minute), t0.Add(3*time.Hour), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("ServiceStats: %v", err)
+	}
+	if len(stats) != 1 || !stats[0].Bucket.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) || stats[0].Checks != 2 {
+		t.Errorf("expected one day bucket of 2 checks, got %+v", stats)
+	}
+
+	if _, err := db.ServiceStats(ctx, "api", t0, t0.Add(time.Hour), 1500*time.Millisecond); err == nil {
+		t.Error("expected error for a fractional bucket")
+	}
+}
+
+func TestServiceStats_UptimeMatchesUptimeBetween(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Uneven intervals, a long gap, a pause and a maintenance check.
+	at := time.Duration(0)
+	for i := range 60 {
+		at += time.Duration(17+i*7%50) * time.Second
+		if i == 30 {
+			at += 90 * time.Minute
+		}
+		r := makeResult("api", checker.StatusUp, 10)
+		if i%7 < 2 {
+			r.Status = checker.StatusDown
+		}
+		r.Maintenance = i == 12
+		r.CheckedAt = t0.Add(at).Add(123 * time.Millisecond)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	if err := db.PauseService(ctx, "api", t0.Add(5*time.Minute)); err != nil {
+		t.Fatalf("PauseService: %v", err)
+	}
+	if err := db.ResumeService(ctx, "api", t0.Add(8*time.Minute)); err != nil {
+		t.Fatalf("ResumeService: %v", err)
+	}
+
+	from, to := t0.Add(3*time.Minute), t0.Add(4*time.Hour)
+	for _, bucket := range []time.Duration{time.Minute, 5 * time.Minute, time.Hour} {
+		stats, err := db.ServiceStats(ctx, "api", from, to, bucket)
+		if err != nil {
+			t.Fatalf("ServiceStats: %v", err)
+		}
+		for _, s := range stats {
+			lo, hi := s.Bucket, s.Bucket.Add(bucket)
+			if lo.Before(from) {
+				lo = from
+			}
+			want, err := db.UptimeBetween(ctx, "api", lo, hi)
+			if err != nil {
+				t.Fatalf("UptimeBetween: %v", err)
+			}
+			switch {
+			case want.Known == 0 && s.UptimePercent != nil:
+				t.Errorf("%s bucket %s: expected unknown uptime, got %v", bucket, s.Bucket, *s.UptimePercent)
+			case want.Known > 0 && s.UptimePercent == nil:
+				t.Errorf("%s bucket %s: expected %v%% uptime, got unknown", bucket, s.Bucket, want.Percent())
+			case want.Known > 0 && math.Abs(*s.UptimePercent-want.Percent()) > 1e-6:
+				t.Errorf("%s bucket %s: expected %v%% uptime, got %v", bucket, s.Bucket, want.Percent(), *s.UptimePercent)
+			}
+		}
+	}
+}
+
+func TestServiceStats_FromRollups(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Three days of checks every 10 minutes, down from 05:00 to 06:00.
+	for m := 0; m < 72*60; m += 10 {
+		status := checker.StatusUp
+		if m/60%24 == 5 {
+			status = checker.StatusDown
+		}
+		r := makeResult("api", status, int64(m%60+10))
+		r.CheckedAt = t0.Add(time.Duration(m) * time.Minute)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	// Raw checks are kept for the last day, hourly rollups for two.
+	now := t0.Add(72*time.Hour + 30*time.Minute)
+	if err := db.Compact(ctx, now, config.RetentionConfig{RawDays: 1, HourlyDays: 2}); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	stats, err := db.ServiceStats(ctx, "api", t0, t0.Add(72*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatalf("ServiceStats: %v", err)
+	}
+	// Day one from daily rollups, day two from hourly ones, day three raw.
+	if len(stats) != 1+24+24 {
+		t.Fatalf("expected 49 buckets, got %d", len(stats))
+	}
+	daily := stats[0]
+	if !daily.Bucket.Equal(t0) || daily.Checks != 144 || daily.Up != 138 || daily.P50Ms != nil || daily.P95Ms != 60 {
+		t.Errorf("unexpected bucket from daily rollups: %+v", daily)
+	}
+	hourly := stats[1+5]
+	if !hourly.Bucket.Equal(t0.Add(29*time.Hour)) || hourly.Checks != 6 || hourly.Up != 0 || hourly.P99Ms != nil ||
+		hourly.AvgMs != 35 || hourly.UptimePercent == nil || *hourly.UptimePercent != 0 {
+		t.Errorf("unexpected bucket from hourly rollups: %+v", hourly)
+	}
+	raw := stats[1+24]
+	if !raw.Bucket.Equal(t0.Add(48*time.Hour)) || raw.Checks != 6 || raw.P50Ms == nil || *raw.P50Ms != 30 {
+		t.Errorf("unexpected bucket from raw checks: %+v", raw)
+	}
+
+	// Daily buckets add up the hourly rollups of a day.
+	stats, err = db.ServiceStats(ctx, "api", t0, t0.Add(72*time.Hour), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("ServiceStats: %v", err)
+	}
+	if len(stats) != 3 || stats[1].Checks != 144 || stats[1].Up != 138 || stats[1].UptimePercent == nil {
+		t.Errorf("unexpected daily buckets: %+v", stats)
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// Store is everything servprobe keeps in its database. DB implements it on
+// both SQLite and PostgreSQL.
+type Store interface {
+	// Checks
+	InsertCheck(ctx context.Context, r checker.CheckResult) error
+	InsertChecks(ctx context.Context, results []checker.CheckResult) error
+	AllLatest(ctx context.Context) ([]Check, error)
+	CurrentRuns(ctx context.Context) ([]ServiceRun, error)
+	ServiceHistory(ctx context.Context, q HistoryQuery) (HistoryPage, error)
+	UptimeBetween(ctx context.Context, service string, from, to time.Time) (Uptime, error)
+	UptimesBetween(ctx context.Context, from, to time.Time) (map[string]Uptime, error)
+	ExportChecks(ctx context.Context, q ExportQuery, fn func(Check) error) error
+	ImportChecks(ctx context.Context, checks []Check) (int, error)
+
+	// Rollups, stats and retention
+	Rollups(ctx context.Context, service string, res Resolution, from, to time.Time) ([]Rollup, error)
+	ServiceStats(ctx context.Context, service string, from, to time.Time, bucket time.Duration) ([]LatencyStats, error)
+	Compact(ctx context.Context, now time.Time, policy config.RetentionConfig) error
+
+	// Services managed through the API, and pauses
+	InsertService(ctx context.Context, svc config.Service) error
+	UpdateService(ctx context.Context, svc config.Service) error
+	DeleteService(ctx context.Context, name string) error
+	ListServices(ctx context.Context) ([]config.Service, error)
+	PauseService(ctx context.Context, service string, at time.Time) error
+	ResumeService(ctx context.Context, service string, at time.Time) error
+	ListPaused(ctx context.Context) (map[string]time.Time, error)
+
+	// Maintenance windows
+	InsertMaintenance(ctx context.Context, w MaintenanceWindow) (MaintenanceWindow, error)
+	ListMaintenance(ctx context.Context, since time.Time) ([]MaintenanceWindow, error)
+	DeleteMaintenance(ctx context.Context, id int64) error
+
+	// Alerts and incidents
+	EnqueueAlert(ctx context.Context, a AlertDelivery) (AlertDelivery, error)
+	ClaimDueAlerts(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]AlertDelivery, error)
+	ListAlerts(ctx context.Context, status string, limit int) ([]AlertDelivery, error)
+	MarkAlertSent(ctx context.Context, id int64, attempts int, at time.Time) error
+	MarkAlertFailed(ctx context.Context, id int64, attempts int, lastErr string, next time.Time) error
+	SaveOutage(ctx context.Context, o AlertOutage) error
+	DeleteOutage(ctx context.Context, service string) error
+	ListOutages(ctx context.Context) ([]AlertOutage, error)
+	ListIncidents(ctx context.Context, q IncidentQuery) ([]Incident, error)
+
+	DBStats() sql.DBStats
+	Close() error
+}
+
+var _ Store = (*DB)(nil)
+
+// Connect opens the database cfg selects and applies pending migrations.
+func Connect(cfg config.StorageConfig) (Store, error) {
+	var (
+		db  *DB
+		err error
+	)
+	switch cfg.Driver {
+	case config.DriverPostgres:
+		db, err = OpenPostgres(cfg.DSN)
+	case "", config.DriverSQLite:
+		db, err = Open(cfg.Path)
+	default:
+		err = fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// openConn opens the database cfg selects without migrating it.
+func openConn(cfg config.StorageConfig) (*dbConn, error) {
+	switch cfg.Driver {
+	case config.DriverPostgres:
+		return openPostgres(cfg.DSN)
+	case "", config.DriverSQLite:
+		return openSQLite(cfg.Path)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
@@ -0,0 +1,126 @@
+-- A database as created by servprobe before schema versioning, with a few
+-- rows. Used to test upgrading existing databases.
+CREATE TABLE IF NOT EXISTS checks (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    service     TEXT    NOT NULL,
+    status      TEXT    NOT NULL CHECK(status IN ('up', 'down')),
+    response_ms INTEGER NOT NULL,
+    error       TEXT    NOT NULL DEFAULT '',
+    checked_at  TEXT    NOT NULL,
+    maintenance INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_checks_service ON checks(service);
+CREATE INDEX IF NOT EXISTS idx_checks_checked_at ON checks(checked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_checks_service_checked ON checks(service, checked_at DESC);
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    name       TEXT    NOT NULL DEFAULT '',
+    services   TEXT    NOT NULL DEFAULT '[]',
+    tags       TEXT    NOT NULL DEFAULT '[]',
+    starts_at  TEXT    NOT NULL,
+    ends_at    TEXT    NOT NULL,
+    created_at TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_ends_at ON maintenance_windows(ends_at);
+
+CREATE TABLE IF NOT EXISTS services (
+    name            TEXT    PRIMARY KEY,
+    type            TEXT    NOT NULL,
+    target          TEXT    NOT NULL,
+    interval        TEXT    NOT NULL,
+    timeout         TEXT    NOT NULL,
+    expected_status INTEGER NOT NULL DEFAULT 0,
+    headers         TEXT    NOT NULL DEFAULT '{}',
+    tags            TEXT    NOT NULL DEFAULT '[]',
+    created_at      TEXT    NOT NULL,
+    updated_at      TEXT    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pauses (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    service    TEXT    NOT NULL,
+    started_at TEXT    NOT NULL,
+    ended_at   TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_pauses_service ON pauses(service, started_at);
+
+CREATE TABLE IF NOT EXISTS alert_outbox (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    service         TEXT    NOT NULL,
+    channel         TEXT    NOT NULL,
+    payload         TEXT    NOT NULL,
+    status          TEXT    NOT NULL CHECK(status IN ('pending', 'sent', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT    NOT NULL DEFAULT '',
+    created_at      TEXT    NOT NULL,
+    next_attempt_at TEXT    NOT NULL,
+    sent_at         TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_outbox_due ON alert_outbox(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS alert_outages (
+    service          TEXT    PRIMARY KEY,
+    started_at       TEXT    NOT NULL,
+    error            TEXT    NOT NULL DEFAULT '',
+    last_notified_at TEXT    NOT NULL,
+    escalation_step  INTEGER NOT NULL DEFAULT 0,
+    escalated        TEXT    NOT NULL DEFAULT '[]'
+);
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    service     TEXT    NOT NULL,
+    started_at  TEXT    NOT NULL,
+    resolved_at TEXT,
+    first_error TEXT    NOT NULL DEFAULT '',
+    check_count INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_started_at ON incidents(started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_incidents_service ON incidents(service, started_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_open ON incidents(service) WHERE resolved_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS check_rollups_hourly (
+    service TEXT    NOT NULL,
+    bucket  TEXT    NOT NULL,
+    checks  INTEGER NOT NULL,
+    up      INTEGER NOT NULL,
+    min_ms  INTEGER NOT NULL,
+    avg_ms  REAL    NOT NULL,
+    p95_ms  INTEGER NOT NULL,
+    max_ms  INTEGER NOT NULL,
+    PRIMARY KEY (service, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_check_rollups_hourly_bucket ON check_rollups_hourly(bucket);
+
+CREATE TABLE IF NOT EXISTS check_rollups_daily (
+    service TEXT    NOT NULL,
+    bucket  TEXT    NOT NULL,
+    checks  INTEGER NOT NULL,
+    up      INTEGER NOT NULL,
+    min_ms  INTEGER NOT NULL,
+    avg_ms  REAL    NOT NULL,
+    p95_ms  INTEGER NOT NULL,
+    max_ms  INTEGER NOT NULL,
+    PRIMARY KEY (service, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_check_rollups_daily_bucket ON check_rollups_daily(bucket);
+
+INSERT INTO checks (service, status, response_ms, error, checked_at, maintenance) VALUES
+    ('api', 'up', 42, '', '2026-03-01T10:00:00.000000000Z', 0),
+    ('api', 'down', 5000, 'timeout', '2026-03-01T10:00:30.000000000Z', 0),
+    ('api', 'up', 40, '', '2026-03-01T10:01:00.000000000Z', 0),
+    ('db', 'up', 3, '', '2026-03-01T10:00:00.000000000Z', 1);
+
+INSERT INTO incidents (service, started_at, resolved_at, first_error, check_count) VALUES
+    ('api', '2026-03-01T10:00:30.000000000Z', '2026-03-01T10:01:00.000000000Z', 'timeout', 1);
+
+INSERT INTO pauses (service, started_at, ended_at) VALUES
+    ('db', '2026-03-01T09:00:00.000000000Z', '2026-03-01T09:30:00.000000000Z');
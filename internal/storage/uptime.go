@@ -0,0 +1,327 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// maxCheckSpan is the longest a check's status is assumed to last. Longer
+// gaps between checks, e.g. while servprobe wasn't running, are unknown.
+const maxCheckSpan = time.Hour
+
+// Uptime is how long a service was up within a range, weighted by duration
+// rather than by number of checks: each check's status lasts until the
+// service's next check, for at most an hour. Time covered by checks taken
+// during maintenance, and time the service was paused, is unknown.
+type Uptime struct {
+	// Known is the time covered by checks that count towards uptime.
+	Known time.Duration
+	Up    time.Duration
+}
+
+// Percent returns the share of the known time the service was up, or 0 if
+// none of it is known.
+func (u Uptime) Percent() float64 {
+	if u.Known <= 0 {
+		return 0
+	}
+	return float64(u.Up) / float64(u.Known) * 100
+}
+
+func (u *Uptime) add(o Uptime) {
+	u.Known += o.Known
+	u.Up += o.Up
+}
+
+type timeRange struct {
+	from, to time.Time
+}
+
+// UptimeBetween returns a service's uptime in [from, to). Whole days and
+// hours are read from the rollups where they exist and the rest is computed
+// from raw checks, so ranges older than the raw checks kept are answered to
+// the precision of the rollup buckets.
+func (d *DB) UptimeBetween(ctx context.Context, service string, from, to time.Time) (Uptime, error) {
+	total, err := d.uptimeBetween(ctx, service, from, to)
+	if err != nil {
+		return Uptime{}, err
+	}
+	return total[service], nil
+}
+
+// UptimesBetween returns the uptime in [from, to) of every service with
+// checks in the range, like UptimeBetween but in the same few queries
+// however many services there are.
+func (d *DB) UptimesBetween(ctx context.Context, from, to time.Time) (map[string]Uptime, error) {
+	return d.uptimeBetween(ctx, "", from, to)
+}
+
+// uptimeBetween computes the uptime in [from, to) of the service, or of
+// every service if service is empty.
+func (d *DB) uptimeBetween(ctx context.Context, service string, from, to time.Time) (map[string]Uptime, error) {
+	total := map[string]Uptime{}
+	addAll := func(m map[string]Uptime) {
+		for name, u := range m {
+			t := total[name]
+			t.add(u)
+			total[name] = t
+		}
+	}
+	pieces := []timeRange{{from.UTC(), to.UTC()}}
+	for _, res := range []Resolution{Daily, Hourly} {
+		lo, hi, ok, err := d.rollupRange(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		step := res.step()
+		var rest []timeRange
+		for _, p := range pieces {
+			a := later(ceilTime(p.from, step), lo)
+			b := earlier(p.to.Truncate(step), hi)
+			if !a.Before(b) {
+				rest = append(rest, p)
+				continue
+			}
+			u, err := d.rollupUptime(ctx, service, res, a, b)
+			if err != nil {
+				return nil, err
+			}
+			addAll(u)
+			if p.from.Before(a) {
+				rest = append(rest, timeRange{p.from, a})
+			}
+			if b.Before(p.to) {
+				rest = append(rest, timeRange{b, p.to})
+			}
+		}
+		pieces = rest
+	}
+
+	for _, p := range pieces {
+		u, err := d.uptimes(ctx, service, p.from, p.to)
+		if err != nil {
+			return nil, err
+		}
+		addAll(u)
+	}
+	return total, nil
+}
+
+// rollupRange returns the range covered by the rollups at res.
+func (d *DB) rollupRange(ctx context.Context, res Resolution) (from, to time.Time, ok bool, err error) {
+	var lo, hi sql.NullString
+	if err := d.db.QueryRowContext(ctx, `SELECT MIN(bucket), MAX(bucket) FROM `+res.table()).Scan(&lo, &hi); err != nil {
+		return from, to, false, fmt.Errorf("finding range of %s rollups: %w", res, err)
+	}
+	if !lo.Valid {
+		return from, to, false, nil
+	}
+	if from, err = parseTime(lo.String); err != nil {
+		return from, to, false, fmt.Errorf("parsing bucket %q: %w", lo.String, err)
+	}
+	if to, err = parseTime(hi.String); err != nil {
+		return from, to, false, fmt.Errorf("parsing bucket %q: %w", hi.String, err)
+	}
+	return from.UTC(), to.UTC().Add(res.step()), true, nil
+}
+
+// rollupUptime sums the uptime in the rollups at res of buckets in
+// [from, to), by service. An empty service sums every service.
+func (d *DB) rollupUptime(ctx context.Context, service string, res Resolution, from, to time.Time) (map[string]Uptime, error) {
+	query := `SELECT service, SUM(up_seconds), SUM(known_seconds) FROM ` + res.table() + ` WHERE bucket >= ? AND bucket < ?`
+	args := []any{formatTime(from), formatTime(to)}
+	if service != "" {
+		query += ` AND service = ?`
+		args = append(args, service)
+	}
+	rows, err := d.db.QueryContext(ctx, query+` GROUP BY service`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("calculating uptime from %s rollups: %w", res, err)
+	}
+	defer rows.Close()
+	out := map[string]Uptime{}
+	for rows.Next() {
+		var (
+			name      string
+			up, known float64
+		)
+		if err := rows.Scan(&name, &up, &known); err != nil {
+			return nil, fmt.Errorf("scanning %s rollup uptime: %w", res, err)
+		}
+		out[name] = Uptime{
+			Known: time.Duration(known * float64(time.Second)),
+			Up:    time.Duration(up * float64(time.Second)),
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating %s rollup uptime: %w", res, err)
+	}
+	return out, nil
+}
+
+// uptimes computes the uptime in [from, to) of the service, or of every
+// service if service is empty, from raw checks. The future is unknown.
+func (d *DB) uptimes(ctx context.Context, service string, from, to time.Time) (map[string]Uptime, error) {
+	to = earlier(to, time.Now())
+	out := map[string]Uptime{}
+	if !from.Before(to) {
+		return out, nil
+	}
+	checks, pauses, err := d.spanChecks(ctx, service, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for name, cs := range checks {
+		out[name] = uptimeOf(cs, pauses[name], from, to)
+	}
+	return out, nil
+}
+
+// spanChecks returns the checks, in order, and pauses by service that
+// uptimeOf needs to weigh [from, to): checks up to maxCheckSpan before from
+// may last into the range, and the first check after it ends the last one in
+// the range.
+func (d *DB) spanChecks(ctx context.Context, service string, from, to time.Time) (map[string][]spanCheck, map[string][]timeRange, error) {
+	lo, hi := from.Add(-maxCheckSpan), to.Add(maxCheckSpan)
+
+	pauses, err := d.pauseRanges(ctx, service, lo, hi)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := `SELECT service, status, checked_at, maintenance FROM checks WHERE checked_at >= ? AND checked_at < ?`
+	args := []any{formatTime(lo), formatTime(hi)}
+	if service != "" {
+		query += ` AND service = ?`
+		args = append(args, service)
+	}
+	rows, err := d.db.QueryContext(ctx, query+` ORDER BY service, checked_at`, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying checks for uptime: %w", err)
+	}
+	defer rows.Close()
+
+	checks := map[string][]spanCheck{}
+	for rows.Next() {
+		var (
+			name, status, checkedAt string
+			c                       spanCheck
+		)
+		if err := rows.Scan(&name, &status, &checkedAt, &c.maintenance); err != nil {
+			return nil, nil, fmt.Errorf("scanning check row: %w", err)
+		}
+		if c.at, err = parseTime(checkedAt); err != nil {
+			return nil, nil, fmt.Errorf("parsing checked_at %q: %w", checkedAt, err)
+		}
+		c.up = status == "up"
+		checks[name] = append(checks[name], c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterating check rows: %w", err)
+	}
+	return checks, pauses, nil
+}
+
+// pauseRanges returns the pauses overlapping [from, to) by service. Pauses
+// still in effect end at to.
+func (d *DB) pauseRanges(ctx context.Context, service string, from, to time.Time) (map[string][]timeRange, error) {
+	query := `SELECT service, started_at, COALESCE(ended_at, ?) FROM pauses WHERE started_at < ? AND (ended_at IS NULL OR ended_at > ?)`
+	args := []any{formatTime(to), formatTime(to), formatTime(from)}
+	if service != "" {
+		query += ` AND service = ?`
+		args = append(args, service)
+	}
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying pauses: %w", err)
+	}
+	defer rows.Close()
+
+	pauses := map[string][]timeRange{}
+	for rows.Next() {
+		var name, started, ended string
+		if err := rows.Scan(&name, &started, &ended); err != nil {
+			return nil, fmt.Errorf("scanning pause row: %w", err)
+		}
+		var p timeRange
+		if p.from, err = parseTime(started); err != nil {
+			return nil, fmt.Errorf("parsing started_at %q: %w", started, err)
+		}
+		if p.to, err = parseTime(ended); err != nil {
+			return nil, fmt.Errorf("parsing ended_at %q: %w", ended, err)
+		}
+		pauses[name] = append(pauses[name], p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating pause rows: %w", err)
+	}
+	return pauses, nil
+}
+
+type spanCheck struct {
+	at          time.Time
+	up          bool
+	maintenance bool
+}
+
+// uptimeOf weighs one service's checks, in order, by how long each status
+// lasted within [from, to).
+func uptimeOf(checks []spanCheck, pauses []timeRange, from, to time.Time) Uptime {
+	var u Uptime
+	for i, c := range checks {
+		if c.maintenance {
+			continue
+		}
+		end := c.at.Add(maxCheckSpan)
+		if i+1 < len(checks) {
+			end = earlier(end, checks[i+1].at)
+		}
+		paused := false
+		for _, p := range pauses {
+			if !c.at.Before(p.from) && c.at.Before(p.to) {
+				paused = true
+				break
+			}
+			if p.from.After(c.at) {
+				end = earlier(end, p.from)
+			}
+		}
+		if paused {
+			continue
+		}
+		if d := earlier(end, to).Sub(later(c.at, from)); d > 0 {
+			u.Known += d
+			if c.up {
+				u.Up += d
+			}
+		}
+	}
+	return u
+}
+
+// ceilTime rounds t up to a multiple of step.
+func ceilTime(t time.Time, step time.Duration) time.Time {
+	if f := t.Truncate(step); !f.Equal(t) {
+		return f.Add(step)
+	}
+	return t
+}
+
+func earlier(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
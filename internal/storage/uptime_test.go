@@ -0,0 +1,119 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+func TestUptimeBetween_WeightsByDuration(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	insert := func(service string, status checker.Status, at time.Duration) {
+		t.Helper()
+		r := makeResult(service, status, 10)
+		r.CheckedAt = t0.Add(at)
+		if err := db.InsertCheck(ctx, r); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	// Both services are down from 12:20 to 12:30, one checked every 10
+	// seconds, the other every 5 minutes.
+	for at := time.Duration(0); at < time.Hour; at += 10 * time.Second {
+		status := checker.StatusUp
+		if at >= 20*time.Minute && at < 30*time.Minute {
+			status = checker.StatusDown
+		}
+		insert("fast", status, at)
+	}
+	for at := time.Duration(0); at < time.Hour; at += 5 * time.Minute {
+		status := checker.StatusUp
+		if at >= 20*time.Minute && at < 30*time.Minute {
+			status = checker.StatusDown
+		}
+		insert("slow", status, at)
+	}
+
+	for _, service := range []string{"fast", "slow"} {
+		u, err := db.UptimeBetween(ctx, service, t0, t0.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("UptimeBetween: %v", err)
+		}
+		if u.Known != time.Hour || u.Up != 50*time.Minute {
+			t.Errorf("%s: expected 50m up of 1h, got %s of %s", service, u.Up, u.Known)
+		}
+	}
+
+	// The paused period is unknown rather than down.
+	if err := db.PauseService(ctx, "slow", t0.Add(20*time.Minute)); err != nil {
+		t.Fatalf("PauseService: %v", err)
+	}
+	if err := db.ResumeService(ctx, "slow", t0.Add(40*time.Minute)); err != nil {
+		t.Fatalf("ResumeService: %v", err)
+	}
+	u, err := db.UptimeBetween(ctx, "slow", t0, t0.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("UptimeBetween: %v", err)
+	}
+	if u.Known != 40*time.Minute || u.Up != 40*time.Minute || u.Percent() != 100 {
+		t.Errorf("expected 40m up of 40m with the pause left out, got %s of %s", u.Up, u.Known)
+	}
+
+	// After the last check its status lasts at most an hour.
+	u, _ = db.UptimeBetween(ctx, "fast", t0.Add(time.Hour), t0.Add(3*time.Hour))
+	if u.Known != time.Hour-10*time.Second {
+		t.Errorf("expected the last check to cover at most an hour, got %s", u.Known)
+	}
+}
+
+func TestUptimesBetween_AllServices(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Two days of checks every 10 minutes; db is down for the first hour
+	// of each day.
+	for m := 0; m < 48*60; m += 10 {
+		for _, service := range []string{"api", "db"} {
+			status := checker.StatusUp
+			if service == "db" && m/60%24 == 0 {
+				status = checker.StatusDown
+			}
+			r := makeResult(service, status, 10)
+			r.CheckedAt = t0.Add(time.Duration(m) * time.Minute)
+			if err := db.InsertCheck(ctx, r); err != nil {
+				t.Fatalf("InsertCheck: %v", err)
+			}
+		}
+	}
+	// The first day is answered from rollups, the second from raw checks.
+	if err := db.Compact(ctx, t0.Add(49*time.Hour), config.RetentionConfig{RawDays: 1}); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	from, to := t0, t0.Add(48*time.Hour)
+	all, err := db.UptimesBetween(ctx, from, to)
+	if err != nil {
+		t.Fatalf("UptimesBetween: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected the uptime of 2 services, got %v", all)
+	}
+	for _, service := range []string{"api", "db"} {
+		want, err := db.UptimeBetween(ctx, service, from, to)
+		if err != nil {
+			t.Fatalf("UptimeBetween: %v", err)
+		}
+		if all[service] != want {
+			t.Errorf("%s: expected %+v, got %+v", service, want, all[service])
+		}
+	}
+	if u := all["db"]; u.Known != 48*time.Hour || u.Up != 46*time.Hour {
+		t.Errorf("expected db down 2 hours, got %s up of %s", u.Up, u.Known)
+	}
+}
@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTP phase spans, children of the check span.
+const (
+	spanDNS       = "http.dns"
+	spanConnect   = "http.connect"
+	spanTLS       = "http.tls"
+	spanFirstByte = "http.first_byte"
+)
+
+// phaseTrace turns the httptrace hooks of an HTTP check into spans for DNS
+// lookup, connecting, the TLS handshake and the wait from sending the
+// request to the first byte of the response. A reused connection has no DNS,
+// connect or TLS span.
+type phaseTrace struct {
+	ctx    context.Context
+	tracer trace.Tracer
+
+	mu sync.Mutex
+	// open holds started spans by name; connect spans are keyed by address
+	// too, as dialing may try several at once.
+	open map[string]trace.Span
+}
+
+func newPhaseTrace(ctx context.Context, tracer trace.Tracer) *phaseTrace {
+	return &phaseTrace{ctx: ctx, tracer: tracer, open: make(map[string]trace.Span)}
+}
+
+func (p *phaseTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			trace.SpanFromContext(p.ctx).SetAttributes(attribute.Bool("http.connection.reused", info.Reused))
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			p.start(spanDNS, spanDNS, attribute.String("net.host.name", info.Host))
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			p.finish(spanDNS, info.Err)
+		},
+		ConnectStart: func(network, addr string) {
+			p.start(spanConnect+" "+addr, spanConnect, attribute.String("net.peer.address", addr))
+		},
+		ConnectDone: func(network, addr string, err error) {
+			p.finish(spanConnect+" "+addr, err)
+		},
+		TLSHandshakeStart: func() {
+			p.start(spanTLS, spanTLS)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			p.finish(spanTLS, err)
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				p.start(spanFirstByte, spanFirstByte)
+			}
+		},
+		GotFirstResponseByte: func() {
+			p.finish(spanFirstByte, nil)
+		},
+	}
+}
+
+// start starts a span called name, tracked under key.
+func (p *phaseTrace) start(key, name string, attrs ...attribute.KeyValue) {
+	_, span := p.tracer.Start(p.ctx, name, trace.WithAttributes(attrs...))
+	p.mu.Lock()
+	p.open[key] = span
+	p.mu.Unlock()
+}
+
+// finish ends the span tracked under key, marking it failed if err is set.
+func (p *phaseTrace) finish(key string, err error) {
+	p.mu.Lock()
+	span, ok := p.open[key]
+	delete(p.open, key)
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// end ends the spans of phases that never finished, e.g. because the check
+// timed out.
+func (p *phaseTrace) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, span := range p.open {
+		span.SetStatus(codes.Error, "not finished")
+		span.End()
+		delete(p.open, key)
+	}
+}
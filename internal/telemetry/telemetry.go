@@ -0,0 +1,206 @@
+// Package telemetry exports check results to an OpenTelemetry collector
+// over OTLP: a span per check, with child spans for the phases of HTTP
+// checks, and metrics of check latency and status.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http/httptrace"
+	"slices"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/version"
+)
+
+// scope names the tracer and meter.
+const scope = "github.com/hazz-dev/servprobe"
+
+// Attributes of check spans and metrics.
+const (
+	attrService = attribute.Key("servprobe.service.name")
+	attrType    = attribute.Key("servprobe.service.type")
+	attrTags    = attribute.Key("servprobe.service.tags")
+	attrStatus  = attribute.Key("servprobe.check.status")
+)
+
+// Telemetry exports checks to an OTLP collector.
+type Telemetry struct {
+	traces  *sdktrace.TracerProvider
+	metrics *sdkmetric.MeterProvider
+	tracer  trace.Tracer
+
+	checks   metric.Int64Counter
+	duration metric.Float64Histogram
+	up       metric.Int64Gauge
+}
+
+// New starts exporting to the collector cfg names. It also makes HTTP checks
+// send W3C traceparent headers, so that backends can join their traces to
+// the check's. Call Shutdown to flush what is left on exit.
+func New(ctx context.Context, cfg config.TelemetryConfig, logger *slog.Logger) (*Telemetry, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	spans, readings, err := exporters(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+		attribute.String("service.version", version.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("building telemetry resource: %w", err)
+	}
+
+	t := &Telemetry{
+		traces: sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(spans),
+			sdktrace.WithResource(res),
+		),
+		metrics: sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(readings,
+				sdkmetric.WithInterval(cfg.MetricInterval.Duration))),
+			sdkmetric.WithResource(res),
+		),
+	}
+	t.tracer = t.traces.Tracer(scope)
+	meter := t.metrics.Meter(scope)
+	t.checks, err = meter.Int64Counter("servprobe.checks",
+		metric.WithDescription("Checks run, by service and status."))
+	if err == nil {
+		t.duration, err = meter.Float64Histogram("servprobe.check.duration",
+			metric.WithDescription("Response time of checks."), metric.WithUnit("s"),
+			metric.WithExplicitBucketBoundaries(.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30))
+	}
+	if err == nil {
+		t.up, err = meter.Int64Gauge("servprobe.service.up",
+			metric.WithDescription("Whether the service's last check found it up (1) or down (0)."))
+	}
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("creating telemetry instruments: %w", err), t.Shutdown(ctx))
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("exporting telemetry", "error", err)
+	}))
+	return t, nil
+}
+
+// exporters creates the span and metric exporters for cfg's protocol. They
+// connect lazily, so a collector that is down doesn't stop servprobe.
+func exporters(ctx context.Context, cfg config.TelemetryConfig) (sdktrace.SpanExporter, sdkmetric.Exporter, error) {
+	var (
+		spans    sdktrace.SpanExporter
+		readings sdkmetric.Exporter
+		err      error
+	)
+	switch cfg.Protocol {
+	case config.ProtocolHTTP:
+		topts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint), otlptracehttp.WithHeaders(cfg.Headers)}
+		mopts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(cfg.Endpoint), otlpmetrichttp.WithHeaders(cfg.Headers)}
+		if cfg.Insecure {
+			topts = append(topts, otlptracehttp.WithInsecure())
+			mopts = append(mopts, otlpmetrichttp.WithInsecure())
+		}
+		if spans, err = otlptracehttp.New(ctx, topts...); err == nil {
+			readings, err = otlpmetrichttp.New(ctx, mopts...)
+		}
+	default:
+		topts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint), otlptracegrpc.WithHeaders(cfg.Headers)}
+		mopts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.Endpoint), otlpmetricgrpc.WithHeaders(cfg.Headers)}
+		if cfg.Insecure {
+			topts = append(topts, otlptracegrpc.WithInsecure())
+			mopts = append(mopts, otlpmetricgrpc.WithInsecure())
+		}
+		if spans, err = otlptracegrpc.New(ctx, topts...); err == nil {
+			readings, err = otlpmetricgrpc.New(ctx, mopts...)
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	return spans, readings, nil
+}
+
+// Shutdown exports what is still buffered and stops exporting.
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	return errors.Join(t.traces.Shutdown(ctx), t.metrics.Shutdown(ctx))
+}
+
+// WrapChecker returns a Checker that runs c in a span and records its
+// results. HTTP checks get a child span per connection phase.
+func (t *Telemetry) WrapChecker(svc config.Service, c checker.Checker) checker.Checker {
+	tags := slices.Clone(svc.Tags)
+	slices.Sort(tags)
+	attrs := []attribute.KeyValue{attrService.String(svc.Name), attrType.String(svc.Type)}
+	if len(tags) > 0 {
+		attrs = append(attrs, attrTags.StringSlice(tags))
+	}
+	// Clipped, so appending the status copies rather than sharing.
+	attrs = slices.Clip(attrs)
+	return &tracedChecker{t: t, checker: c, http: svc.Type == "http", attrs: attrs}
+}
+
+type tracedChecker struct {
+	t       *Telemetry
+	checker checker.Checker
+	http    bool
+	attrs   []attribute.KeyValue
+}
+
+func (c *tracedChecker) Check(ctx context.Context) checker.CheckResult {
+	ctx, span := c.t.tracer.Start(ctx, "check", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(c.attrs...))
+	defer span.End()
+
+	var phases *phaseTrace
+	if c.http {
+		phases = newPhaseTrace(ctx, c.t.tracer)
+		ctx = httptrace.WithClientTrace(ctx, phases.clientTrace())
+	}
+	result := c.checker.Check(ctx)
+	if phases != nil {
+		phases.end()
+	}
+	if ctx.Err() != nil {
+		// Stopped mid-check; the scheduler discards the result too.
+		span.SetStatus(codes.Error, "canceled")
+		return result
+	}
+
+	status := attrStatus.String(string(result.Status))
+	span.SetAttributes(status)
+	if result.Status == checker.StatusDown {
+		span.SetStatus(codes.Error, result.Error)
+	}
+
+	// Recorded in the span's context, the duration keeps the trace as an
+	// exemplar.
+	c.t.checks.Add(ctx, 1, metric.WithAttributes(append(c.attrs, status)...))
+	c.t.duration.Record(ctx, result.ResponseTime.Seconds(), metric.WithAttributes(c.attrs...))
+	up := int64(0)
+	if result.Status == checker.StatusUp {
+		up = 1
+	}
+	c.t.up.Record(ctx, up, metric.WithAttributes(c.attrs...))
+	return result
+}
@@ -0,0 +1,272 @@
+package telemetry_test
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/telemetry"
+)
+
+// collector stands in for an OpenTelemetry collector, over gRPC and HTTP.
+type collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mu      sync.Mutex
+	spans   []*tracepb.Span
+	metrics []*metricpb.Metric
+	headers []string
+}
+
+func (c *collector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.addSpans(req)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// metricsService adapts collector to the metrics service, whose Export
+// method clashes with the trace service's.
+type metricsService struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+	c *collector
+}
+
+func (m metricsService) Export(_ context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	m.c.addMetrics(req)
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *collector) addSpans(req *coltracepb.ExportTraceServiceRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func (c *collector) addMetrics(req *colmetricpb.ExportMetricsServiceRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			c.metrics = append(c.metrics, sm.Metrics...)
+		}
+	}
+}
+
+// ServeHTTP takes OTLP/HTTP requests in protobuf encoding.
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.headers = append(c.headers, r.Header.Get("X-Api-Key"))
+	c.mu.Unlock()
+	var resp proto.Message
+	switch r.URL.Path {
+	case "/v1/traces":
+		var req coltracepb.ExportTraceServiceRequest
+		err = proto.Unmarshal(body, &req)
+		c.addSpans(&req)
+		resp = &coltracepb.ExportTraceServiceResponse{}
+	case "/v1/metrics":
+		var req colmetricpb.ExportMetricsServiceRequest
+		err = proto.Unmarshal(body, &req)
+		c.addMetrics(&req)
+		resp = &colmetricpb.ExportMetricsServiceResponse{}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(out)
+}
+
+// startCollector serves c over protocol and returns its host:port.
+func startCollector(t *testing.T, c *collector, protocol string) string {
+	t.Helper()
+	if protocol == config.ProtocolHTTP {
+		srv := httptest.NewServer(c)
+		t.Cleanup(srv.Close)
+		return strings.TrimPrefix(srv.URL, "http://")
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, c)
+	colmetricpb.RegisterMetricsServiceServer(srv, metricsService{c: c})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestTelemetry_ExportsChecks(t *testing.T) {
+	for _, protocol := range []string{config.ProtocolGRPC, config.ProtocolHTTP} {
+		t.Run(protocol, func(t *testing.T) {
+			testExport(t, protocol)
+		})
+	}
+}
+
+func testExport(t *testing.T, protocol string) {
+	var traceparent string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+	}))
+	defer target.Close()
+
+	c := &collector{}
+	ctx := context.Background()
+	tel, err := telemetry.New(ctx, config.TelemetryConfig{
+		Endpoint:       startCollector(t, c, protocol),
+		Protocol:       protocol,
+		Insecure:       true,
+		Headers:        map[string]string{"X-Api-Key": "secret"},
+		ServiceName:    "servprobe",
+		MetricInterval: config.Duration{Duration: time.Hour},
+	}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// localhost rather than the IP, so that the check looks it up.
+	api := config.Service{
+		Name:    "api",
+		Type:    "http",
+		Target:  strings.Replace(target.URL, "127.0.0.1", "localhost", 1),
+		Timeout: config.Duration{Duration: 5 * time.Second},
+		Tags:    []string{"prod", "critical"},
+	}
+	broken := api
+	broken.Name, broken.ExpectedStatus, broken.Tags = "broken", http.StatusNoContent, nil
+	for _, svc := range []config.Service{api, broken} {
+		inner, err := checker.New(svc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tel.WrapChecker(svc, inner).Check(ctx)
+	}
+	if err := tel.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var checks []*tracepb.Span
+	children := map[string][]string{}
+	for _, s := range c.spans {
+		if s.Name == "check" {
+			checks = append(checks, s)
+		} else {
+			parent := hex.EncodeToString(s.ParentSpanId)
+			children[parent] = append(children[parent], s.Name)
+		}
+	}
+	if len(checks) != 2 {
+		t.Fatalf("expected 2 check spans, got %d of %d spans", len(checks), len(c.spans))
+	}
+	ok := checks[0]
+	if attr(ok.Attributes, "servprobe.service.name") != "api" || attr(ok.Attributes, "servprobe.check.status") != "up" ||
+		attr(ok.Attributes, "servprobe.service.tags") != "critical,prod" || ok.Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		t.Errorf("unexpected check span: %v", ok)
+	}
+	phases := strings.Join(children[hex.EncodeToString(ok.SpanId)], " ")
+	for _, phase := range []string{"http.dns", "http.connect", "http.first_byte"} {
+		if !strings.Contains(phases, phase) {
+			t.Errorf("expected a %s child span, got %q", phase, phases)
+		}
+	}
+	if bad := checks[1]; bad.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || !strings.Contains(bad.Status.GetMessage(), "expected status 204") {
+		t.Errorf("expected the down check's span to be an error, got %v", bad.Status)
+	}
+
+	// The last request the target saw came from the second check.
+	want := "00-" + hex.EncodeToString(checks[1].TraceId) + "-" + hex.EncodeToString(checks[1].SpanId) + "-01"
+	if traceparent != want {
+		t.Errorf("expected traceparent %q, got %q", want, traceparent)
+	}
+
+	found := map[string]bool{}
+	for _, m := range c.metrics {
+		found[m.Name] = true
+		switch m.Name {
+		case "servprobe.checks":
+			if n := len(m.GetSum().GetDataPoints()); n != 2 {
+				t.Errorf("expected a count per service, got %d", n)
+			}
+		case "servprobe.check.duration":
+			var count uint64
+			for _, dp := range m.GetHistogram().GetDataPoints() {
+				count += dp.Count
+			}
+			if count != 2 || m.Unit != "s" {
+				t.Errorf("expected 2 durations in seconds, got %d in %q", count, m.Unit)
+			}
+		case "servprobe.service.up":
+			for _, dp := range m.GetGauge().GetDataPoints() {
+				want := int64(0)
+				if attr(dp.Attributes, "servprobe.service.name") == "api" {
+					want = 1
+				}
+				if dp.GetAsInt() != want {
+					t.Errorf("unexpected up data point: %v", dp)
+				}
+			}
+		}
+	}
+	for _, name := range []string{"servprobe.checks", "servprobe.check.duration", "servprobe.service.up"} {
+		if !found[name] {
+			t.Errorf("expected metric %s", name)
+		}
+	}
+	if protocol == config.ProtocolHTTP && (len(c.headers) == 0 || c.headers[0] != "secret") {
+		t.Errorf("expected the configured headers, got %q", c.headers)
+	}
+}
+
+// attr returns the value of the attribute key, with string slices joined by
+// commas.
+func attr(attrs []*commonpb.KeyValue, key string) string {
+	for _, kv := range attrs {
+		if kv.Key != key {
+			continue
+		}
+		if arr := kv.Value.GetArrayValue(); arr != nil {
+			var vals []string
+			for _, v := range arr.Values {
+				vals = append(vals, v.GetStringValue())
+			}
+			return strings.Join(vals, ",")
+		}
+		return kv.Value.GetStringValue()
+	}
+	return ""
+}
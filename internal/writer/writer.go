@@ -0,0 +1,271 @@
+// Package writer stores check results asynchronously, in batches, so that
+// checks don't wait on the database.
+package writer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+)
+
+// Store writes a batch of check results in one transaction.
+type Store interface {
+	InsertChecks(ctx context.Context, results []checker.CheckResult) error
+}
+
+var (
+	// ErrQueueFull is returned by InsertCheck for a result that was dropped
+	// because the queue stayed full for a whole flush interval.
+	ErrQueueFull = errors.New("write queue full, check result dropped")
+	// ErrClosed is returned by InsertCheck after Close.
+	ErrClosed = errors.New("writer closed")
+)
+
+// maxAttempts is how often a batch is tried before its results are given up
+// on. Failed batches are retried on the next flush.
+const maxAttempts = 3
+
+// writeTimeout bounds a single batch write.
+const writeTimeout = 30 * time.Second
+
+// Stats reports on the write pipeline.
+type Stats struct {
+	// Queued is the number of results waiting to be written.
+	Queued int `json:"queued"`
+	// Written, Dropped and Failed count results since startup: stored,
+	// turned away because the queue was full, and given up on after the
+	// database rejected them maxAttempts times.
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Failed  uint64 `json:"failed"`
+	// LagSeconds is how long the oldest result of the last batch written
+	// waited in the queue.
+	LagSeconds float64 `json:"lag_seconds"`
+	// Lagging is set while results wait longer than five flush intervals.
+	Lagging bool `json:"lagging"`
+}
+
+type pending struct {
+	result   checker.CheckResult
+	queuedAt time.Time
+}
+
+// Writer queues check results and writes them in batches. It implements the
+// InsertCheck half of scheduler.Store.
+type Writer struct {
+	store     Store
+	interval  time.Duration
+	batchSize int
+	logger    *slog.Logger
+
+	queue chan pending
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+
+	// batch is only used by the run goroutine; its length is mirrored in
+	// inBatch for Stats.
+	batch    []pending
+	attempts int
+	inBatch  atomic.Int64
+
+	written, dropped, failed atomic.Uint64
+	lag                      atomic.Int64
+	lagging                  atomic.Bool
+}
+
+// New creates a Writer. Call Start before queueing results.
+func New(store Store, cfg config.WriteConfig, logger *slog.Logger) *Writer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.FlushInterval.Duration <= 0 {
+		cfg.FlushInterval.Duration = config.DefaultFlushInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = config.DefaultBatchSize
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = config.DefaultQueueSize
+	}
+	return &Writer{
+		store:     store,
+		interval:  cfg.FlushInterval.Duration,
+		batchSize: cfg.BatchSize,
+		logger:    logger,
+		queue:     make(chan pending, cfg.QueueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start writes queued results in the background until Close.
+func (w *Writer) Start() {
+	go w.run()
+}
+
+// InsertCheck queues r to be written. If the queue is full it waits up to one
+// flush interval for room, slowing the caller down, and then drops r and
+// returns ErrQueueFull.
+func (w *Writer) InsertCheck(ctx context.Context, r checker.CheckResult) error {
+	p := pending{result: r, queuedAt: time.Now()}
+	select {
+	case <-w.stop:
+		return ErrClosed
+	default:
+	}
+	select {
+	case w.queue <- p:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(w.interval)
+	defer timer.Stop()
+	select {
+	case <-w.stop:
+		return ErrClosed
+	case w.queue <- p:
+		return nil
+	case <-ctx.Done():
+		w.dropped.Add(1)
+		return ctx.Err()
+	case <-timer.C:
+		w.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting results, writes those still queued and waits until
+// they are stored or ctx is done.
+func (w *Writer) Close(ctx context.Context) error {
+	w.once.Do(func() { close(w.stop) })
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the current state of the pipeline.
+func (w *Writer) Stats() Stats {
+	return Stats{
+		Queued:     len(w.queue) + int(w.inBatch.Load()),
+		Written:    w.written.Load(),
+		Dropped:    w.dropped.Load(),
+		Failed:     w.failed.Load(),
+		LagSeconds: time.Duration(w.lag.Load()).Seconds(),
+		Lagging:    w.lagging.Load(),
+	}
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		// A batch that is full, e.g. because writing it keeps failing,
+		// isn't added to; the queue fills up instead.
+		queue := w.queue
+		if len(w.batch) >= w.batchSize {
+			queue = nil
+		}
+		select {
+		case p := <-queue:
+			w.add(p)
+			if len(w.batch) >= w.batchSize {
+				w.flush()
+			}
+		case <-ticker.C:
+			w.flush()
+		case <-w.stop:
+			w.drain()
+			return
+		}
+	}
+}
+
+// drain writes everything still queued, retrying failed batches right away.
+func (w *Writer) drain() {
+	for {
+		for len(w.batch) < w.batchSize {
+			select {
+			case p := <-w.queue:
+				w.add(p)
+				continue
+			default:
+			}
+			break
+		}
+		if len(w.batch) == 0 {
+			return
+		}
+		w.flush()
+	}
+}
+
+func (w *Writer) add(p pending) {
+	w.batch = append(w.batch, p)
+	w.inBatch.Store(int64(len(w.batch)))
+}
+
+// flush writes the current batch. On failure the batch is kept for the next
+// flush until it has been tried maxAttempts times.
+func (w *Writer) flush() {
+	if len(w.batch) == 0 {
+		return
+	}
+	results := make([]checker.CheckResult, len(w.batch))
+	for i, p := range w.batch {
+		results[i] = p.result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	err := w.store.InsertChecks(ctx, results)
+	cancel()
+	if err != nil {
+		w.attempts++
+		if w.attempts < maxAttempts {
+			w.logger.Warn("writing check results, will retry", "results", len(results), "attempt", w.attempts, "error", err)
+			return
+		}
+		w.logger.Error("writing check results, giving up", "results", len(results), "error", err)
+		w.failed.Add(uint64(len(results)))
+		w.reset()
+		return
+	}
+
+	lag := time.Since(w.batch[0].queuedAt)
+	w.written.Add(uint64(len(results)))
+	w.lag.Store(int64(lag))
+	w.reportLag(lag)
+	w.reset()
+}
+
+func (w *Writer) reset() {
+	w.batch = w.batch[:0]
+	w.attempts = 0
+	w.inBatch.Store(0)
+}
+
+// reportLag logs when results start and stop waiting longer than five flush
+// intervals to be written.
+func (w *Writer) reportLag(lag time.Duration) {
+	lagging := lag > 5*w.interval
+	if lagging == w.lagging.Swap(lagging) {
+		return
+	}
+	if lagging {
+		w.logger.Warn("check results are written late", "lag", lag, "queued", len(w.queue))
+	} else {
+		w.logger.Info("check results are written on time again", "lag", lag)
+	}
+}
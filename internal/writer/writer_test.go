@@ -0,0 +1,174 @@
+package writer_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hazz-dev/servprobe/internal/checker"
+	"github.com/hazz-dev/servprobe/internal/config"
+	"github.com/hazz-dev/servprobe/internal/writer"
+)
+
+type mockStore struct {
+	mu      sync.Mutex
+	batches [][]checker.CheckResult
+	// gate, if set, blocks writes until it is closed.
+	gate chan struct{}
+	// failures is how many writes fail before they succeed.
+	failures int
+}
+
+func (m *mockStore) InsertChecks(ctx context.Context, results []checker.CheckResult) error {
+	if m.gate != nil {
+		<-m.gate
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("database is locked")
+	}
+	m.batches = append(m.batches, append([]checker.CheckResult(nil), results...))
+	return nil
+}
+
+func (m *mockStore) sizes() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sizes []int
+	for _, b := range m.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func writeConfig(interval time.Duration, batch, queue int) config.WriteConfig {
+	return config.WriteConfig{FlushInterval: config.Duration{Duration: interval}, BatchSize: batch, QueueSize: queue}
+}
+
+func result(service string) checker.CheckResult {
+	return checker.CheckResult{ServiceName: service, Status: checker.StatusUp, CheckedAt: time.Now()}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriter_BatchesAndFlushesOnClose(t *testing.T) {
+	store := &mockStore{}
+	w := writer.New(store, writeConfig(time.Hour, 2, 10), nil)
+	w.Start()
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := w.InsertCheck(ctx, result(name)); err != nil {
+			t.Fatalf("InsertCheck: %v", err)
+		}
+	}
+	// Full batches are written without waiting for the interval.
+	waitFor(t, func() bool { return len(store.sizes()) == 2 })
+	if got := w.Stats().Queued; got != 1 {
+		t.Errorf("expected 1 queued result, got %d", got)
+	}
+
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	sizes := store.sizes()
+	if len(sizes) != 3 || sizes[2] != 1 {
+		t.Fatalf("expected the last result to be written on close, got batches %v", sizes)
+	}
+	if store.batches[0][0].ServiceName != "a" || store.batches[2][0].ServiceName != "e" {
+		t.Error("expected results to be written in order")
+	}
+	if s := w.Stats(); s.Written != 5 || s.Queued != 0 {
+		t.Errorf("unexpected stats after close: %+v", s)
+	}
+	if err := w.InsertCheck(ctx, result("f")); !errors.Is(err, writer.ErrClosed) {
+		t.Errorf("expected ErrClosed after close, got %v", err)
+	}
+}
+
+func TestWriter_FlushesOnInterval(t *testing.T) {
+	store := &mockStore{}
+	w := writer.New(store, writeConfig(10*time.Millisecond, 100, 10), nil)
+	w.Start()
+	defer w.Close(context.Background())
+
+	if err := w.InsertCheck(context.Background(), result("a")); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+	waitFor(t, func() bool { return len(store.sizes()) == 1 })
+}
+
+func TestWriter_DropsWhenQueueStaysFull(t *testing.T) {
+	store := &mockStore{gate: make(chan struct{})}
+	w := writer.New(store, writeConfig(20*time.Millisecond, 1, 1), nil)
+	w.Start()
+	ctx := context.Background()
+
+	// The first result is being written, the second fills the queue.
+	if err := w.InsertCheck(ctx, result("a")); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+	waitFor(t, func() bool { return w.Stats().Queued == 1 })
+	if err := w.InsertCheck(ctx, result("b")); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+
+	start := time.Now()
+	if err := w.InsertCheck(ctx, result("c")); !errors.Is(err, writer.ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("expected to wait a flush interval for room, waited %s", waited)
+	}
+	if s := w.Stats(); s.Dropped != 1 || s.Queued != 2 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+
+	close(store.gate)
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if s := w.Stats(); s.Written != 2 || s.Dropped != 1 {
+		t.Errorf("unexpected stats after close: %+v", s)
+	}
+}
+
+func TestWriter_RetriesFailedBatches(t *testing.T) {
+	store := &mockStore{failures: 2}
+	w := writer.New(store, writeConfig(5*time.Millisecond, 10, 10), nil)
+	w.Start()
+	ctx := context.Background()
+
+	if err := w.InsertCheck(ctx, result("a")); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+	waitFor(t, func() bool { return w.Stats().Written == 1 })
+
+	// A batch that keeps failing is given up on.
+	store.mu.Lock()
+	store.failures = 100
+	store.mu.Unlock()
+	if err := w.InsertCheck(ctx, result("b")); err != nil {
+		t.Fatalf("InsertCheck: %v", err)
+	}
+	waitFor(t, func() bool { return w.Stats().Failed == 1 })
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if s := w.Stats(); s.Written != 1 || s.Queued != 0 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}